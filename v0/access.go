@@ -0,0 +1,141 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// AccessRecorder records the fields of the data and the functions used by
+// the executions of a set, for example to find the data a page really
+// needs, or to check the data fabricated by FakeData. It is safe to use
+// from concurrent executions.
+//
+// Fields are recorded by their path from the data passed to Execute, as in
+// "User.Name". The elements iterated by {{range}} are written with [], as
+// in "Items[].Title". A path starting with "?" is relative to a value that
+// doesn't come from a field, such as the result of a function, and "?"
+// alone is recorded when a variable holding such a value is used.
+type AccessRecorder struct {
+	mutex  sync.Mutex
+	fields map[string]bool
+	funcs  map[string]bool
+}
+
+// RecordAccess makes executions of the set record the data they access in
+// r. The return value is the set, so calls can be chained.
+func (s *Set) RecordAccess(r *AccessRecorder) *Set {
+	s.recorder = r
+	return s
+}
+
+// Fields returns the sorted paths of the fields accessed.
+func (r *AccessRecorder) Fields() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return sortedKeys(r.fields)
+}
+
+// Funcs returns the sorted names of the functions called. Functions
+// inserted by contextual escaping are not included.
+func (r *AccessRecorder) Funcs() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return sortedKeys(r.funcs)
+}
+
+// field records the access to a field.
+func (r *AccessRecorder) field(path string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.fields == nil {
+		r.fields = make(map[string]bool)
+	}
+	r.fields[path] = true
+}
+
+// funcCall records a call to a function.
+func (r *AccessRecorder) funcCall(name string) {
+	if strings.HasPrefix(name, "html_template_") {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.funcs == nil {
+		r.funcs = make(map[string]bool)
+	}
+	r.funcs[name] = true
+}
+
+// sortedKeys returns the sorted keys of m.
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unknownPath is the path of values that don't come from a field.
+const unknownPath = "?"
+
+// joinPath returns the path of the fields in the value at path.
+func joinPath(path string, fields []string) string {
+	if path == "" {
+		return strings.Join(fields, ".")
+	}
+	return path + "." + strings.Join(fields, ".")
+}
+
+// setDotPath sets the path of dot and returns a function that restores it.
+func (s *state) setDotPath(path string) func() {
+	saved := s.dotPath
+	s.dotPath = path
+	return func() { s.dotPath = saved }
+}
+
+// varPath returns the path of the value of the named variable.
+func (s *state) varPath(name string) string {
+	for i := s.mark() - 1; i >= 0; i-- {
+		if s.vars[i].name == name {
+			return s.vars[i].path
+		}
+	}
+	return unknownPath
+}
+
+// pipePath returns the path of the value of a pipeline that is a single
+// field, variable or dot.
+func (s *state) pipePath(pipe *parse.PipeNode) string {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return unknownPath
+	}
+	return s.argPath(pipe.Cmds[0].Args[0])
+}
+
+// argPath returns the path of the value of an argument.
+func (s *state) argPath(n parse.Node) string {
+	switch n := n.(type) {
+	case *parse.ChainNode:
+		return joinPath(s.argPath(n.Node), n.Field)
+	case *parse.DotNode:
+		return s.dotPath
+	case *parse.FieldNode:
+		return joinPath(s.dotPath, n.Ident)
+	case *parse.PipeNode:
+		return s.pipePath(n)
+	case *parse.VariableNode:
+		if len(n.Ident) == 1 {
+			return s.varPath(n.Ident[0])
+		}
+		return joinPath(s.varPath(n.Ident[0]), n.Ident[1:])
+	}
+	return unknownPath
+}
@@ -0,0 +1,44 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestRecordAccess(t *testing.T) {
+	type item struct {
+		Title string
+		Tags  []string
+	}
+	type user struct {
+		Name  string
+		Email string
+	}
+	data := map[string]interface{}{
+		"User":  user{"Ann", "ann@example.com"},
+		"Items": []item{{"a", []string{"x"}}, {"b", nil}},
+		"Site":  map[string]string{"Name": "Acme"},
+	}
+	r := new(AccessRecorder)
+	set := Must(new(Set).RecordAccess(r).FlatBuiltins().Parse(`
+{{define "page"}}{{upper .User.Name}}{{with .Site}}{{.Name}}{{end}}
+{{range $i, $item := .Items}}{{$i}}{{template "item" $item}}{{end}}
+{{$u := .User}}{{printf "%s" $u.Email}}{{(index .Items 0).Title}}{{end}}
+{{define "item"}}{{.Title}}{{range .Tags}}{{len .}}{{end}}{{end}}`))
+	if err := set.Execute(ioutil.Discard, "page", data); err != nil {
+		t.Fatal(err)
+	}
+	fields := []string{"?", "?.Title", "Items", "Items[]", "Items[].Tags", "Items[].Title", "Site", "Site.Name", "User", "User.Email", "User.Name"}
+	if got := r.Fields(); !reflect.DeepEqual(got, fields) {
+		t.Errorf("expected fields %q, got %q", fields, got)
+	}
+	funcs := []string{"index", "len", "printf", "upper"}
+	if got := r.Funcs(); !reflect.DeepEqual(got, funcs) {
+		t.Errorf("expected funcs %q, got %q", funcs, got)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Alias makes the name an alias of the template named target, so that
+// executing, calling or extending the template by its old name keeps
+// working after it is renamed. The target can itself be an alias. Aliases
+// are resolved when the set is compiled: it is an error if a template is
+// also defined with the name of an alias, if an alias refers to an
+// undefined template, or if aliases form a cycle. The return value is the
+// set, so calls can be chained.
+func (s *Set) Alias(name, target string) *Set {
+	aliases := make(map[string]string, len(s.aliases)+1)
+	for k, v := range s.aliases {
+		aliases[k] = v
+	}
+	aliases[name] = target
+	s.aliases = aliases
+	return s
+}
+
+// resolveAlias returns the name of the template the named alias refers to,
+// following aliases of aliases, or name if it is not an alias. The search
+// stops at an alias cycle, which compilation reports.
+func resolveAlias(aliases map[string]string, name string) string {
+	for i := 0; i < len(aliases); i++ {
+		target, ok := aliases[name]
+		if !ok {
+			break
+		}
+		name = target
+	}
+	return name
+}
+
+// resolveAliases replaces the references to aliases in the tree by the
+// names of their targets, and adds a copy of the target under the name of
+// each alias, to be executed by it. It runs before inlining.
+func resolveAliases(tree parse.Tree, aliases map[string]string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resolved := make(map[string]string, len(aliases))
+	for _, name := range names {
+		if tree[name] != nil {
+			return fmt.Errorf("template: alias %q is also defined as a template", name)
+		}
+		chain := []string{name}
+		target := aliases[name]
+		for {
+			if _, ok := aliases[target]; !ok {
+				break
+			}
+			for _, n := range chain {
+				if n == target {
+					return fmt.Errorf("template: alias cycle: %s -> %s",
+						strings.Join(chain, " -> "), target)
+				}
+			}
+			chain = append(chain, target)
+			target = aliases[target]
+		}
+		if tree[target] == nil {
+			return fmt.Errorf("template: alias %q refers to undefined template %q", name, target)
+		}
+		resolved[name] = target
+	}
+	for _, define := range tree {
+		if target, ok := resolved[define.Parent]; ok {
+			define.Parent = target
+		}
+		templateCalls(define.List, func(n *parse.TemplateNode) {
+			if target, ok := resolved[n.Name]; ok {
+				n.Name = target
+			}
+		})
+	}
+	for _, name := range names {
+		define := tree[resolved[name]].CopyDefine()
+		define.Name = name
+		tree[name] = define
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	s := Must(new(Set).
+		Alias("old-layout", "layout").
+		Alias("older-button", "old-button").
+		Alias("old-button", "button").
+		Parse(`
+{{define "layout"}}[{{slot "body"}}{{end}}]{{end}}
+{{define "button"}}<{{.}}>{{end}}
+{{define "page" "old-layout"}}{{fill "body"}}{{template "older-button" "ok"}}{{end}}{{end}}`))
+	// Verify resolves the aliases before compilation.
+	if err := s.Verify(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	tests := []struct {
+		name, output string
+	}{
+		{"page", "[<ok>]"},
+		{"old-button", "<x>"},
+		{"older-button", "<x>"},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := s.Execute(&b, test.name, "x"); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if b.String() != test.output {
+			t.Errorf("%s: got %q, want %q", test.name, b.String(), test.output)
+		}
+	}
+}
+
+func TestAliasErrors(t *testing.T) {
+	const text = `{{define "a"}}a{{end}}{{define "b"}}b{{end}}`
+	tests := []struct {
+		aliases [][2]string
+		err     string
+	}{
+		{[][2]string{{"a", "b"}}, `alias "a" is also defined as a template`},
+		{[][2]string{{"x", "y"}}, `alias "x" refers to undefined template "y"`},
+		{[][2]string{{"x", "y"}, {"y", "z"}, {"z", "x"}}, `alias cycle: x -> y -> z -> x`},
+	}
+	s := Must(new(Set).Alias("x", "y").Parse(`{{define "a"}}{{template "x"}}{{end}}`))
+	if err := s.Verify(); err == nil || !strings.Contains(err.Error(), `no such template "x"`) {
+		t.Errorf("got error %v, want undefined template", err)
+	}
+	for _, test := range tests {
+		s := new(Set)
+		for _, a := range test.aliases {
+			s.Alias(a[0], a[1])
+		}
+		_, err := Must(s.Parse(text)).Compile()
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %q", test.aliases, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/template/v0/escape"
+)
+
+// Asset describes an image returned by an AssetResolver.
+type Asset struct {
+	URL      string         // The URL of the image, used for src.
+	Width    int            // The width in pixels, or 0 if unknown.
+	Height   int            // The height in pixels, or 0 if unknown.
+	Variants []AssetVariant // Alternative sizes, used for srcset.
+}
+
+// AssetVariant is an alternative size of an image.
+type AssetVariant struct {
+	URL        string // The URL of the variant.
+	Descriptor string // The width or density descriptor, as "640w" or "2x".
+}
+
+// AssetResolver returns the asset with the given name, usually from a
+// manifest generated when the assets are built.
+type AssetResolver func(name string) (Asset, error)
+
+// Assets adds to the set an imgTag function that emits a complete <img>
+// tag for an asset returned by resolve, with its dimensions to avoid
+// layout shifts and its variants in srcset:
+//
+//     {{imgTag "logo.png" "alt" "Gorilla" "class" "logo"}}
+//
+// The arguments after the asset name are attribute name/value pairs,
+// which are handled as in the attr function. The return value is the set,
+// so calls can be chained.
+func (s *Set) Assets(resolve AssetResolver) *Set {
+	return s.Funcs(FuncMap{
+		"imgTag": func(name string, attrs ...interface{}) (escape.HTML, error) {
+			asset, err := resolve(name)
+			if err != nil {
+				return "", err
+			}
+			return imgTag(asset, attrs)
+		},
+	})
+}
+
+// srcsetDescriptor matches the width and density descriptors of srcset.
+var srcsetDescriptor = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[wx]$`)
+
+// imgTag returns the <img> tag for the asset with the given attributes.
+func imgTag(asset Asset, attrs []interface{}) (escape.HTML, error) {
+	if !isSafeURL(asset.URL) {
+		return "", fmt.Errorf("imgTag: unsafe URL %q", asset.URL)
+	}
+	var b bytes.Buffer
+	b.WriteString(`<img src="`)
+	b.WriteString(escape.HTMLEscapeString(escape.URLNormalizer(asset.URL)))
+	b.WriteByte('"')
+	if len(asset.Variants) != 0 {
+		srcset := make([]string, len(asset.Variants))
+		for i, v := range asset.Variants {
+			if !isSafeURL(v.URL) {
+				return "", fmt.Errorf("imgTag: unsafe URL %q", v.URL)
+			}
+			if !srcsetDescriptor.MatchString(v.Descriptor) {
+				return "", fmt.Errorf("imgTag: bad srcset descriptor %q", v.Descriptor)
+			}
+			// Commas separate the candidates of srcset.
+			u := strings.Replace(escape.URLNormalizer(v.URL), ",", "%2c", -1)
+			srcset[i] = u + " " + v.Descriptor
+		}
+		b.WriteString(` srcset="`)
+		b.WriteString(escape.HTMLEscapeString(strings.Join(srcset, ", ")))
+		b.WriteByte('"')
+	}
+	if asset.Width > 0 {
+		fmt.Fprintf(&b, ` width="%d"`, asset.Width)
+	}
+	if asset.Height > 0 {
+		fmt.Fprintf(&b, ` height="%d"`, asset.Height)
+	}
+	extra, err := escape.Attr(attrs...)
+	if err != nil {
+		return "", err
+	}
+	if extra != "" {
+		b.WriteByte(' ')
+		b.WriteString(string(extra))
+	}
+	b.WriteByte('>')
+	return escape.HTML(b.String()), nil
+}
+
+// isSafeURL returns whether u is a relative URL or uses the http, https or
+// mailto scheme.
+func isSafeURL(u string) bool {
+	if i := strings.IndexRune(u, ':'); i >= 0 && strings.IndexRune(u[:i], '/') < 0 {
+		switch strings.ToLower(u[:i]) {
+		case "http", "https", "mailto":
+		default:
+			return false
+		}
+	}
+	return true
+}
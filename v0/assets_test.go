@@ -0,0 +1,54 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestAssets(t *testing.T) {
+	assets := map[string]Asset{
+		"logo.png": {
+			URL:    "/static/logo.3f2a.png",
+			Width:  120,
+			Height: 40,
+			Variants: []AssetVariant{
+				{"/static/logo.3f2a.png", "1x"},
+				{"/static/logo@2x,v2.png", "2x"},
+			},
+		},
+		"bad.png": {URL: "javascript:alert(1)"},
+	}
+	set := Must(new(Set).Assets(func(name string) (Asset, error) {
+		if a, ok := assets[name]; ok {
+			return a, nil
+		}
+		return Asset{}, fmt.Errorf("no asset %q", name)
+	}).Parse(`{{define "a"}}<p>{{imgTag .Name "alt" .Alt "class" ""}}</p>{{end}}`)).Escape()
+
+	tests := []struct {
+		name, alt string
+		output    string
+		ok        bool
+	}{
+		{"logo.png", `"Gorilla"`, `<p><img src="/static/logo.3f2a.png" srcset="/static/logo.3f2a.png 1x, /static/logo@2x%2cv2.png 2x" width="120" height="40" alt="&#34;Gorilla&#34;"></p>`, true},
+		{"bad.png", "", "", false},
+		{"missing.png", "", "", false},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		err := set.Execute(&b, "a", map[string]string{"Name": test.name, "Alt": test.alt})
+		switch {
+		case !test.ok && err == nil:
+			t.Errorf("%s: expected error; got none", test.name)
+		case test.ok && err != nil:
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		case test.ok && b.String() != test.output:
+			t.Errorf("%s: expected\n\t%q\ngot\n\t%q", test.name, test.output, b.String())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gorilla/template/v0/escape"
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Bypass describes a value that was written without contextual escaping.
+type Bypass struct {
+	Template string // The name of the executing template.
+	Location string // The location of the action, as "name:line:column".
+	Func     string // The function that received the value.
+	Type     string // The type of the value, such as "escape.HTML".
+}
+
+// AuditBypasses makes executions of the set call fn each time a value
+// bypasses contextual escaping: when it is passed to the noescape
+// function, or when an escaping function receives a value of a content
+// type such as escape.HTML, which it trusts. It is meant to monitor the
+// use of raw output in production, and fn must be safe to call
+// concurrently. The return value is the set, so calls can be chained.
+func (s *Set) AuditBypasses(fn func(Bypass)) *Set {
+	s.audit = fn
+	return s
+}
+
+// auditCall reports a bypass if the function with the given name receives
+// a value that bypasses escaping as its last argument.
+func (s *state) auditCall(node parse.Node, name string, arg reflect.Value) {
+	if name != "noescape" && !strings.HasPrefix(name, "html_template_") {
+		return
+	}
+	if arg.Kind() == reflect.Interface {
+		arg = arg.Elem()
+	}
+	if name != "noescape" {
+		if !arg.IsValid() {
+			return
+		}
+		switch arg.Interface().(type) {
+		case escape.CSS, escape.HTML, escape.HTMLAttr, escape.JS, escape.JSStr, escape.URL:
+		default:
+			return
+		}
+	}
+	typ := "nil"
+	if arg.IsValid() {
+		typ = arg.Type().String()
+	}
+	location, _ := s.tmpl.ErrorContext(node)
+	s.snap.audit(Bypass{
+		Template: s.tmpl.Name,
+		Location: location,
+		Func:     name,
+		Type:     typ,
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/template/v0/escape"
+)
+
+func TestAuditBypasses(t *testing.T) {
+	var bypasses []Bypass
+	set := new(Set).Funcs(FuncMap{
+		"noescape": func(a ...interface{}) string {
+			return fmt.Sprint(a...)
+		},
+	})
+	set = Must(set.Parse(`{{define "a"}}{{.Text}}{{.Raw}}
+<a href="{{.URL}}">{{.Text | noescape}}</a>{{end}}`)).Escape().AuditBypasses(func(b Bypass) {
+		bypasses = append(bypasses, b)
+	})
+	data := map[string]interface{}{
+		"Text": "<x>",
+		"Raw":  escape.HTML("<b>"),
+		"URL":  escape.URL("javascript:void(0)"),
+	}
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "a", data); err != nil {
+		t.Fatal(err)
+	}
+	expect := []Bypass{
+		{"a", "a:1:25", "html_template_htmlescaper", "escape.HTML"},
+		{"a", "a:2:11", "html_template_urlfilter", "escape.URL"},
+		{"a", "a:2:29", "noescape", "string"},
+	}
+	if !reflect.DeepEqual(bypasses, expect) {
+		t.Errorf("expected bypasses\n\t%v\ngot\n\t%v", expect, bypasses)
+	}
+}
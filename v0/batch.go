@@ -0,0 +1,78 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// bufferPool holds the buffers used by ExecuteBatch.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// ExecuteBatch applies the named template to each of the items, for bulk
+// jobs like newsletters or report exports. Items are rendered in parallel
+// by a pool of GOMAXPROCS workers, and the output for the item at index i
+// is passed to sink. Sink may be called concurrently and in any order, and
+// the reader is only valid until sink returns, since its buffer is reused.
+//
+// The first error, either from an execution or returned by sink, stops the
+// batch and is returned; items not yet started are skipped.
+func (s *Set) ExecuteBatch(name string, items []interface{}, sink func(i int, r io.Reader) error) error {
+	p, err := s.Prepare(name)
+	if err != nil {
+		return err
+	}
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return firstErr != nil
+	}
+	indexes := make(chan int)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(items) {
+		workers = len(items)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				b := bufferPool.Get().(*bytes.Buffer)
+				b.Reset()
+				err := p.Execute(b, items[i])
+				if err == nil {
+					err = sink(i, b)
+				}
+				bufferPool.Put(b)
+				if err != nil {
+					mutex.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range items {
+		if failed() {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return firstErr
+}
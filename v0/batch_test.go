@@ -0,0 +1,52 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestExecuteBatch(t *testing.T) {
+	set := Must(new(Set).Parse(`{{define "a"}}<p>{{.}}</p>{{end}}`)).Escape()
+	items := make([]interface{}, 100)
+	for i := range items {
+		items[i] = fmt.Sprintf("<%d>", i)
+	}
+	var mutex sync.Mutex
+	got := make(map[int]string)
+	err := set.ExecuteBatch("a", items, func(i int, r io.Reader) error {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		got[i] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range items {
+		if want := fmt.Sprintf("<p>&lt;%d&gt;</p>", i); got[i] != want {
+			t.Errorf("item %d: expected %q; got %q", i, want, got[i])
+		}
+	}
+
+	// The first error stops the batch.
+	err = set.ExecuteBatch("a", items, func(i int, r io.Reader) error {
+		return fmt.Errorf("sink failed")
+	})
+	if err == nil || err.Error() != "sink failed" {
+		t.Errorf("expected error from the sink; got %v", err)
+	}
+	if err = set.ExecuteBatch("missing", items, nil); err == nil {
+		t.Errorf("expected error for a missing template")
+	}
+}
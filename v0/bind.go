@@ -0,0 +1,35 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Bind declares the type of data the template with the given name expects.
+// Render checks its type parameter against the bound type, so a call site
+// passing data of another type fails before the template is executed.
+// Execute doesn't check it. The return value is the set, so calls can be
+// chained.
+func (s *Set) Bind(name string, typ reflect.Type) *Set {
+	bindings := make(map[string]reflect.Type, len(s.bindings)+1)
+	for k, v := range s.bindings {
+		bindings[k] = v
+	}
+	bindings[name] = typ
+	s.bindings = bindings
+	return s
+}
+
+// checkBinding returns an error if values of type typ can't be passed to
+// the template with the given name, according to the type bound with Bind.
+func (s *Set) checkBinding(name string, typ reflect.Type) error {
+	bound, ok := s.bindings[name]
+	if !ok || typ.AssignableTo(bound) {
+		return nil
+	}
+	return fmt.Errorf("template: %q is bound to %s, can't render it with %s", name, bound, typ)
+}
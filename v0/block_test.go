@@ -6,6 +6,7 @@ package template
 
 import (
 	"bytes"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -121,3 +122,195 @@ func TestSlot(t *testing.T) {
 		}
 	}
 }
+
+func TestConst(t *testing.T) {
+	tpl := `
+	{{define "base"}}
+		A
+		{{slot "body"}}-b-{{end}}
+		{{const "footer"}}-f-{{end}}
+	{{end}}
+
+	{{define "page" "base"}}
+		{{fill "body"}}-p-{{end}}
+	{{end}}
+
+	{{define "sub" "page"}}
+		{{fill "body"}}-s-{{end}}
+	{{end}}`
+	bad := `
+	{{define "bad" "page"}}
+		{{fill "footer"}}-x-{{end}}
+	{{end}}`
+	tests := []struct {
+		name   string
+		output string
+		ok     bool
+	}{
+		{"base", "A-b--f-", true},
+		{"page", "A-p--f-", true},
+		{"sub", "A-s--f-", true},
+		{"bad", `template: "bad": fill "footer" overrides a constant block`, false},
+	}
+	for _, test := range tests {
+		set := Must(new(Set).Parse(tpl))
+		if !test.ok {
+			set = Must(set.Parse(bad))
+		}
+		b := new(bytes.Buffer)
+		err := set.Execute(b, test.name, nil)
+		if !test.ok {
+			if err == nil || err.Error() != test.output {
+				t.Errorf("%s: expected error %q, got %v", test.name, test.output, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected exec error: %s", test.name, err)
+			continue
+		}
+		output := strings.NewReplacer(" ", "", "\n", "", "\t", "").Replace(b.String())
+		if output != test.output {
+			t.Errorf("%s: expected %q, got %q", test.name, test.output, output)
+		}
+	}
+}
+
+func TestSlotDefault(t *testing.T) {
+	tpl := `
+	{{define "layout"}}[{{slot "nav" default "default-nav"}}{{end}}]{{end}}
+	{{define "default-nav"}}nav:{{.}}{{end}}
+	{{define "page" "layout"}}{{end}}
+	{{define "custom" "layout"}}{{fill "nav"}}custom{{end}}{{end}}`
+	tests := []execTest{
+		{"layout", tpl, "[nav:d]", "d", true},
+		{"page", tpl, "[nav:d]", "d", true},
+		{"custom", tpl, "[custom]", "d", true},
+	}
+	for _, test := range tests {
+		set := Must(new(Set).Parse(test.input))
+		b := new(bytes.Buffer)
+		if err := set.Execute(b, test.name, test.data); err != nil {
+			t.Errorf("%s: unexpected exec error: %s", test.name, err)
+			continue
+		}
+		if b.String() != test.output {
+			t.Errorf("%s: expected %q, got %q", test.name, test.output, b.String())
+		}
+	}
+	if _, err := new(Set).Parse(`{{define "x"}}{{slot "nav" default "n"}}x{{end}}{{end}}`); err == nil {
+		t.Errorf("expected error for slot with default template and contents")
+	}
+	set := Must(new(Set).Parse(`{{define "x"}}{{slot "nav" default "n"}}{{end}}{{end}}`))
+	if s := set.tree["x"].String(); s != `{{define "x"}}{{slot "nav" default "n"}}{{end}}{{end}}` {
+		t.Errorf("unexpected String: %s", s)
+	}
+}
+
+func TestFillMode(t *testing.T) {
+	tpl := `
+	{{define "base"}}[{{slot "scripts"}}b{{end}}]{{end}}
+	{{define "page" "base"}}{{fill "scripts" append}}p{{end}}{{end}}
+	{{define "first" "base"}}{{fill "scripts" prepend}}f{{end}}{{end}}
+	{{define "sub" "page"}}{{fill "scripts" append}}s{{end}}{{end}}
+	{{define "other" "page"}}{{fill "scripts"}}o{{end}}{{end}}
+	{{define "deep" "mid2"}}{{fill "scripts" prepend}}d{{end}}{{end}}
+	{{define "mid2" "base"}}{{end}}`
+	tests := []execTest{
+		{"page", tpl, "[bp]", nil, true},
+		{"first", tpl, "[fb]", nil, true},
+		{"sub", tpl, "[bps]", nil, true},
+		{"other", tpl, "[o]", nil, true},
+		{"deep", tpl, "[db]", nil, true},
+	}
+	for _, test := range tests {
+		set := Must(new(Set).Parse(test.input))
+		b := new(bytes.Buffer)
+		if err := set.Execute(b, test.name, test.data); err != nil {
+			t.Errorf("%s: unexpected exec error: %s", test.name, err)
+			continue
+		}
+		if b.String() != test.output {
+			t.Errorf("%s: expected %q, got %q", test.name, test.output, b.String())
+		}
+	}
+}
+
+func TestSlotsAndFills(t *testing.T) {
+	set := Must(new(Set).Parse(`
+	{{define "base"}}{{slot "head"}}{{end}}{{if .}}{{slot "body"}}{{end}}{{end}}{{const "footer"}}{{end}}{{end}}
+	{{define "layout" "base"}}{{fill "body"}}{{slot "main"}}{{end}}{{slot "aside"}}{{end}}{{end}}{{end}}
+	{{define "page" "layout"}}{{fill "main"}}m{{end}}{{fill "head" append}}h{{end}}{{end}}`))
+	tests := []struct {
+		name  string
+		slots []string
+		fills []string
+	}{
+		{"base", []string{"head", "body"}, nil},
+		{"layout", []string{"head", "body", "main", "aside"}, []string{"body"}},
+		{"page", []string{"head", "body", "main", "aside"}, []string{"main", "head"}},
+	}
+	for _, test := range tests {
+		slots, err := set.Slots(test.name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else if !reflect.DeepEqual(slots, test.slots) {
+			t.Errorf("%s: expected slots %v, got %v", test.name, test.slots, slots)
+		}
+		fills, err := set.Fills(test.name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else if !reflect.DeepEqual(fills, test.fills) {
+			t.Errorf("%s: expected fills %v, got %v", test.name, test.fills, fills)
+		}
+	}
+	if _, err := set.Slots("missing"); err == nil {
+		t.Errorf("expected error for missing template")
+	}
+	if _, err := set.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Slots("page"); err == nil {
+		t.Errorf("expected error after compilation")
+	}
+}
+
+func TestDuplicateSlot(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string // Empty if no error.
+	}{
+		{
+			`{{define "a"}}{{slot "x"}}{{end}}
+{{if .}}{{slot "x"}}{{end}}{{end}}{{end}}`,
+			`template: "a": slot "x" is declared twice, at a:1:21 and at a:2:15`,
+		},
+		{
+			`{{define "a"}}{{slot "x"}}{{end}}{{slot "y"}}{{end}}{{end}}
+{{define "b" "a"}}{{fill "y"}}{{slot "x"}}{{end}}{{end}}{{end}}`,
+			`template: "b": slot "x" is declared twice, at a:1:21 and at b:2:37`,
+		},
+		{
+			// A fill may declare the slot it replaces.
+			`{{define "a"}}{{slot "x"}}{{end}}{{end}}
+{{define "b" "a"}}{{fill "x"}}[{{slot "x"}}{{end}}]{{end}}{{end}}
+{{define "c" "b"}}{{fill "x"}}c{{end}}{{end}}`,
+			``,
+		},
+	}
+	for _, test := range tests {
+		set, err := new(Set).Parse(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = set.Compile()
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%q: unexpected error: %s", test.input, err)
+		case test.err != "" && err == nil:
+			t.Errorf("%q: expected error %q", test.input, test.err)
+		case test.err != "" && err.Error() != test.err:
+			t.Errorf("%q: expected error %q, got %q", test.input, test.err, err)
+		}
+	}
+}
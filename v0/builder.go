@@ -0,0 +1,63 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+// SetBuilder accumulates the options and the template sources of a set,
+// and builds it in one step. It separates the two stages in the life of a
+// set: templates are added to the builder, and the built set is compiled,
+// so templates can't be added to it.
+//
+//     set, err := template.NewSetBuilder(template.Config{Escape: true}).
+//         ParseGlob("templates/*.html").
+//         Parse(extra).
+//         Build()
+type SetBuilder struct {
+	config  Config
+	sources []func(*Set) (*Set, error)
+}
+
+// NewSetBuilder returns a builder of sets with the options from config.
+func NewSetBuilder(config Config) *SetBuilder {
+	return &SetBuilder{config: config}
+}
+
+// add adds a template source to the builder.
+func (b *SetBuilder) add(source func(*Set) (*Set, error)) *SetBuilder {
+	b.sources = append(b.sources, source)
+	return b
+}
+
+// Parse adds the template definitions in text, as Set.Parse does. The
+// return value is the builder, so calls can be chained.
+func (b *SetBuilder) Parse(text string) *SetBuilder {
+	return b.add(func(s *Set) (*Set, error) {
+		return s.Parse(text)
+	})
+}
+
+// ParseTemplate adds the named template with the given text, as
+// Set.ParseTemplate does. The return value is the builder, so calls can be
+// chained.
+func (b *SetBuilder) ParseTemplate(name, text string) *SetBuilder {
+	return b.add(func(s *Set) (*Set, error) {
+		return s.ParseTemplate(name, text)
+	})
+}
+
+// Build creates a set with the options of the builder, parses the sources
+// in the order they were added and compiles the set. The first error is
+// returned. Each call builds a new set, reading the sources again.
+func (b *SetBuilder) Build() (*Set, error) {
+	s, err := NewSet(b.config)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range b.sources {
+		if _, err := source(s); err != nil {
+			return nil, err
+		}
+	}
+	return s.Compile()
+}
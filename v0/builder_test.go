@@ -0,0 +1,48 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetBuilder(t *testing.T) {
+	builder := NewSetBuilder(Config{Escape: true}).
+		ParseGlob("testdata/tmpl*.tmpl").
+		Parse(templateFileExecTests[0].input)
+	set, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := templateFileExecTests[0].output; b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+	if _, err := set.Parse(`{{define "z"}}z{{end}}`); err == nil {
+		t.Errorf("expected error adding templates to a built set")
+	}
+	// Each build creates a new set.
+	other, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == set {
+		t.Errorf("expected a new set")
+	}
+
+	if _, err := NewSetBuilder(Config{}).ParseFiles("testdata/missing.tmpl").Build(); err == nil {
+		t.Errorf("expected error for a missing file")
+	}
+	if _, err := NewSetBuilder(Config{}).Parse(`{{define "a"}}{{template "b"}}{{end}}`).ParseTemplate("b", "{{template `a`}}").Build(); err == nil {
+		t.Errorf("expected compilation error")
+	}
+	if _, err := NewSetBuilder(Config{Placeholder: "?"}).Build(); err == nil {
+		t.Errorf("expected config error")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cache defines a Cache interface for values such as rendered
+// output, so that applications can store it in memory or in a shared
+// store interchangeably, and provides an in-memory LRU cache and a no-op
+// cache. The template package stores rendered output in a Cache with
+// Set.OutputCache.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache stores values by key. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the value stored for key, and whether it was found.
+	Get(key string) (value interface{}, ok bool)
+	// Set stores the value for key. If ttl is positive, the value expires
+	// after that duration; otherwise it doesn't expire.
+	Set(key string, value interface{}, ttl time.Duration)
+	// Delete removes the value stored for key, if any.
+	Delete(key string)
+}
+
+// NoOp is a cache that stores nothing, to disable caching.
+type NoOp struct{}
+
+// Get always reports that the key was not found.
+func (NoOp) Get(key string) (interface{}, bool) {
+	return nil, false
+}
+
+// Set does nothing.
+func (NoOp) Set(key string, value interface{}, ttl time.Duration) {}
+
+// Delete does nothing.
+func (NoOp) Delete(key string) {}
+
+// LRU is an in-memory cache that holds a bounded number of values. When it
+// is full, storing a new value evicts the least recently used one.
+type LRU struct {
+	mutex   sync.Mutex
+	size    int
+	list    *list.List // Of *entry, most recently used first.
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+// entry is a value stored in an LRU cache.
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time // Zero if the value doesn't expire.
+}
+
+// NewLRU returns an LRU cache that holds at most size values. A size of
+// zero or less means no limit.
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:    size,
+		list:    list.New(),
+		entries: make(map[string]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// Get returns the value stored for key, and whether it was found. The
+// value becomes the most recently used.
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if !e.expires.IsZero() && !c.now().Before(e.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.list.MoveToFront(elem)
+	return e.value, true
+}
+
+// Set stores the value for key, evicting the least recently used value if
+// the cache is full.
+func (c *LRU) Set(key string, value interface{}, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry)
+		e.value, e.expires = value, expires
+		c.list.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.list.PushFront(&entry{key, value, expires})
+	if c.size > 0 && c.list.Len() > c.size {
+		c.remove(c.list.Back())
+	}
+}
+
+// Delete removes the value stored for key, if any.
+func (c *LRU) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len returns the number of values in the cache, including expired values
+// that were not removed yet.
+func (c *LRU) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.list.Len()
+}
+
+// remove removes the element from the cache. The caller must hold the
+// mutex.
+func (c *LRU) remove(elem *list.Element) {
+	c.list.Remove(elem)
+	delete(c.entries, elem.Value.(*entry).key)
+}
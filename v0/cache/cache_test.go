@@ -0,0 +1,68 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// Both implementations satisfy the interface.
+var _ = []Cache{NoOp{}, NewLRU(1)}
+
+func TestLRU(t *testing.T) {
+	c := NewLRU(2)
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a): expected 1, true; got %v, %v", v, ok)
+	}
+	// "b" is the least recently used.
+	c.Set("c", 3, 0)
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c): expected 3, true; got %v, %v", v, ok)
+	}
+	c.Set("a", 4, 0)
+	if v, _ := c.Get("a"); v != 4 {
+		t.Errorf("Get(a): expected 4; got %v", v)
+	}
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected a to be deleted")
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected 1 value; got %d", c.Len())
+	}
+}
+
+func TestLRUTTL(t *testing.T) {
+	now := time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(0)
+	c.now = func() time.Time { return now }
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, 0)
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("expected a before its expiration")
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected a to expire")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Errorf("expected b not to expire")
+	}
+}
+
+func TestNoOp(t *testing.T) {
+	var c NoOp
+	c.Set("a", 1, 0)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected NoOp to store nothing")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/gorilla/template/v0/escape"
+	"github.com/gorilla/template/v0/parse"
+)
+
+// The {{capture}} action executes its contents and assigns the output to
+// a variable instead of writing it, so that it can be written more than
+// once, or elsewhere:
+//
+//	{{capture $title}}{{.Name}} - {{.Site}}{{end}}
+//	<title>{{$title}}</title>
+//	...
+//	<h1>{{$title}}</h1>
+//
+// The variable is visible after the action, until the end of the enclosing
+// control structure. In an escaped set, the contents are escaped for the
+// context of the action, which must be HTML text, a script or a style
+// sheet, and the output is typed as safe content for that context, so it
+// is not escaped again when written in the same kind of context.
+
+// walkCapture executes a {{capture}} node.
+func (s *state) walkCapture(dot reflect.Value, c *parse.CaptureNode) {
+	var b bytes.Buffer
+	func() {
+		saved := s.wr
+		defer func() { s.wr = saved }()
+		defer s.pop(s.mark())
+		s.wr = &b
+		if s.snap.indent {
+			s.wr = newIndentWriter(&b, nil)
+		}
+		s.walk(dot, c.List)
+	}()
+	s.push(c.Variable.Ident[0], reflect.ValueOf(capturedValue(c.Content, b.String())))
+}
+
+// capturedValue returns the output of a capture typed as the given
+// content.
+func capturedValue(content, output string) interface{} {
+	switch content {
+	case "html":
+		return escape.HTML(output)
+	case "js":
+		return escape.JS(output)
+	case "css":
+		return escape.CSS(output)
+	}
+	return output
+}
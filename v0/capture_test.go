@@ -0,0 +1,69 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	tests := []struct {
+		input, output string
+	}{
+		{`{{capture $x}}<b>{{.}}</b>{{end}}[{{$x}}|{{$x}}]`, `[<b>&lt;A&gt;</b>|<b>&lt;A&gt;</b>]`},
+		{`{{capture $x}}{{range $i, $c := strings.split "a,b" ","}}{{$i}}{{end}}{{end}}{{$x}}`, `01`},
+		{`{{if true}}{{capture $x}}y{{end}}{{$x}}{{end}}`, `y`},
+		{`<script>{{capture $x}}f({{.}}){{end}}{{$x}};</script>`, `<script>f("\u003cA\u003e");</script>`},
+		{`{{capture $x}}x{{end}}<a title="{{$x}}">`, `<a title="x">`},
+	}
+	for _, test := range tests {
+		s, err := new(Set).Escape().Parse(`{{define "t"}}` + test.input + `{{end}}`)
+		if err != nil {
+			t.Errorf("%s: %s", test.input, err)
+			continue
+		}
+		var b bytes.Buffer
+		if err := s.Execute(&b, "t", "<A>"); err != nil {
+			t.Errorf("%s: %s", test.input, err)
+			continue
+		}
+		if b.String() != test.output {
+			t.Errorf("%s: got %q, want %q", test.input, b.String(), test.output)
+		}
+	}
+	// Without escaping, the output is a string.
+	var b bytes.Buffer
+	s := Must(new(Set).Parse(`{{define "t"}}{{capture $x}}<{{.}}>{{end}}{{printf "%T %s" $x $x}}{{end}}`))
+	if err := s.Execute(&b, "t", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "string <a>"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestCaptureErrors(t *testing.T) {
+	tests := []struct {
+		input, err string
+	}{
+		{`{{capture $x}}{{end}}{{$y}}`, `undefined variable "$y"`},
+		{`{{capture $x}}{{$y := 1}}{{end}}{{$y}}`, `undefined variable "$y"`},
+		{`{{capture $x}}{{$x}}{{end}}`, `undefined variable "$x"`},
+		{`{{capture .X}}{{end}}`, `unexpected ".X" in capture`},
+		{`<a title="{{capture $x}}{{end}}">`, `{{capture $x}} in unsupported context`},
+		{`{{capture $x}}<a href="{{end}}`, `{{capture $x}} ends in context`},
+	}
+	for _, test := range tests {
+		s, err := new(Set).Escape().Parse(`{{define "t"}}` + test.input + `{{end}}`)
+		if err == nil {
+			_, err = s.Compile()
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.input, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Coerce converts a and b to a common basic type, so that they can be
+// compared or used in arithmetic. This is what the comparison functions
+// (eq, ne, lt, le, gt, ge) and the functions in the math namespace do with
+// their arguments.
+//
+// The returned values have one of the types bool, int64, uint64, float64,
+// complex128 or string. Pointers are followed and named types are treated
+// according to their underlying kind, so escape.HTML coerces to string and
+// time.Duration to int64. The rules are:
+//
+//   - booleans coerce only with booleans, to bool;
+//   - strings coerce only with strings, to string: numbers are never parsed
+//     from or formatted to strings;
+//   - signed integers coerce to int64 and unsigned integers to uint64;
+//   - a signed and an unsigned integer coerce to int64 if the unsigned value
+//     fits, to uint64 if the signed value is not negative, and to float64
+//     otherwise;
+//   - an integer and a float coerce to float64;
+//   - a complex and any other number coerce to complex128.
+//
+// Any other combination, including nil values, returns an error.
+func Coerce(a, b interface{}) (x, y interface{}, err error) {
+	v, w, err := coerce(reflect.ValueOf(a), reflect.ValueOf(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	return v.Interface(), w.Interface(), nil
+}
+
+// basicKind classifies values for coercion.
+type basicKind int
+
+const (
+	invalidKind basicKind = iota
+	boolKind
+	intKind
+	uintKind
+	floatKind
+	complexKind
+	stringKind
+)
+
+// basicKindOf returns the basic kind of v, after following pointers.
+func basicKindOf(v reflect.Value) (basicKind, reflect.Value) {
+	v, _ = indirect(v)
+	switch v.Kind() {
+	case reflect.Bool:
+		return boolKind, v
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intKind, v
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintKind, v
+	case reflect.Float32, reflect.Float64:
+		return floatKind, v
+	case reflect.Complex64, reflect.Complex128:
+		return complexKind, v
+	case reflect.String:
+		return stringKind, v
+	}
+	return invalidKind, v
+}
+
+// coerce implements Coerce using reflect.Values.
+func coerce(a, b reflect.Value) (x, y reflect.Value, err error) {
+	ka, a := basicKindOf(a)
+	kb, b := basicKindOf(b)
+	if ka == invalidKind || kb == invalidKind {
+		return zero, zero, fmt.Errorf("can't coerce %s and %s", typeString(a), typeString(b))
+	}
+	if ka > kb {
+		// Sort to check fewer combinations.
+		x, y, err = coerce(b, a)
+		return y, x, err
+	}
+	switch {
+	case ka == boolKind && kb == boolKind:
+		return reflect.ValueOf(a.Bool()), reflect.ValueOf(b.Bool()), nil
+	case ka == stringKind && kb == stringKind:
+		return reflect.ValueOf(a.String()), reflect.ValueOf(b.String()), nil
+	case ka == boolKind || kb == stringKind:
+		// A bool with something else, or a string with something else.
+	case ka == intKind && kb == intKind:
+		return reflect.ValueOf(a.Int()), reflect.ValueOf(b.Int()), nil
+	case ka == uintKind && kb == uintKind:
+		return reflect.ValueOf(a.Uint()), reflect.ValueOf(b.Uint()), nil
+	case ka == intKind && kb == uintKind:
+		i, u := a.Int(), b.Uint()
+		switch {
+		case u <= math.MaxInt64:
+			return reflect.ValueOf(i), reflect.ValueOf(int64(u)), nil
+		case i >= 0:
+			return reflect.ValueOf(uint64(i)), reflect.ValueOf(u), nil
+		}
+		return reflect.ValueOf(float64(i)), reflect.ValueOf(float64(u)), nil
+	case kb == floatKind:
+		return reflect.ValueOf(toFloat(a)), reflect.ValueOf(b.Float()), nil
+	case kb == complexKind:
+		return reflect.ValueOf(toComplex(a)), reflect.ValueOf(b.Complex()), nil
+	}
+	return zero, zero, fmt.Errorf("incompatible types %s and %s", a.Type(), b.Type())
+}
+
+// typeString returns the type of v for error messages.
+func typeString(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return v.Type().String()
+}
+
+// toFloat converts a number that is not complex to float64.
+func toFloat(v reflect.Value) float64 {
+	switch k, _ := basicKindOf(v); k {
+	case intKind:
+		return float64(v.Int())
+	case uintKind:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+// toComplex converts a number to complex128.
+func toComplex(v reflect.Value) complex128 {
+	if k, _ := basicKindOf(v); k == complexKind {
+		return v.Complex()
+	}
+	return complex(toFloat(v), 0)
+}
+
+// Comparison.
+
+// compare coerces a and b and returns -1, 0 or +1 depending on whether a is
+// less than, equal or greater than b. If ordered is false only equality is
+// checked, and the result is 0 for equal values and 1 otherwise.
+func compare(a, b interface{}, ordered bool) (int, error) {
+	x, y, err := Coerce(a, b)
+	if err != nil {
+		return 0, err
+	}
+	var less bool
+	switch x := x.(type) {
+	case bool:
+		if ordered {
+			return 0, fmt.Errorf("can't order booleans")
+		}
+	case int64:
+		less = x < y.(int64)
+	case uint64:
+		less = x < y.(uint64)
+	case float64:
+		less = x < y.(float64)
+	case complex128:
+		if ordered {
+			return 0, fmt.Errorf("can't order complex numbers")
+		}
+	case string:
+		less = x < y.(string)
+	}
+	switch {
+	case x == y:
+		return 0, nil
+	case less:
+		return -1, nil
+	}
+	return 1, nil
+}
+
+// eq returns whether a == b.
+func eq(a, b interface{}) (bool, error) {
+	c, err := compare(a, b, false)
+	return c == 0, err
+}
+
+// ne returns whether a != b.
+func ne(a, b interface{}) (bool, error) {
+	c, err := compare(a, b, false)
+	return c != 0, err
+}
+
+// lt returns whether a < b.
+func lt(a, b interface{}) (bool, error) {
+	c, err := compare(a, b, true)
+	return c < 0, err
+}
+
+// le returns whether a <= b.
+func le(a, b interface{}) (bool, error) {
+	c, err := compare(a, b, true)
+	return c <= 0, err
+}
+
+// gt returns whether a > b.
+func gt(a, b interface{}) (bool, error) {
+	c, err := compare(a, b, true)
+	return c > 0, err
+}
+
+// ge returns whether a >= b.
+func ge(a, b interface{}) (bool, error) {
+	c, err := compare(a, b, true)
+	return c >= 0, err
+}
+
+// Arithmetic.
+
+// mathAdd returns the sum of its arguments.
+func mathAdd(a, b interface{}) (interface{}, error) {
+	return arith("add", a, b)
+}
+
+// mathSub returns the difference of its arguments.
+func mathSub(a, b interface{}) (interface{}, error) {
+	return arith("sub", a, b)
+}
+
+// mathMul returns the product of its arguments.
+func mathMul(a, b interface{}) (interface{}, error) {
+	return arith("mul", a, b)
+}
+
+// mathDiv returns the quotient of its arguments. The division of integers
+// truncates the result.
+func mathDiv(a, b interface{}) (interface{}, error) {
+	return arith("div", a, b)
+}
+
+// mathMod returns the remainder of the division of its integer arguments.
+func mathMod(a, b interface{}) (interface{}, error) {
+	return arith("mod", a, b)
+}
+
+// arith coerces a and b and applies the named operation. Results of signed
+// integer operations have type int, like integer constants in templates.
+// Integer operations whose result overflows, including subtractions of
+// unsigned integers that would be negative, are errors.
+func arith(op string, a, b interface{}) (interface{}, error) {
+	x, y, err := Coerce(a, b)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case int64:
+		y := y.(int64)
+		if (op == "div" || op == "mod") && y == 0 {
+			return nil, fmt.Errorf("integer division by zero")
+		}
+		var r int64
+		overflow := false
+		switch op {
+		case "add":
+			r = x + y
+			overflow = (y > 0 && r < x) || (y < 0 && r > x)
+		case "sub":
+			r = x - y
+			overflow = (y > 0 && r > x) || (y < 0 && r < x)
+		case "mul":
+			r = x * y
+			overflow = x != 0 && (r/x != y || (x == -1 && y == math.MinInt64))
+		case "div":
+			r = x / y
+			overflow = x == math.MinInt64 && y == -1
+		case "mod":
+			r = x % y
+		}
+		if overflow {
+			return nil, fmt.Errorf("integer overflow in %s", op)
+		}
+		if int64(int(r)) == r {
+			return int(r), nil
+		}
+		return r, nil
+	case uint64:
+		y := y.(uint64)
+		if (op == "div" || op == "mod") && y == 0 {
+			return nil, fmt.Errorf("integer division by zero")
+		}
+		switch op {
+		case "add":
+			if x+y < x {
+				return nil, fmt.Errorf("integer overflow in %s", op)
+			}
+			return x + y, nil
+		case "sub":
+			if y > x {
+				return nil, fmt.Errorf("integer overflow in %s", op)
+			}
+			return x - y, nil
+		case "mul":
+			if x != 0 && x*y/x != y {
+				return nil, fmt.Errorf("integer overflow in %s", op)
+			}
+			return x * y, nil
+		case "div":
+			return x / y, nil
+		case "mod":
+			return x % y, nil
+		}
+	case float64:
+		y := y.(float64)
+		switch op {
+		case "add":
+			return x + y, nil
+		case "sub":
+			return x - y, nil
+		case "mul":
+			return x * y, nil
+		case "div":
+			return x / y, nil
+		}
+	case complex128:
+		y := y.(complex128)
+		switch op {
+		case "add":
+			return x + y, nil
+		case "sub":
+			return x - y, nil
+		case "mul":
+			return x * y, nil
+		case "div":
+			return x / y, nil
+		}
+	}
+	return nil, fmt.Errorf("can't %s %T values", op, x)
+}
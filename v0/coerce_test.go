@@ -0,0 +1,148 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gorilla/template/v0/escape"
+)
+
+type coerceTest struct {
+	a, b interface{}
+	x, y interface{} // nil for an error.
+}
+
+var (
+	three    = 3
+	nilInt   *int
+	maxUint  = uint64(math.MaxUint64)
+	bigUint8 = uint8(200)
+)
+
+var coerceTests = []coerceTest{
+	// Booleans.
+	{true, false, true, false},
+	{true, 1, nil, nil},
+	{true, "true", nil, nil},
+	// Strings.
+	{"a", "b", "a", "b"},
+	{escape.HTML("<b>"), "b", "<b>", "b"},
+	{"1", 1, nil, nil},
+	{1.5, "1.5", nil, nil},
+	// Signed integers.
+	{1, 2, int64(1), int64(2)},
+	{int8(-1), int64(2), int64(-1), int64(2)},
+	{time.Second, 1, int64(time.Second), int64(1)},
+	// Unsigned integers.
+	{uint(1), uint8(2), uint64(1), uint64(2)},
+	{maxUint, uint16(1), maxUint, uint64(1)},
+	// Signed and unsigned integers.
+	{-1, bigUint8, int64(-1), int64(200)},
+	{uint32(7), 2, int64(7), int64(2)},
+	{1, maxUint, uint64(1), maxUint},
+	{-1, maxUint, float64(-1), float64(maxUint)},
+	// Integers and floats.
+	{1, 1.5, float64(1), 1.5},
+	{float32(0.5), uint(2), float64(0.5), float64(2)},
+	{0.5, 0.25, 0.5, 0.25},
+	// Complex numbers.
+	{1i, 2, 1i, complex(2, 0)},
+	{1.5, complex64(1i), complex(1.5, 0), 1i},
+	// Pointers.
+	{&three, 3, int64(3), int64(3)},
+	{nilInt, 3, nil, nil},
+	// Other types and nil.
+	{nil, nil, nil, nil},
+	{nil, 1, nil, nil},
+	{[]int{1}, []int{1}, nil, nil},
+	{struct{}{}, struct{}{}, nil, nil},
+}
+
+func TestCoerce(t *testing.T) {
+	for _, test := range coerceTests {
+		x, y, err := Coerce(test.a, test.b)
+		if test.x == nil {
+			if err == nil {
+				t.Errorf("Coerce(%#v, %#v): expected error, got %#v, %#v", test.a, test.b, x, y)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Coerce(%#v, %#v): unexpected error: %s", test.a, test.b, err)
+			continue
+		}
+		if x != test.x || y != test.y {
+			t.Errorf("Coerce(%#v, %#v): expected %#v, %#v, got %#v, %#v", test.a, test.b, test.x, test.y, x, y)
+		}
+		// Coercion is symmetric.
+		y, x, err = Coerce(test.b, test.a)
+		if err != nil || x != test.x || y != test.y {
+			t.Errorf("Coerce(%#v, %#v): expected %#v, %#v, got %#v, %#v (%v)", test.b, test.a, test.y, test.x, y, x, err)
+		}
+	}
+}
+
+var comparisonTests = []execTest{
+	{"eq ints", "{{eq 1 1}} {{eq 1 2}}", "true false", nil, true},
+	{"eq mixed ints", "{{eq .I 17}} {{eq .U16 16}}", "true true", tVal, true},
+	{"eq int float", "{{eq 1 1.0}}", "true", nil, true},
+	{"eq strings", `{{eq .X "x"}}`, "true", tVal, true},
+	{"eq bools", "{{eq .True true}}", "true", tVal, true},
+	{"eq string int", `{{eq "1" 1}}`, "", nil, false},
+	{"eq complex", "{{eq 1i 1i}}", "true", nil, true},
+	{"ne", "{{ne 1 2}} {{ne 1 1}}", "true false", nil, true},
+	{"lt", "{{lt 1 2}} {{lt 2 1}} {{lt 1 1}}", "true false false", nil, true},
+	{"le", "{{le 1 2}} {{le 2 1}} {{le 1 1}}", "true false true", nil, true},
+	{"gt", "{{gt 1 2}} {{gt 2 1}} {{gt 1 1}}", "false true false", nil, true},
+	{"ge", "{{ge 1 2}} {{ge 2 1}} {{ge 1 1}}", "false true true", nil, true},
+	{"lt strings", `{{lt "a" "b"}}`, "true", nil, true},
+	{"lt negative uint", "{{lt -1 .U16}}", "true", tVal, true},
+	{"lt bools", "{{lt true false}}", "", nil, false},
+	{"lt complex", "{{lt 1i 2i}}", "", nil, false},
+	{"in if", "{{if lt .I 20}}small{{end}}", "small", tVal, true},
+}
+
+func TestComparison(t *testing.T) {
+	testExecute(comparisonTests, nil, t)
+}
+
+var arithTests = []execTest{
+	{"add", "{{math.add 1 2}}", "3", nil, true},
+	{"add mixed ints", "{{math.add .I .U16}}", "33", tVal, true},
+	{"add int result", "{{typeOf (math.add .U16 -1)}}", "int", tVal, true},
+	{"add uints", "{{typeOf (math.add .U16 .U16)}}", "uint64", tVal, true},
+	{"add float", "{{math.add 1 0.5}}", "1.5", nil, true},
+	{"add complex", "{{math.add 1 1i}}", "(1+1i)", nil, true},
+	{"add strings", `{{math.add "a" "b"}}`, "", nil, false},
+	{"sub", "{{math.sub 1 2}}", "-1", nil, true},
+	{"mul", "{{math.mul 3 .I}}", "51", tVal, true},
+	{"div int", "{{math.div 7 2}}", "3", nil, true},
+	{"div float", "{{math.div 7 2.0}}", "3.5", nil, true},
+	{"div by zero", "{{math.div 7 0}}", "", nil, false},
+	{"mod", "{{math.mod 7 2}}", "1", nil, true},
+	{"mod float", "{{math.mod 7.5 2}}", "", nil, false},
+	{"mod by zero", "{{math.mod 7 0}}", "", nil, false},
+	{"nil pointer", "{{math.add .NIL 1}}", "", tVal, false},
+	{"pointer", "{{math.add .PI 1}}", "24", tVal, true},
+	{"add overflow", "{{math.add 9223372036854775807 1}}", "", nil, false},
+	{"add negative overflow", "{{math.add -9223372036854775808 -1}}", "", nil, false},
+	{"sub overflow", "{{math.sub -9223372036854775808 1}}", "", nil, false},
+	{"mul overflow", "{{math.mul 4294967296 4294967296}}", "", nil, false},
+	{"mul min overflow", "{{math.mul -1 -9223372036854775808}}", "", nil, false},
+	{"div overflow", "{{math.div -9223372036854775808 -1}}", "", nil, false},
+	{"mul negative", "{{math.mul -3 4}}", "-12", nil, true},
+	{"sub uints", "{{math.sub .U16 .U16}}", "0", tVal, true},
+	{"sub uints underflow", "{{math.sub (math.sub .U16 .U16) .U16}}", "", tVal, false},
+	{"add uints overflow", "{{math.add . .}}", "", ^uint64(0), false},
+	{"mul uints overflow", "{{math.mul . .}}", "", ^uint64(0), false},
+	{"mul uints", "{{math.mul . 2}}", "4", uint64(2), true},
+}
+
+func TestArith(t *testing.T) {
+	testExecute(arithTests, nil, t)
+}
@@ -0,0 +1,175 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package compat provides a Template type with the API of html/template,
+// backed by a template.Set, to help migrating code incrementally.
+//
+// Associated templates share a set, as in html/template. Contextual
+// escaping is always enabled. Unlike html/template, the text outside
+// {{define}} actions is ignored when the text contains any.
+package compat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/gorilla/template/v0"
+)
+
+// FuncMap is the type of the map defining the mapping from names to
+// functions.
+type FuncMap template.FuncMap
+
+// Template is a named template in a set of associated templates.
+type Template struct {
+	name string
+	set  *template.Set
+}
+
+// New allocates a new template with the given name.
+func New(name string) *Template {
+	return &Template{name: name, set: new(template.Set).Escape()}
+}
+
+// Must is a helper that wraps a call to a function returning
+// (*Template, error) and panics if the error is non-nil.
+func Must(t *Template, err error) *Template {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string {
+	return t.name
+}
+
+// New allocates a new template associated with t, with the given name.
+func (t *Template) New(name string) *Template {
+	return &Template{name: name, set: t.set}
+}
+
+// Set returns the set that holds t and its associated templates.
+func (t *Template) Set() *template.Set {
+	return t.set
+}
+
+// Delims sets the action delimiters for subsequent calls to Parse.
+func (t *Template) Delims(left, right string) *Template {
+	t.set.Delims(left, right)
+	return t
+}
+
+// Funcs adds the elements of the argument map to the function map of the
+// associated templates.
+func (t *Template) Funcs(funcMap FuncMap) *Template {
+	t.set.Funcs(template.FuncMap(funcMap))
+	return t
+}
+
+// Parse parses text as the contents of t. If the text contains {{define}}
+// actions, the templates they define are associated with t instead.
+func (t *Template) Parse(text string) (*Template, error) {
+	if _, err := t.set.ParseTemplate(t.name, text); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseFiles parses the named files and associates the resulting templates
+// with t. A file without {{define}} actions defines a template named after
+// the base name of the file.
+func (t *Template) ParseFiles(filenames ...string) (*Template, error) {
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("template: no files named in call to ParseFiles")
+	}
+	for _, filename := range filenames {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = t.set.ParseTemplate(filepath.Base(filename), string(b)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// ParseGlob parses the files matched by the pattern like ParseFiles.
+func (t *Template) ParseGlob(pattern string) (*Template, error) {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("template: pattern matches no files: %#q", pattern)
+	}
+	return t.ParseFiles(filenames...)
+}
+
+// Execute applies t to the data object and writes the output to wr.
+func (t *Template) Execute(wr io.Writer, data interface{}) error {
+	return t.set.Execute(wr, t.name, data)
+}
+
+// ExecuteTemplate applies the template associated with t that has the
+// given name to the data object and writes the output to wr.
+func (t *Template) ExecuteTemplate(wr io.Writer, name string, data interface{}) error {
+	return t.set.Execute(wr, name, data)
+}
+
+// Lookup returns the template with the given name that is associated with
+// t, or nil if there is no such template.
+func (t *Template) Lookup(name string) *Template {
+	for _, n := range t.set.Names() {
+		if n == name {
+			return &Template{name: name, set: t.set}
+		}
+	}
+	return nil
+}
+
+// Templates returns the templates associated with t, including t itself if
+// it was defined.
+func (t *Template) Templates() []*Template {
+	var templates []*Template
+	for _, name := range t.set.Names() {
+		templates = append(templates, &Template{name: name, set: t.set})
+	}
+	return templates
+}
+
+// DefinedTemplates returns a string listing the defined templates,
+// prefixed by the string "; defined templates are: ". If there are none,
+// it returns the empty string.
+func (t *Template) DefinedTemplates() string {
+	names := t.set.Names()
+	if len(names) == 0 {
+		return ""
+	}
+	b := new(bytes.Buffer)
+	for i, name := range names {
+		if i == 0 {
+			b.WriteString("; defined templates are: ")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%q", name)
+	}
+	return b.String()
+}
+
+// Clone returns a duplicate of the template and its associated templates.
+// Templates can be added to the copy without affecting the original.
+func (t *Template) Clone() (*Template, error) {
+	set, err := t.set.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &Template{name: t.name, set: set}, nil
+}
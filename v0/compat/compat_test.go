@@ -0,0 +1,56 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package compat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	tmpl := Must(New("page").Funcs(FuncMap{"upper": strings.ToUpper}).Parse(`<p>{{upper .}}</p>{{template "footer"}}`))
+	Must(tmpl.New("footer").Parse(`<footer>{{"&"}}</footer>`))
+	Must(tmpl.Parse(`{{define "other"}}other{{end}}`))
+	b := new(bytes.Buffer)
+	if err := tmpl.Execute(b, "<b>"); err != nil {
+		t.Fatal(err)
+	}
+	expect := "<p>&lt;B&gt;</p><footer>&amp;</footer>"
+	if b.String() != expect {
+		t.Errorf("expected %q; got %q", expect, b.String())
+	}
+	b.Reset()
+	if err := tmpl.ExecuteTemplate(b, "other", nil); err != nil || b.String() != "other" {
+		t.Errorf("expected %q; got %q, %v", "other", b.String(), err)
+	}
+	if tmpl.Lookup("footer") == nil || tmpl.Lookup("missing") != nil {
+		t.Errorf("unexpected Lookup results")
+	}
+	if n := len(tmpl.Templates()); n != 3 {
+		t.Errorf("expected 3 templates; got %d", n)
+	}
+	expect = `; defined templates are: "footer", "other", "page"`
+	if s := tmpl.DefinedTemplates(); s != expect {
+		t.Errorf("expected %q; got %q", expect, s)
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	tmpl, err := New("root").ParseFiles("../testdata/auto.tmpl", "../testdata/file1.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err := tmpl.ExecuteTemplate(b, "auto.tmpl", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "auto x!\n" {
+		t.Errorf("expected %q; got %q", "auto x!\n", b.String())
+	}
+	if tmpl.Lookup("x") == nil {
+		t.Errorf("template defined in file not found")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Config holds the options of a set, to create it with NewSet instead of
+// calling the option methods one by one. The zero value holds the
+// defaults.
+type Config struct {
+	LeftDelim       string       // The left action delimiter; see Set.Delims.
+	RightDelim      string       // The right action delimiter; see Set.Delims.
+	Syntax          Syntax       // The syntax of the templates; see Set.Syntax.
+	AutoDefine      bool         // See Set.AutoDefine.
+	FileInheritance bool         // See Set.FileInheritance; needs AutoDefine.
+	Funcs           FuncMap      // Functions added to the set; see Set.Funcs.
+	FlatBuiltins    bool         // See Set.FlatBuiltins.
+	Escape          bool         // Turns on contextual escaping; see Set.Escape.
+	RetainSource    SourcePolicy // See Set.RetainSource.
+	PrintNil        NilPolicy    // How nil and missing values print; see Set.PrintNil.
+	Lenient         bool         // See Set.Lenient.
+	Placeholder     string       // The output of failed actions; needs Lenient.
+	Profile         *Profile     // See Set.Profile.
+	Limits          Limits       // Bounds on the templates parsed; see Set.Limit.
+}
+
+// NewSet returns a new set with the options from config. The options are
+// validated together, and all the problems found are reported in the
+// returned error.
+func NewSet(config Config) (*Set, error) {
+	var errs []string
+	if config.Syntax < SyntaxNative || config.Syntax > SyntaxMustache {
+		errs = append(errs, fmt.Sprintf("unknown syntax %d", config.Syntax))
+	} else if config.Syntax != SyntaxNative && (config.LeftDelim != "" || config.RightDelim != "") {
+		errs = append(errs, "delimiters only apply to the native syntax")
+	}
+	if config.FileInheritance && !config.AutoDefine && config.Syntax == SyntaxNative {
+		errs = append(errs, "FileInheritance needs AutoDefine")
+	}
+	names := make([]string, 0, len(config.Funcs))
+	for name := range config.Funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn := config.Funcs[name]
+		if err := checkReservedFunc(name); err != nil {
+			errs = append(errs, strings.TrimPrefix(err.Error(), "template: "))
+			continue
+		}
+		if v := reflect.ValueOf(fn); v.Kind() != reflect.Func {
+			errs = append(errs, fmt.Sprintf("value for %q is not a function", name))
+		} else if !goodFunc(v.Type()) {
+			errs = append(errs, fmt.Sprintf("function %q has %d results", name, v.Type().NumOut()))
+		}
+	}
+	if config.RetainSource < SourceKeep || config.RetainSource > SourceLines {
+		errs = append(errs, fmt.Sprintf("unknown source policy %d", config.RetainSource))
+	}
+	if config.PrintNil < NilPrint || config.PrintNil > NilError {
+		errs = append(errs, fmt.Sprintf("unknown nil policy %d", config.PrintNil))
+	}
+	if config.Placeholder != "" && !config.Lenient {
+		errs = append(errs, "Placeholder needs Lenient")
+	}
+	if l := config.Limits; l.MaxTemplateSize < 0 || l.MaxTotalSize < 0 || l.MaxFiles < 0 {
+		errs = append(errs, "negative limit")
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("template: invalid config: %s", strings.Join(errs, "; "))
+	}
+	s := new(Set).Delims(config.LeftDelim, config.RightDelim).Syntax(config.Syntax)
+	if config.AutoDefine {
+		s.AutoDefine()
+	}
+	if config.FileInheritance {
+		s.FileInheritance()
+	}
+	s.Funcs(config.Funcs)
+	if config.FlatBuiltins {
+		s.FlatBuiltins()
+	}
+	if config.Escape {
+		s.Escape()
+	}
+	s.RetainSource(config.RetainSource).PrintNil(config.PrintNil).Profile(config.Profile)
+	s.Limit(config.Limits)
+	if config.Lenient {
+		s.Lenient(config.Placeholder)
+	}
+	return s, nil
+}
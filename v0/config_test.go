@@ -0,0 +1,60 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewSet(t *testing.T) {
+	set, err := NewSet(Config{
+		LeftDelim:    "[[",
+		RightDelim:   "]]",
+		Funcs:        FuncMap{"shout": func(s string) string { return s + "!" }},
+		FlatBuiltins: true,
+		Escape:       true,
+		PrintNil:     NilEmpty,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Must(set.Parse(`[[define "a"]]<p>[[upper .X | shout]][[.Y]]</p>[[end]]`))
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "a", map[string]interface{}{"X": "<hi>", "Y": nil}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<p>&lt;HI&gt;!</p>"; b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+
+	_, err = NewSet(Config{
+		Syntax:          SyntaxJinja,
+		LeftDelim:       "<%",
+		FileInheritance: true,
+		Funcs:           FuncMap{"html": strings.ToUpper, "bad": 1},
+		Placeholder:     "?",
+	})
+	want := `template: invalid config: delimiters only apply to the native syntax; ` +
+		`value for "bad" is not a function; ` +
+		`function "html" is reserved for escaping and can't be overridden; Placeholder needs Lenient`
+	if err == nil || err.Error() != want {
+		t.Errorf("expected error\n\t%s\ngot\n\t%v", want, err)
+	}
+	if _, err = NewSet(Config{FileInheritance: true}); err == nil {
+		t.Errorf("expected error for FileInheritance without AutoDefine")
+	}
+	if _, err = NewSet(Config{Limits: Limits{MaxFiles: -1}}); err == nil {
+		t.Errorf("expected error for negative limit")
+	}
+	set, err = NewSet(Config{Limits: Limits{MaxTemplateSize: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = set.Parse(`{{define "a"}}a{{end}}`); err == nil || !strings.Contains(err.Error(), "larger than 10 bytes") {
+		t.Errorf("expected error for a template over the limit; got %v", err)
+	}
+}
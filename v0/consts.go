@@ -0,0 +1,122 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// evalConsts removes from the tree the constants defined at the top level
+// of the parsed texts with {{set $NAME := pipeline}}, and evaluates their
+// pipelines, so that all templates in the set can use them as variables.
+// The pipelines are evaluated with nil data, and can use other constants.
+// It returns an error if a template uses a constant that is not defined.
+// The caller must hold the mutex.
+func (s *Set) evalConsts() error {
+	defines := make(map[string]*parse.DefineNode)
+	for name, define := range s.tree {
+		if define.IsConstant() {
+			defines[name] = define
+		}
+	}
+	if err := checkConsts(s.tree, defines); err != nil {
+		return err
+	}
+	for name := range defines {
+		delete(s.tree, name)
+	}
+	if len(defines) == 0 {
+		return nil
+	}
+	s.consts = make(map[string]reflect.Value, len(defines))
+	snap := s.current()
+	evaluating := make(map[string]bool)
+	var eval func(name string) error
+	eval = func(name string) (err error) {
+		define := defines[name]
+		if _, done := s.consts[name]; done || define == nil {
+			return nil
+		}
+		if evaluating[name] {
+			return fmt.Errorf("template: constant %s refers to itself", name)
+		}
+		evaluating[name] = true
+		pipe := define.List.Nodes[0].(*parse.ActionNode).Pipe
+		var deps []string
+		pipeVariables(pipe, func(v *parse.VariableNode) {
+			deps = append(deps, v.Ident[0])
+		})
+		for _, dep := range deps {
+			if err = eval(dep); err != nil {
+				return err
+			}
+		}
+		defer errRecover(&err)
+		state := &state{
+			snap: snap,
+			tmpl: define,
+			wr:   ioutil.Discard,
+			vars: []variable{{"$", zero, ""}},
+		}
+		s.consts[name] = state.evalPipeline(zero, pipe)
+		return nil
+	}
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := eval(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkConsts returns an error if a template in tree, or a constant, uses
+// a variable named like a constant that is not one of defines.
+func checkConsts(tree parse.Tree, defines map[string]*parse.DefineNode) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		define := tree[name]
+		for _, v := range define.Constants() {
+			if defines[v.Ident[0]] == nil {
+				location, _ := define.ErrorContext(v)
+				return fmt.Errorf("template: %s: undefined variable %q", location, v.Ident[0])
+			}
+		}
+	}
+	return nil
+}
+
+// pipeVariables calls fn for each variable used in the commands of pipe.
+func pipeVariables(pipe *parse.PipeNode, fn func(*parse.VariableNode)) {
+	var arg func(n parse.Node)
+	arg = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ChainNode:
+			arg(n.Node)
+		case *parse.PipeNode:
+			pipeVariables(n, fn)
+		case *parse.VariableNode:
+			fn(n)
+		}
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, n := range cmd.Args {
+			arg(n)
+		}
+	}
+}
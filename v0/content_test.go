@@ -6,6 +6,7 @@ package template
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -270,3 +271,68 @@ func TestStringer(t *testing.T) {
 		t.Errorf("expected %q got %q", expect, b.String())
 	}
 }
+
+type htmler struct {
+	s string
+}
+
+func (h htmler) HTML() escape.HTML {
+	return escape.HTML("<b>" + h.s + "</b>")
+}
+
+type textMarshaler struct {
+	s string
+}
+
+func (m *textMarshaler) MarshalText() ([]byte, error) {
+	if m.s == "" {
+		return nil, errors.New("empty")
+	}
+	return []byte("<" + m.s + ">"), nil
+}
+
+func TestPrinterInterfaces(t *testing.T) {
+	data := struct {
+		H  htmler
+		PH *htmler
+		M  textMarshaler
+		E  textMarshaler
+		S  contentStringer
+	}{
+		htmler{"x"}, &htmler{"y"}, textMarshaler{"z"}, textMarshaler{}, contentStringer{3},
+	}
+	tests := []struct {
+		input  string
+		escape bool
+		output string
+		ok     bool
+	}{
+		{`{{.H}} {{.PH}}`, false, `<b>x</b> <b>y</b>`, true},
+		{`{{.H}} {{.PH}}`, true, `<b>x</b> <b>y</b>`, true},
+		{`<a title="{{.H}}">`, true, `<a title="x">`, true},
+		{`{{.M}}`, false, `<z>`, true},
+		{`{{.M}}`, true, `&lt;z&gt;`, true},
+		{`{{.S}}`, true, `string=3`, true},
+		{`{{.E}}`, false, ``, false},
+	}
+	for _, test := range tests {
+		set := Must(new(Set).Parse(`{{define "t"}}` + test.input + `{{end}}`))
+		if test.escape {
+			set.Escape()
+		}
+		b := new(bytes.Buffer)
+		err := set.Execute(b, "t", &data)
+		if test.ok && err != nil {
+			t.Errorf("%q: unexpected error: %s", test.input, err)
+			continue
+		} else if !test.ok {
+			if err == nil {
+				t.Errorf("%q: expected error", test.input)
+			}
+			continue
+		}
+		if got := b.String(); got != test.output {
+			t.Errorf("%q: expected %q, got %q", test.input, test.output, got)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"github.com/gorilla/template/v0/escape"
+)
+
+// InlineCode compiles the set and returns the inline scripts, styles,
+// event handlers and style attributes of its templates, with the actions
+// that write into them, as described in escape.FindInlineCode. It helps to
+// plan the adoption of a Content-Security-Policy, which blocks inline code
+// unless it allows it with 'unsafe-inline', nonces or hashes.
+func (s *Set) InlineCode() ([]escape.InlineCode, error) {
+	if _, err := s.Compile(); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return escape.FindInlineCode(s.tree)
+}
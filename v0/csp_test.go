@@ -0,0 +1,55 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInlineCode(t *testing.T) {
+	set := Must(new(Set).Parse(`
+{{define "page"}}<p>{{.Name}}</p>{{template "script" .}}<a onclick="go({{.ID}})" style="color: {{.Color}}">x</a>{{end}}
+{{define "script"}}<script>var user = {{.Name}}; {{if .Admin}}admin({{.ID}});{{end}}</script>{{end}}
+{{define "style"}}<style>p { color: red }</style>{{end}}
+{{define "plain"}}<p>{{.}}</p>{{end}}
+`))
+	for _, escaped := range []bool{false, true} {
+		set, err := set.Clone()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if escaped {
+			set.Escape()
+		}
+		codes, err := set.InlineCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, code := range codes {
+			got = append(got, fmt.Sprintf("%s %s %s", code.Template, code.Kind, strings.Join(code.Actions, " ")))
+		}
+		expect := []string{
+			"page event handler {{.ID}}",
+			"page style attribute {{.Color}}",
+			"script script {{.Name}} {{.ID}}",
+			"style style ",
+		}
+		if escaped {
+			expect = []string{
+				"page event handler {{.ID | html_template_jsvalescaper | html_template_attrescaper}}",
+				"page style attribute {{.Color | html_template_cssvaluefilter | html_template_attrescaper}}",
+				"script script {{.Name | html_template_jsvalescaper}} {{.ID | html_template_jsvalescaper}}",
+				"style style ",
+			}
+		}
+		if !reflect.DeepEqual(got, expect) {
+			t.Errorf("escaped %v: expected\n\t%q\ngot\n\t%q", escaped, expect, got)
+		}
+	}
+}
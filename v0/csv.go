@@ -0,0 +1,93 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// The functions of the "csv" namespace write values as fields of comma or
+// tab separated values, typically in a text template exporting a report:
+//
+//	{{csv.row "Name" "Amount"}}
+//	{{range .}}{{csv.row .Name .Amount}}
+//	{{end}}
+//
+// Rows don't include the line ending, which is written by the template.
+// Set.LineEndings(LineEndingCRLF) makes it "\r\n", as RFC 4180 requires.
+
+// csvField returns the value as a CSV field, quoted as described in RFC 4180
+// if it contains a comma, a double quote or a line break.
+func csvField(v interface{}) (string, error) {
+	return csvRow(v)
+}
+
+// csvRow returns the values as the fields of a CSV row. An argument that is
+// a slice or an array gives one field per element.
+func csvRow(args ...interface{}) (string, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(fieldStrings(args)); err != nil {
+		return "", err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// tsvField returns the value as a field of tab separated values. Tabs, line
+// breaks and backslashes, which can't appear in fields, are written as the
+// escapes "\t", "\n", "\r" and "\\".
+func tsvField(v interface{}) string {
+	return tsvRow(v)
+}
+
+// tsvRow returns the values as the fields of a row of tab separated values.
+// An argument that is a slice or an array gives one field per element.
+func tsvRow(args ...interface{}) string {
+	fields := fieldStrings(args)
+	for i, f := range fields {
+		fields[i] = tsvReplacer.Replace(f)
+	}
+	return strings.Join(fields, "\t")
+}
+
+var tsvReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+// fieldStrings returns the fields given by args, formatted as by print.
+// Slices and arrays are expanded, unless they are byte slices or implement
+// fmt.Stringer. Nil values give empty fields.
+func fieldStrings(args []interface{}) []string {
+	var fields []string
+	for _, arg := range args {
+		v, isNil := indirect(reflect.ValueOf(arg))
+		if _, ok := arg.(fmt.Stringer); !ok {
+			switch {
+			case !v.IsValid() || isNil:
+				fields = append(fields, "")
+				continue
+			case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8,
+				v.Kind() == reflect.Array:
+				for i := 0; i < v.Len(); i++ {
+					fields = append(fields, fmt.Sprint(v.Index(i).Interface()))
+				}
+				continue
+			}
+		}
+		fields = append(fields, fmt.Sprint(arg))
+	}
+	return fields
+}
@@ -0,0 +1,40 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCSVFuncs(t *testing.T) {
+	var nilPtr *int
+	tests := []struct {
+		input  string
+		data   interface{}
+		output string
+	}{
+		{`{{csv.field .}}`, "plain", `plain`},
+		{`{{csv.field .}}`, `say "hi", bye`, `"say ""hi"", bye"`},
+		{`{{csv.field .}}`, "a\nb", "\"a\nb\""},
+		{`{{csv.field .}}`, nilPtr, ``},
+		{`{{csv.row "a" 1 .}}`, []string{"b,c", "d"}, `a,1,"b,c",d`},
+		{`{{csv.row .}}`, time.Second, `1s`},
+		{`{{csv.tsvField .}}`, "a\tb\\c\r\n", `a\tb\\c\r\n`},
+		{`{{csv.tsvRow "a" . 2.5}}`, []interface{}{"b c", 3}, "a\tb c\t3\t2.5"},
+	}
+	for _, test := range tests {
+		s := Must(new(Set).ParseTemplate("t", test.input))
+		var b bytes.Buffer
+		if err := s.Execute(&b, "t", test.data); err != nil {
+			t.Errorf("%s: %s", test.input, err)
+			continue
+		}
+		if b.String() != test.output {
+			t.Errorf("%s with %v: got %q, want %q", test.input, test.data, b.String(), test.output)
+		}
+	}
+}
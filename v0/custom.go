@@ -0,0 +1,81 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// NodeExecutor executes a custom node, writing its output to wr. The node
+// is a parse.CustomNode inserted by a compilation pass. dot is the value
+// of dot where the node executes, and value is the value of the pipeline
+// of the node, or nil if it has none. body executes the contents of the
+// node with the given dot, writing to the given writer; it can be called
+// any number of times, including zero.
+//
+// The output of an executor is written verbatim, even if the set is
+// escaped: the executor is responsible for writing safe output, while the
+// contents of the node are escaped as usual.
+type NodeExecutor func(wr io.Writer, node *parse.CustomNode, dot, value interface{},
+	body func(wr io.Writer, dot interface{}) error) error
+
+// Executor registers the function that executes the custom nodes of the
+// given kind. Executing a custom node without an executor is an error. The
+// return value is the set, so calls can be chained.
+func (s *Set) Executor(kind string, fn NodeExecutor) *Set {
+	if s.executors == nil {
+		s.executors = make(map[string]NodeExecutor)
+	}
+	s.executors[kind] = fn
+	return s
+}
+
+// walkCustom executes a custom node with its executor.
+func (s *state) walkCustom(dot reflect.Value, node *parse.CustomNode) {
+	fn := s.snap.executors[node.Kind]
+	if fn == nil {
+		s.errorf("no executor for custom node of kind %q", node.Kind)
+	}
+	defer s.pop(s.mark())
+	var value interface{}
+	if node.Pipe != nil {
+		if v := s.evalLazy(s.evalPipeline(dot, node.Pipe)); v.IsValid() {
+			value = v.Interface()
+		}
+	}
+	var bodyErr error
+	body := func(wr io.Writer, d interface{}) (err error) {
+		if node.List == nil {
+			return nil
+		}
+		defer func() {
+			errRecover(&err)
+			bodyErr = err
+		}()
+		saved := s.wr
+		defer func() { s.wr = saved }()
+		s.wr = wr
+		if s.snap.recorder != nil {
+			defer s.setDotPath(unknownPath)()
+		}
+		s.walk(reflect.ValueOf(d), node.List)
+		return nil
+	}
+	var dotValue interface{}
+	if dot.IsValid() {
+		dotValue = dot.Interface()
+	}
+	if err := fn(s.wr, node, dotValue, value, body); err != nil {
+		if err == bodyErr {
+			// Errors in the contents already have their context.
+			panic(err)
+		}
+		s.at(node)
+		s.errorf("%s", err)
+	}
+}
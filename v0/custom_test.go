@@ -0,0 +1,73 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// wrapPass wraps the contents of all templates in a custom node of the
+// given kind, with the pipeline .X.
+func wrapPass(kind string) CompilePass {
+	return CompilePassFunc(func(tree parse.Tree) error {
+		for _, define := range tree {
+			field := &parse.FieldNode{NodeType: parse.NodeField, Ident: []string{"X"}}
+			cmd := &parse.CommandNode{NodeType: parse.NodeCommand, Args: []parse.Node{field}}
+			pipe := &parse.PipeNode{NodeType: parse.NodePipe, Cmds: []*parse.CommandNode{cmd}}
+			node := parse.NewCustom(kind, "data", pipe, define.List)
+			define.List = &parse.ListNode{NodeType: parse.NodeList, Nodes: []parse.Node{node}}
+		}
+		return nil
+	})
+}
+
+func TestCustomNode(t *testing.T) {
+	repeat := func(wr io.Writer, node *parse.CustomNode, dot, value interface{}, body func(io.Writer, interface{}) error) error {
+		fmt.Fprintf(wr, "[%s %v]", node.Data, value)
+		for i := 0; i < 2; i++ {
+			b := new(bytes.Buffer)
+			if err := body(b, dot); err != nil {
+				return err
+			}
+			io.WriteString(wr, strings.ToUpper(b.String()))
+		}
+		return nil
+	}
+	set := Must(new(Set).Parse(`{{define "a"}}<b title="{{.Y}}">{{.Y}}</b>{{end}}`)).
+		AddPass(wrapPass("repeat")).Escape().Executor("repeat", repeat)
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "a", map[string]string{"X": "x", "Y": "<y>"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `[data x]<B TITLE="&LT;Y&GT;">&LT;Y&GT;</B><B TITLE="&LT;Y&GT;">&LT;Y&GT;</B>`
+	if b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+	// Errors in the contents are returned unchanged.
+	err := set.Execute(new(bytes.Buffer), "a", struct{ X int }{1})
+	if err == nil || !strings.Contains(err.Error(), `executing "a" at <.Y>`) {
+		t.Errorf("expected error in the contents; got %v", err)
+	}
+	// Errors of the executor get the context of the node.
+	set = Must(new(Set).Parse(`{{define "a"}}a{{end}}`)).AddPass(wrapPass("fail")).
+		Executor("fail", func(io.Writer, *parse.CustomNode, interface{}, interface{}, func(io.Writer, interface{}) error) error {
+			return fmt.Errorf("failed")
+		})
+	err = set.Execute(new(bytes.Buffer), "a", nil)
+	if err == nil || !strings.Contains(err.Error(), "failed") || !strings.HasPrefix(err.Error(), "template: a") {
+		t.Errorf("expected executor error; got %v", err)
+	}
+	set = Must(new(Set).Parse(`{{define "a"}}a{{end}}`)).AddPass(wrapPass("missing"))
+	err = set.Execute(new(bytes.Buffer), "a", nil)
+	if err == nil || !strings.Contains(err.Error(), `no executor for custom node of kind "missing"`) {
+		t.Errorf("expected missing executor error; got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gorilla/template/v0/escape"
+)
+
+// Debug enables the {{debug}} builtin, which writes the template call
+// stack, the value of dot and the variables in scope where it executes, as
+// escaped preformatted HTML:
+//
+//	<pre class="template-debug">templates: page > article
+//	dot (main.Article): {Title:Hello Body:...}
+//	$ (main.Page): {...}
+//	$i (int): 2
+//	</pre>
+//
+// Without Debug, {{debug}} writes nothing, so the actions can be left in
+// templates while they are not needed. The return value is the set, so
+// calls can be chained.
+func (s *Set) Debug() *Set {
+	s.debug = true
+	return s
+}
+
+// debugBuiltin is the implementation of {{debug}} when debugging is not
+// enabled. When it is, the call is replaced by a dump, see state.debug.
+func debugBuiltin() escape.HTML {
+	return ""
+}
+
+// debugFunc is the value of debugBuiltin, to recognize its calls.
+var debugFunc = reflect.ValueOf(debugBuiltin)
+
+// debug returns the dump written by {{debug}} for the given dot.
+func (s *state) debug(dot reflect.Value) escape.HTML {
+	var b bytes.Buffer
+	b.WriteString(`<pre class="template-debug">`)
+	calls := append(append([]string(nil), s.callers...), s.tmpl.Name)
+	fmt.Fprintf(&b, "templates: %s\n", escape.HTMLEscapeString(strings.Join(calls, " > ")))
+	writeDebugValue(&b, "dot", dot)
+	for _, v := range s.vars {
+		writeDebugValue(&b, v.name, v.value)
+	}
+	b.WriteString("</pre>")
+	return escape.HTML(b.String())
+}
+
+// writeDebugValue writes a line with the name, type and value of a dumped
+// value.
+func writeDebugValue(b *bytes.Buffer, name string, v reflect.Value) {
+	if v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		fmt.Fprintf(b, "%s: <nil>\n", escape.HTMLEscapeString(name))
+		return
+	}
+	line := fmt.Sprintf("%s (%s): %+v", name, v.Type(), v)
+	b.WriteString(escape.HTMLEscapeString(line))
+	b.WriteString("\n")
+}
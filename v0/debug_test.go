@@ -0,0 +1,50 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	const text = `
+{{define "page"}}{{range $i, $x := .Items}}{{template "item" $x}}{{end}}{{end}}
+{{define "item"}}<p>{{.}}</p>{{debug}}{{end}}`
+	data := map[string][]string{"Items": {"<b>"}}
+	var b bytes.Buffer
+	if err := Must(new(Set).Escape().Parse(text)).Execute(&b, "page", data); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<p>&lt;b&gt;</p>`; b.String() != want {
+		t.Errorf("without Debug: got %q, want %q", b.String(), want)
+	}
+	b.Reset()
+	if err := Must(new(Set).Escape().Debug().Parse(text)).Execute(&b, "page", data); err != nil {
+		t.Fatal(err)
+	}
+	want := `<p>&lt;b&gt;</p><pre class="template-debug">templates: page &gt; item
+dot (string): &lt;b&gt;
+$ (string): &lt;b&gt;
+</pre>`
+	if b.String() != want {
+		t.Errorf("with Debug: got\n%s\nwant\n%s", b.String(), want)
+	}
+	// Variables in scope.
+	b.Reset()
+	s := Must(new(Set).Debug().Parse(`{{define "t"}}{{range $i, $x := .}}{{debug}}{{end}}{{end}}`))
+	if err := s.Execute(&b, "t", []int{7}); err != nil {
+		t.Fatal(err)
+	}
+	want = `<pre class="template-debug">templates: t
+dot (int): 7
+$ ([]int): [7]
+$i (int): 0
+$x (int): 7
+</pre>`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"sort"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Deprecate marks the template or function with the given name as
+// deprecated. When the set is compiled, a warning with the message is
+// logged through the set's logger for each use of the name: calls to the
+// template or the function, and templates that extend the template. A
+// warning is also logged each time the template is executed directly.
+// The return value is the set, so calls can be chained.
+func (s *Set) Deprecate(name, msg string) *Set {
+	deprecated := make(map[string]string, len(s.deprecated)+1)
+	for k, v := range s.deprecated {
+		deprecated[k] = v
+	}
+	deprecated[name] = msg
+	s.deprecated = deprecated
+	return s
+}
+
+// warnDeprecated logs a warning for each use of a deprecated name in the
+// tree. It runs before inlining.
+func warnDeprecated(tree parse.Tree, deprecated map[string]string, log logConfig) {
+	if len(deprecated) == 0 || log.logger == nil || log.level > LogWarn {
+		return
+	}
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		define := tree[name]
+		if msg, ok := deprecated[define.Parent]; ok {
+			location, _ := define.ErrorContext(define)
+			log.logf(LogWarn, "template: %s: extends deprecated template %q: %s", location, define.Parent, msg)
+		}
+		templateCalls(define.List, func(n *parse.TemplateNode) {
+			if msg, ok := deprecated[n.Name]; ok {
+				location, _ := define.ErrorContext(n)
+				log.logf(LogWarn, "template: %s: calls deprecated template %q: %s", location, n.Name, msg)
+			}
+		})
+		funcCalls(define.List, func(n *parse.IdentifierNode) {
+			if msg, ok := deprecated[n.Ident]; ok {
+				location, _ := define.ErrorContext(n)
+				log.logf(LogWarn, "template: %s: calls deprecated function %q: %s", location, n.Ident, msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeprecate(t *testing.T) {
+	const text = `
+{{define "old"}}{{slot "body"}}{{end}}{{end}}
+{{define "new" "old"}}{{oldTitle}}{{template "oldPart"}}{{end}}
+{{define "oldPart"}}part{{end}}`
+	r := &logRecorder{}
+	s := new(Set).
+		Funcs(FuncMap{"oldTitle": strings.ToUpper}).
+		Logger(r, LogWarn).
+		Deprecate("old", "use a layout").
+		Deprecate("oldPart", "inline it").
+		Deprecate("oldTitle", "use title")
+	if _, err := Must(s.Parse(text)).Compile(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`WARN template: new:3:2: extends deprecated template "old": use a layout`,
+		`WARN template: new:3:45: calls deprecated template "oldPart": inline it`,
+		`WARN template: new:3:24: calls deprecated function "oldTitle": use title`,
+	}
+	if !reflect.DeepEqual(r.msgs, want) {
+		t.Errorf("got messages\n%q\nwant\n%q", r.msgs, want)
+	}
+	r.msgs = nil
+	if err := s.Execute(ioutil.Discard, "oldPart", nil); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{`WARN template: executing deprecated template "oldPart": inline it`}
+	if !reflect.DeepEqual(r.msgs, want) {
+		t.Errorf("got messages\n%q\nwant\n%q", r.msgs, want)
+	}
+}
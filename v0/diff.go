@@ -0,0 +1,81 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Diff compiles both sets and returns the sorted names of the templates
+// whose output could differ between them: templates defined in only one
+// set, templates whose compiled trees differ, and templates that call one
+// of those, directly or indirectly. It is meant to check which pages are
+// affected by a release that only changes templates.
+//
+// The functions of the sets are not compared. A change to the constants
+// or the execution options of the sets affects all templates.
+func Diff(old, new *Set) ([]string, error) {
+	a, err := old.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	b, err := new.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool)
+	global := !equalConsts(a.consts, b.consts) || a.nilPolicy != b.nilPolicy ||
+		a.lenient != b.lenient || a.placeholder != b.placeholder
+	for name, define := range a.tree {
+		if global || b.tree[name] == nil || b.tree[name].List.String() != define.List.String() {
+			changed[name] = true
+		}
+	}
+	for name := range b.tree {
+		if a.tree[name] == nil {
+			changed[name] = true
+		}
+	}
+	// Propagate the changes to callers until nothing changes.
+	for more := true; more; {
+		more = false
+		for _, tree := range []parse.Tree{a.tree, b.tree} {
+			for name, define := range tree {
+				if changed[name] {
+					continue
+				}
+				templateCalls(define.List, func(n *parse.TemplateNode) {
+					if changed[n.Name] && !changed[name] {
+						changed[name] = true
+						more = true
+					}
+				})
+			}
+		}
+	}
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// equalConsts returns whether two sets of constants are equal.
+func equalConsts(a, b map[string]reflect.Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, v := range a {
+		w, ok := b[name]
+		if !ok || !reflect.DeepEqual(valueInterface(v), valueInterface(w)) {
+			return false
+		}
+	}
+	return true
+}
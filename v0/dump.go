@@ -0,0 +1,156 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/template/v0/escape"
+)
+
+// Limits of the values rendered by dump.
+const (
+	maxDumpDepth  = 6   // nesting depth of containers.
+	maxDumpItems  = 50  // elements, entries or fields per container.
+	maxDumpString = 200 // bytes of strings.
+)
+
+// dump is the dump builtin: it returns an indented rendering of v, with the
+// type of each value, as escaped preformatted HTML. Deep or large values
+// are truncated.
+//
+//	{{dump .}}
+//
+// renders, for a struct:
+//
+//	<pre class="template-dump">(main.User) {
+//	  Name: (string) "Ann"
+//	  Tags: ([]string) len=1 [
+//	    0: (string) "admin"
+//	  ]
+//	}</pre>
+func dump(v interface{}) escape.HTML {
+	d := &dumper{visited: make(map[uintptr]bool)}
+	d.value(reflect.ValueOf(v), 0)
+	return escape.HTML(`<pre class="template-dump">` +
+		escape.HTMLEscapeString(d.b.String()) + `</pre>`)
+}
+
+// dumper holds the state of a dump.
+type dumper struct {
+	b       bytes.Buffer
+	visited map[uintptr]bool // pointers being dumped, to detect cycles.
+}
+
+// value writes v with its type, indented at the given depth.
+func (d *dumper) value(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		d.b.WriteString("<nil>")
+		return
+	}
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	fmt.Fprintf(&d.b, "(%s) ", v.Type())
+	d.body(v, depth)
+}
+
+// body writes v without its type, indented at the given depth.
+func (d *dumper) body(v reflect.Value, depth int) {
+	switch v.Kind() {
+	case reflect.Interface:
+		d.b.WriteString("nil")
+	case reflect.Ptr:
+		if v.IsNil() {
+			d.b.WriteString("nil")
+			return
+		}
+		if d.visited[v.Pointer()] {
+			d.b.WriteString("<cycle>")
+			return
+		}
+		d.visited[v.Pointer()] = true
+		defer delete(d.visited, v.Pointer())
+		d.b.WriteString("&")
+		d.body(v.Elem(), depth)
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.Struct:
+		d.contents(v, depth)
+	case reflect.String:
+		s := v.String()
+		if len(s) > maxDumpString {
+			fmt.Fprintf(&d.b, "%q... (%d bytes)", s[:maxDumpString], len(s))
+			return
+		}
+		d.b.WriteString(strconv.Quote(s))
+	default:
+		fmt.Fprintf(&d.b, "%v", v)
+	}
+}
+
+// contents writes the elements, entries or fields of a container.
+func (d *dumper) contents(v reflect.Value, depth int) {
+	n := 0
+	open, close := "{", "}"
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil() {
+			d.b.WriteString("nil")
+			return
+		}
+		n = v.Len()
+		fmt.Fprintf(&d.b, "len=%d ", n)
+		if v.Kind() != reflect.Map {
+			open, close = "[", "]"
+		}
+	case reflect.Struct:
+		n = v.NumField()
+	}
+	if n == 0 {
+		d.b.WriteString(open + close)
+		return
+	}
+	if depth >= maxDumpDepth {
+		d.b.WriteString(open + "..." + close)
+		return
+	}
+	d.b.WriteString(open + "\n")
+	indent := strings.Repeat("  ", depth+1)
+	item := func(label string, elem reflect.Value) {
+		d.b.WriteString(indent + label + ": ")
+		d.value(elem, depth+1)
+		d.b.WriteString("\n")
+	}
+	count := 0
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		for ; count < n && count < maxDumpItems; count++ {
+			item(strconv.Itoa(count), v.Index(count))
+		}
+	case reflect.Map:
+		for _, key := range sortKeys(v.MapKeys()) {
+			if count == maxDumpItems {
+				break
+			}
+			label := fmt.Sprintf("%v", key)
+			if key.Kind() == reflect.String {
+				label = strconv.Quote(key.String())
+			}
+			item(label, v.MapIndex(key))
+			count++
+		}
+	case reflect.Struct:
+		for ; count < n && count < maxDumpItems; count++ {
+			item(v.Type().Field(count).Name, v.Field(count))
+		}
+	}
+	if count < n {
+		fmt.Fprintf(&d.b, "%s... (%d more)\n", indent, n-count)
+	}
+	d.b.WriteString(strings.Repeat("  ", depth) + close)
+}
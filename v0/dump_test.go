@@ -0,0 +1,72 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+)
+
+type dumpUser struct {
+	Name    string
+	Tags    []string
+	Meta    map[string]interface{}
+	Friend  *dumpUser
+	private int
+}
+
+func TestDump(t *testing.T) {
+	u := &dumpUser{
+		Name: "<Ann>",
+		Tags: []string{"admin"},
+		Meta: map[string]interface{}{"b": 2, "a": nil},
+	}
+	u.Friend = u
+	tests := []struct {
+		data   interface{}
+		output string
+	}{
+		{nil, `<nil>`},
+		{3, `(int) 3`},
+		{[]int{}, `([]int) len=0 []`},
+		{u, `(*template.dumpUser) &{
+  Name: (string) "<Ann>"
+  Tags: ([]string) len=1 [
+    0: (string) "admin"
+  ]
+  Meta: (map[string]interface {}) len=2 {
+    "a": (interface {}) nil
+    "b": (int) 2
+  }
+  Friend: (*template.dumpUser) <cycle>
+  private: (int) 0
+}`},
+		{strings.Repeat("x", maxDumpString+1), `(string) "` + strings.Repeat("x", maxDumpString) + `"... (201 bytes)`},
+		{make([]int, maxDumpItems+2), "... (2 more)\n]"},
+	}
+	s := Must(new(Set).Escape().Parse(`{{define "t"}}{{dump .}}{{end}}`))
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := s.Execute(&b, "t", test.data); err != nil {
+			t.Errorf("%v: %s", test.data, err)
+			continue
+		}
+		got := b.String()
+		if !strings.HasPrefix(got, `<pre class="template-dump">`) || !strings.HasSuffix(got, `</pre>`) {
+			t.Errorf("%v: got %q, want a pre element", test.data, got)
+			continue
+		}
+		got = strings.TrimSuffix(strings.TrimPrefix(got, `<pre class="template-dump">`), `</pre>`)
+		if strings.ContainsAny(got, `<>"`) {
+			t.Errorf("%v: got %q, want it escaped", test.data, got)
+		}
+		got = html.UnescapeString(got)
+		if got != test.output && !strings.HasSuffix(got, test.output) {
+			t.Errorf("%v: got\n%s\nwant\n%s", test.data, got, test.output)
+		}
+	}
+}
@@ -5,6 +5,8 @@
 package escape
 
 import (
+	"bytes"
+	"fmt"
 	"strings"
 )
 
@@ -173,3 +175,88 @@ func attrType(name string) contentType {
 	}
 	return contentTypePlain
 }
+
+// Attr returns the HTML attributes for the given name/value pairs, as in
+//     <input {{html.attr "name" .Name "value" .Value "disabled" .Disabled}}>
+// Pairs whose value is nil, false or the empty string are left out, and a
+// true value produces an attribute without value. Other values are
+// sanitized according to the attribute type: URLs are filtered and
+// normalized as in an href attribute, and the declarations of a style
+// attribute are filtered as CSS values. Event handlers and srcdoc are
+// rejected, since their content needs contextual escaping, as are the
+// attributes whose value changes the meaning of the element or of the
+// page, such as http-equiv or rel, which contextual escaping doesn't allow
+// to be named by an action either. The value attribute is allowed, since
+// it holds user input.
+func Attr(pairs ...interface{}) (HTMLAttr, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("escape: attr needs name/value pairs, got %d arguments", len(pairs))
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(pairs); i += 2 {
+		name, ok := pairs[i].(string)
+		if !ok || !isAttrName(name) {
+			return "", fmt.Errorf("escape: bad attribute name %v", pairs[i])
+		}
+		var value string
+		v := pairs[i+1]
+		if v != nil {
+			v = indirect(v)
+		}
+		switch v := v.(type) {
+		case nil:
+			continue
+		case bool:
+			if !v {
+				continue
+			}
+		default:
+			switch attrType(name) {
+			case contentTypeURL:
+				value = attrEscaper(urlNormalizer(urlFilter(v)))
+			case contentTypeCSS:
+				value = attrEscaper(cssDeclFilter(v))
+			case contentTypeHTML, contentTypeJS:
+				return "", fmt.Errorf("escape: attribute %q needs contextual escaping and can't be set with attr", name)
+			case contentTypeUnsafe:
+				if strings.ToLower(name) != "value" {
+					return "", fmt.Errorf("escape: attribute %q is unsafe and can't be set with attr", name)
+				}
+				value = attrEscaper(v)
+			default:
+				value = attrEscaper(v)
+			}
+			if value == "" {
+				continue
+			}
+		}
+		if b.Len() != 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(name)
+		if value != "" {
+			b.WriteString(`="`)
+			b.WriteString(value)
+			b.WriteByte('"')
+		}
+	}
+	return HTMLAttr(b.String()), nil
+}
+
+// isAttrName returns whether name is a valid attribute name for Attr.
+func isAttrName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case '0' <= r && r <= '9':
+		case 'a' <= r && r <= 'z':
+		case 'A' <= r && r <= 'Z':
+		case r == '-' || r == ':' || r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
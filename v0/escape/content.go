@@ -19,6 +19,13 @@ type (
 	// See http://www.w3.org/TR/css3-syntax/#style
 	CSS string
 
+	// Doctype encapsulates a complete document type or XML declaration
+	// from a trusted source, for example, `<!DOCTYPE html>` or
+	// `<?xml version="1.0" encoding="UTF-8"?>`. It is written as is in
+	// text if it matches the grammar of such a declaration, and escaped
+	// otherwise.
+	Doctype string
+
 	// HTML encapsulates a known safe HTML document fragment.
 	// It should not be used for HTML from a third-party, or HTML with
 	// unclosed tags or comments. The outputs of a sound HTML sanitizer
@@ -60,6 +67,7 @@ type contentType uint8
 const (
 	contentTypePlain contentType = iota
 	contentTypeCSS
+	contentTypeDoctype
 	contentTypeHTML
 	contentTypeHTMLAttr
 	contentTypeJS
@@ -110,6 +118,8 @@ func stringify(args ...interface{}) (string, contentType) {
 			return s, contentTypePlain
 		case CSS:
 			return string(s), contentTypeCSS
+		case Doctype:
+			return string(s), contentTypeDoctype
 		case HTML:
 			return string(s), contentTypeHTML
 		case HTMLAttr:
@@ -6,6 +6,7 @@ package escape
 
 import (
 	"fmt"
+	"strings"
 )
 
 // context describes the state an HTML parser must be in when it reaches the
@@ -29,6 +30,128 @@ func (c context) String() string {
 	return fmt.Sprintf("{%v %v %v %v %v %v %v}", c.state, c.delim, c.urlPart, c.jsCtx, c.attr, c.element, c.err)
 }
 
+// Context describes the state of an HTML parser at some point of the
+// output, which determines how values must be escaped there. The fields
+// hold the names used by the escaper, without their prefix: for example,
+// after `<a title="` the State is "Attr", the Delim is "DoubleQuote", and
+// the other fields are "None", except JSCtx which is "Regexp".
+type Context struct {
+	State   string // Text, Tag, AttrName, Attr, URL, JS, CSS, RCDATA, HTMLCmt...
+	Delim   string // The delimiter that ends the current attribute.
+	URLPart string // The part of the URL, in URL states.
+	JSCtx   string // Whether a '/' starts a regexp or a division, in JS states.
+	Attr    string // The kind of the current attribute, in a tag.
+	Element string // The special element, in its tag or body.
+}
+
+// ContextAfter returns the context at the end of the given HTML, computed
+// as the escaper does for the text of templates, starting outside any tag.
+// It returns an error if the escaper rejects the HTML, for example because
+// of a quote in an unquoted attribute value.
+func ContextAfter(html string) (Context, error) {
+	var c context
+	for s := []byte(html); len(s) > 0; {
+		c1, n := contextAfterText(c, s)
+		if c1.state == stateError {
+			return Context{}, c1.err
+		}
+		c, s = c1, s[n:]
+	}
+	return Context{
+		State:   strings.TrimPrefix(c.state.String(), "state"),
+		Delim:   strings.TrimPrefix(c.delim.String(), "delim"),
+		URLPart: strings.TrimPrefix(c.urlPart.String(), "urlPart"),
+		JSCtx:   strings.TrimPrefix(c.jsCtx.String(), "jsCtx"),
+		Attr:    strings.TrimPrefix(c.attr.String(), "attr"),
+		Element: strings.TrimPrefix(c.element.String(), "element"),
+	}, nil
+}
+
+// stateDescriptions describe the states in error messages.
+var stateDescriptions = [...]string{
+	stateText:        "text",
+	stateTag:         "a tag",
+	stateAttrName:    "an attribute name",
+	stateAfterName:   "a tag after an attribute name",
+	stateBeforeValue: "a tag before an attribute value",
+	stateHTMLCmt:     "an HTML comment",
+	stateRCDATA:      "text",
+	stateAttr:        "an attribute value",
+	stateURL:         "a URL",
+	stateJS:          "JavaScript",
+	stateJSDqStr:     "a double-quoted JavaScript string",
+	stateJSSqStr:     "a single-quoted JavaScript string",
+	stateJSRegexp:    "a JavaScript regular expression",
+	stateJSBlockCmt:  "a JavaScript comment",
+	stateJSLineCmt:   "a JavaScript line comment",
+	stateCSS:         "CSS",
+	stateCSSDqStr:    "a double-quoted CSS string",
+	stateCSSSqStr:    "a single-quoted CSS string",
+	stateCSSDqURL:    "a double-quoted CSS URL",
+	stateCSSSqURL:    "a single-quoted CSS URL",
+	stateCSSURL:      "a CSS URL",
+	stateCSSBlockCmt: "a CSS comment",
+	stateCSSLineCmt:  "a CSS line comment",
+	stateDecl:        "a doctype or XML declaration",
+	stateError:       "an error",
+}
+
+// describe returns a description of the context for error messages, such
+// as "in a URL in a double-quoted attribute".
+func (c context) describe() string {
+	var attr string
+	switch c.delim {
+	case delimDoubleQuote:
+		attr = "a double-quoted attribute"
+	case delimSingleQuote:
+		attr = "a single-quoted attribute"
+	case delimSpaceOrTagEnd:
+		attr = "an unquoted attribute"
+	}
+	s := "in " + stateDescriptions[c.state]
+	switch {
+	case attr != "" && c.state == stateAttr:
+		s = "in " + attr + " value"
+	case attr != "":
+		s += " in " + attr
+	}
+	if c.element != elementNone {
+		name := strings.ToLower(strings.TrimPrefix(c.element.String(), "element"))
+		if isInTag(c.state) || c.delim != delimNone {
+			s += " of <" + name + ">"
+		} else {
+			s += " in <" + name + ">"
+		}
+	}
+	return s
+}
+
+// unclosed returns the innermost construct that is open in the context,
+// such as "quote" or "tag", for error messages.
+func (c context) unclosed() string {
+	switch c.state {
+	case stateHTMLCmt, stateJSBlockCmt, stateJSLineCmt, stateCSSBlockCmt, stateCSSLineCmt:
+		return "comment"
+	case stateJSDqStr, stateJSSqStr, stateCSSDqStr, stateCSSSqStr:
+		return "string"
+	case stateJSRegexp:
+		return "regular expression"
+	case stateCSSDqURL, stateCSSSqURL, stateCSSURL:
+		return "url(...)"
+	case stateDecl:
+		return "declaration"
+	}
+	switch {
+	case c.delim == delimDoubleQuote || c.delim == delimSingleQuote:
+		return "quote"
+	case c.delim == delimSpaceOrTagEnd || isInTag(c.state):
+		return "tag"
+	case c.element != elementNone:
+		return "<" + strings.ToLower(strings.TrimPrefix(c.element.String(), "element")) + "> element"
+	}
+	return "construct"
+}
+
 // eq returns whether two contexts are equal.
 func (c context) eq(d context) bool {
 	return c.state == d.state &&
@@ -130,6 +253,8 @@ const (
 	stateCSSBlockCmt
 	// stateCSSLineCmt occurs inside a CSS // line comment.
 	stateCSSLineCmt
+	// stateDecl occurs inside a <!DOCTYPE ...> or <?xml ...?> declaration.
+	stateDecl
 	// stateError is an infectious error state outside any valid
 	// HTML/CSS/JS construct.
 	stateError
@@ -159,6 +284,7 @@ var stateNames = [...]string{
 	stateCSSURL:      "stateCSSURL",
 	stateCSSBlockCmt: "stateCSSBlockCmt",
 	stateCSSLineCmt:  "stateCSSLineCmt",
+	stateDecl:        "stateDecl",
 	stateError:       "stateError",
 }
 
@@ -7,6 +7,7 @@ package escape
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -266,3 +267,39 @@ func cssValueFilter(args ...interface{}) string {
 	}
 	return string(b)
 }
+
+// cssDeclFilter allows a list of innocuous CSS declarations, as in the
+// value of a style attribute: "color: red; margin: 0". Each declaration
+// must have a property name made of letters and hyphens, and a value
+// allowed by cssValueFilter. Otherwise the whole list is filtered out.
+func cssDeclFilter(args ...interface{}) string {
+	s, t := stringify(args...)
+	if t == contentTypeCSS {
+		return s
+	}
+	var decls []string
+	for _, decl := range strings.Split(s, ";") {
+		if strings.TrimSpace(decl) == "" {
+			continue
+		}
+		colon := strings.IndexByte(decl, ':')
+		if colon < 0 {
+			return filterFailsafe
+		}
+		name := strings.TrimSpace(decl[:colon])
+		if name == "" || strings.Contains(name, "--") {
+			return filterFailsafe
+		}
+		for _, r := range name {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != '-' {
+				return filterFailsafe
+			}
+		}
+		value := strings.TrimSpace(decl[colon+1:])
+		if value == "" || cssValueFilter(value) == filterFailsafe {
+			return filterFailsafe
+		}
+		decls = append(decls, name+": "+value)
+	}
+	return strings.Join(decls, "; ")
+}
@@ -179,6 +179,17 @@ const (
 	//   Look for missing semicolons inside branches, and maybe add
 	//   parentheses to make it clear which interpretation you intend.
 	ErrSlashAmbig
+
+	// ErrCaptureContext: "{{capture}} in unsupported context"
+	// Example:
+	//   <a title="{{capture $t}}{{.}}{{end}}">
+	// Discussion:
+	//   The output of a {{capture}} is typed as safe content for the
+	//   context it is rendered in, so it is not escaped again when the
+	//   variable is written in the same context. Only HTML text and the
+	//   contents of <script> and <style> elements have such a type.
+	//   Capture the value in HTML text and write it in the attribute.
+	ErrCaptureContext
 )
 
 func (e *Error) Error() string {
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"fmt"
 	"html"
+	"strings"
 
 	"github.com/gorilla/template/v0/parse"
 )
@@ -44,6 +45,7 @@ var FuncMap = map[string]interface{}{
 	"html_template_commentescaper":  commentEscaper,
 	"html_template_cssescaper":      cssEscaper,
 	"html_template_cssvaluefilter":  cssValueFilter,
+	"html_template_declfilter":      declFilter,
 	"html_template_htmlnamefilter":  htmlNameFilter,
 	"html_template_htmlescaper":     htmlEscaper,
 	"html_template_jsregexpescaper": jsRegexpEscaper,
@@ -60,6 +62,8 @@ var FuncMap = map[string]interface{}{
 // templates injection safe.
 type escaper struct {
 	tree parse.Tree
+	// define is the template being escaped, to locate errors.
+	define *parse.DefineNode
 	// output[templateName] is the output context for a templateName that
 	// has been mangled to include its input context.
 	output map[string]context
@@ -74,18 +78,31 @@ type escaper struct {
 	actionNodeEdits   map[*parse.ActionNode][]string
 	templateNodeEdits map[*parse.TemplateNode]string
 	textNodeEdits     map[*parse.TextNode][]byte
+	// xxxSinks record the inline code written by nodes, see InlineCode.
+	actionSinks map[*parse.ActionNode]string
+	textSinks   map[*parse.TextNode][]string
+	// unquoted records the actions that write unquoted attribute values,
+	// see FindUnquotedAttrs.
+	unquoted map[*parse.ActionNode]bool
+	// captureNodeEdits are the content types of the captures.
+	captureNodeEdits map[*parse.CaptureNode]string
 }
 
 // newEscaper creates a blank escaper for the given set.
 func newEscaper(t parse.Tree) *escaper {
 	return &escaper{
 		t,
+		nil,
 		map[string]context{},
 		parse.Tree{},
 		map[string]bool{},
 		map[*parse.ActionNode][]string{},
 		map[*parse.TemplateNode]string{},
 		map[*parse.TextNode][]byte{},
+		map[*parse.ActionNode]string{},
+		map[*parse.TextNode][]string{},
+		map[*parse.ActionNode]bool{},
+		map[*parse.CaptureNode]string{},
 	}
 }
 
@@ -101,8 +118,18 @@ func (e *escaper) escape(c context, n parse.Node) context {
 	switch n := n.(type) {
 	case *parse.ActionNode:
 		return e.escapeAction(c, n)
+	case *parse.CaptureNode:
+		return e.escapeCapture(c, n)
+	case *parse.CustomNode:
+		// The contents of a custom node can execute any number of times,
+		// like the body of a range.
+		b := &parse.BranchNode{NodeType: parse.NodeRange, Pos: n.Pos, Line: n.Line, List: n.List}
+		return e.escapeBranch(c, b, "range")
 	case *parse.IfNode:
 		return e.escapeBranch(c, &n.BranchNode, "if")
+	case *parse.LetNode:
+		// The body of a let always executes exactly once.
+		return e.escapeList(c, n.List)
 	case *parse.ListNode:
 		return e.escapeList(c, n)
 	case *parse.RangeNode:
@@ -123,7 +150,13 @@ func (e *escaper) escapeAction(c context, n *parse.ActionNode) context {
 		// A local variable assignment, not an interpolation.
 		return c
 	}
+	if kind := inlineCodeKind(c); kind != "" {
+		e.actionSinks[n] = kind
+	}
 	c = nudge(c)
+	if c.delim == delimSpaceOrTagEnd {
+		e.unquoted[n] = true
+	}
 	s := make([]string, 0, 3)
 	switch c.state {
 	case stateError:
@@ -164,6 +197,8 @@ func (e *escaper) escapeAction(c context, n *parse.ActionNode) context {
 		s = append(s, "html_template_htmlescaper")
 	case stateRCDATA:
 		s = append(s, "html_template_rcdataescaper")
+	case stateDecl:
+		s = append(s, "html_template_declfilter")
 	case stateAttr:
 		// Handled below in delim check.
 	case stateAttrName, stateTag:
@@ -262,6 +297,43 @@ func join(a, b context, line int, nodeName string) context {
 	}
 }
 
+// captureContents are the content types of the output of captures,
+// indexed by the state of the context they are rendered in.
+var captureContents = map[state]string{
+	stateText: "html",
+	stateJS:   "js",
+	stateCSS:  "css",
+}
+
+// escapeCapture escapes a {{capture}} node. Its contents are escaped in the
+// context of the node and must end in it. They write nothing in place, so
+// the context after the node is unchanged.
+func (e *escaper) escapeCapture(c context, n *parse.CaptureNode) context {
+	content := captureContents[c.state]
+	if content == "" || c.delim != delimNone {
+		return context{
+			state: stateError,
+			err:   errorf(ErrCaptureContext, n.Line, "{{capture %s}} in unsupported context %v", n.Variable, c),
+		}
+	}
+	c1 := e.escapeList(c, n.List)
+	if c1.state == stateError {
+		return c1
+	}
+	if c1.state == stateJS {
+		// Nothing is written in place, so what follows is unaffected.
+		c1.jsCtx = c.jsCtx
+	}
+	if !c1.eq(c) {
+		return context{
+			state: stateError,
+			err:   errorf(ErrBranchEnd, n.Line, "{{capture %s}} ends in context %v, not %v", n.Variable, c1, c),
+		}
+	}
+	e.captureNodeEdits[n] = content
+	return c
+}
+
 // escapeBranch escapes a branch template node: "if", "range" and "with".
 func (e *escaper) escapeBranch(c context, n *parse.BranchNode, nodeName string) context {
 	c0 := e.escapeList(c, n.List)
@@ -281,7 +353,98 @@ func (e *escaper) escapeBranch(c context, n *parse.BranchNode, nodeName string)
 		}
 	}
 	c1 := e.escapeList(c, n.ElseList)
-	return join(c0, c1, n.Line, nodeName)
+	j := join(c0, c1, n.Line, nodeName)
+	if j.state == stateError && c0.state != stateError && c1.state != stateError {
+		j.err.Description = e.describeBranchEnds(c, n, nodeName, c0, c1)
+	}
+	return j
+}
+
+// describeBranchEnds describes the contexts c0 and c1 that the branches of
+// n, executed from context c, end in, and where the constructs they leave
+// open start.
+func (e *escaper) describeBranchEnds(c context, n *parse.BranchNode, nodeName string, c0, c1 context) string {
+	elseName := "{{else}} branch"
+	if n.ElseList == nil {
+		elseName = "implicit " + elseName
+	}
+	branches := []struct {
+		name string
+		list *parse.ListNode
+		end  context
+	}{
+		{"{{" + nodeName + "}} branch", n.List, c0},
+		{elseName, n.ElseList, c1},
+	}
+	var parts []string
+	for _, b := range branches {
+		s := fmt.Sprintf("the %s ends %s", b.name, b.end.describe())
+		if !b.end.eq(c) {
+			if pos := e.openingPos(c, b.list); pos >= 0 && e.define != nil {
+				s += fmt.Sprintf(", with an unclosed %s at %s", b.end.unclosed(), e.location(pos))
+			}
+		}
+		parts = append(parts, s)
+	}
+	return fmt.Sprintf("{{%s}} branches end in different contexts: %s", nodeName, strings.Join(parts, ", but "))
+}
+
+// openingPos returns the position of the last change of context in the
+// list executed from context c, which opens the construct the list ends
+// in, or -1 if the context doesn't change.
+func (e *escaper) openingPos(c context, n *parse.ListNode) parse.Pos {
+	// Escape on a copy of e, to leave it unchanged.
+	e1 := newEscaper(e.tree)
+	for k, v := range e.output {
+		e1.output[k] = v
+	}
+	e1.define = e.define
+	pos := parse.Pos(-1)
+	var walk func(n *parse.ListNode)
+	walk = func(n *parse.ListNode) {
+		if n == nil {
+			return
+		}
+		for _, m := range n.Nodes {
+			switch m := m.(type) {
+			case *parse.ListNode:
+				walk(m)
+			case *parse.TextNode:
+				for s, i := m.Text, 0; i < len(s) && c.state != stateError; {
+					c1, n := contextAfterText(c, s[i:])
+					if !c1.eq(c) {
+						pos = m.Pos + parse.Pos(i)
+					} else if n == 0 {
+						break
+					}
+					c, i = c1, i+n
+				}
+			default:
+				c1 := e1.escape(c, m)
+				if !c1.eq(c) {
+					pos = m.Position()
+				}
+				c = c1
+			}
+		}
+	}
+	walk(n)
+	return pos
+}
+
+// location returns the location of pos in the template being escaped, as
+// "name:line:column".
+func (e *escaper) location(pos parse.Pos) string {
+	location, _ := e.define.ErrorContext(&parse.TextNode{NodeType: parse.NodeText, Pos: pos})
+	// Report derived templates under their original name.
+	if i := strings.Index(location, "$htmltemplate_"); i >= 0 {
+		j := strings.IndexByte(location[i:], ':')
+		if j < 0 {
+			return location[:i]
+		}
+		return location[:i] + location[i+j:]
+	}
+	return location
 }
 
 // escapeList escapes a list template node.
@@ -301,6 +464,7 @@ func (e *escaper) escapeList(c context, n *parse.ListNode) context {
 // which is the same as whether e was updated.
 func (e *escaper) escapeListConditionally(c context, n *parse.ListNode, filter func(*escaper, context) bool) (context, bool) {
 	e1 := newEscaper(e.tree)
+	e1.define = e.define
 	// Make type inferences available to f.
 	for k, v := range e.output {
 		e1.output[k] = v
@@ -327,6 +491,18 @@ func (e *escaper) escapeListConditionally(c context, n *parse.ListNode, filter f
 		for k, v := range e1.textNodeEdits {
 			e.editTextNode(k, v)
 		}
+		for k, v := range e1.actionSinks {
+			e.actionSinks[k] = v
+		}
+		for k, v := range e1.textSinks {
+			e.textSinks[k] = v
+		}
+		for k, v := range e1.unquoted {
+			e.unquoted[k] = v
+		}
+		for k, v := range e1.captureNodeEdits {
+			e.captureNodeEdits[k] = v
+		}
 	}
 	return c, ok
 }
@@ -428,6 +604,10 @@ func (e *escaper) escapeTemplateBody(c context, t *parse.DefineNode) (context, b
 	// works >90% of the time.
 	n := t.Name
 	e.output[n] = c
+	defer func(define *parse.DefineNode) {
+		e.define = define
+	}(e.define)
+	e.define = t
 	return e.escapeListConditionally(c, t.List, filter)
 }
 
@@ -445,14 +625,15 @@ var delimEnds = [...]string{
 	delimSpaceOrTagEnd: " \t\n\f\r>",
 }
 
-var doctypeBytes = []byte("<!DOCTYPE")
-
 // escapeText escapes a text template node.
 func (e *escaper) escapeText(c context, n *parse.TextNode) context {
 	s, written, i, b := n.Text, 0, 0, new(bytes.Buffer)
 	for i != len(s) {
 		c1, nread := contextAfterText(c, s[i:])
 		i1 := i + nread
+		if kind := inlineCodeKind(c1); kind != "" && kind != inlineCodeKind(c) {
+			e.textSinks[n] = append(e.textSinks[n], kind)
+		}
 		if c.state == stateText || c.state == stateRCDATA {
 			end := i1
 			if c1.state != c.state {
@@ -464,7 +645,7 @@ func (e *escaper) escapeText(c context, n *parse.TextNode) context {
 				}
 			}
 			for j := i; j < end; j++ {
-				if s[j] == '<' && !bytes.HasPrefix(bytes.ToUpper(s[j:]), doctypeBytes) {
+				if s[j] == '<' {
 					b.Write(s[written:j])
 					b.WriteString("&lt;")
 					written = j + 1
@@ -609,6 +790,9 @@ func (e *escaper) commit() {
 	for n, s := range e.textNodeEdits {
 		n.Text = s
 	}
+	for n, content := range e.captureNodeEdits {
+		n.Content = content
+	}
 }
 
 // template returns the named template given a mangled template name.
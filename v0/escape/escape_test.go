@@ -336,6 +336,22 @@ func TestEscapeText(t *testing.T) {
 			`<a style="background: url( x `,
 			context{state: stateCSS, delim: delimDoubleQuote},
 		},
+		{
+			`<!DOCTYPE html`,
+			context{state: stateDecl},
+		},
+		{
+			`<!doctype html>`,
+			context{state: stateText},
+		},
+		{
+			`<?xml version="1.0"`,
+			context{state: stateDecl},
+		},
+		{
+			`<?xml version="1.0"?><`,
+			context{state: stateText},
+		},
 		{
 			`<!-- foo`,
 			context{state: stateHTMLCmt},
@@ -472,6 +488,34 @@ func TestEscapeText(t *testing.T) {
 			`<a g:value`,
 			context{state: stateAttrName},
 		},
+		{
+			`<br/`,
+			context{state: stateTag},
+		},
+		{
+			`<br/>`,
+			context{state: stateText},
+		},
+		{
+			`<input checked/`,
+			context{state: stateTag},
+		},
+		{
+			`<input / value=`,
+			context{state: stateBeforeValue},
+		},
+		{
+			`<img src="a.png" />`,
+			context{state: stateText},
+		},
+		{
+			`<img src=a.png/`,
+			context{state: stateURL, delim: delimSpaceOrTagEnd, urlPart: urlPartPreQuery},
+		},
+		{
+			`<script src="a.js"/>`,
+			context{state: stateJS, element: elementScript},
+		},
 		{
 			`<a svg:style='`,
 			context{state: stateCSS, delim: delimSingleQuote},
@@ -499,3 +543,109 @@ func TestEscapeText(t *testing.T) {
 		}
 	}
 }
+
+func TestContextAfter(t *testing.T) {
+	tests := []struct {
+		input  string
+		output Context
+	}{
+		{
+			``,
+			Context{"Text", "None", "None", "Regexp", "None", "None"},
+		},
+		{
+			`<a title="`,
+			Context{"Attr", "DoubleQuote", "None", "Regexp", "None", "None"},
+		},
+		{
+			`<a href='/x?`,
+			Context{"URL", "SingleQuote", "QueryOrFrag", "Regexp", "None", "None"},
+		},
+		{
+			`<script>var x = 1`,
+			Context{"JS", "None", "None", "DivOp", "None", "Script"},
+		},
+		{
+			`<style>p { color: "`,
+			Context{"CSSDqStr", "None", "None", "Regexp", "None", "Style"},
+		},
+		{
+			`<textarea>`,
+			Context{"RCDATA", "None", "None", "Regexp", "None", "Textarea"},
+		},
+	}
+	for _, test := range tests {
+		c, err := ContextAfter(test.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.input, err)
+			continue
+		}
+		if c != test.output {
+			t.Errorf("%q: expected %+v; got %+v", test.input, test.output, c)
+		}
+	}
+	if _, err := ContextAfter(`<a title=x"`); err == nil {
+		t.Errorf("expected error for a quote in an unquoted attribute")
+	}
+}
+
+func TestSanitizers(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     func(...interface{}) string
+		input  interface{}
+		output string
+	}{
+		{"JSStrEscaper", JSStrEscaper, `it's </script>`, `it\x27s \x3c\/script\x3e`},
+		{"JSStrEscaper", JSStrEscaper, JSStr(`a\nb`), `a\nb`},
+		{"CSSValueFilter", CSSValueFilter, "10px", "10px"},
+		{"CSSValueFilter", CSSValueFilter, "expression(alert(1))", "ZgotmplZ"},
+		{"CSSValueFilter", CSSValueFilter, CSS("expression(x)"), "expression(x)"},
+		{"URLNormalizer", URLNormalizer, "/a b?c=%20", "/a%20b?c=%20"},
+		{"RCDATAEscaper", RCDATAEscaper, "</textarea>", "&lt;/textarea&gt;"},
+	}
+	for _, test := range tests {
+		if got := test.fn(test.input); got != test.output {
+			t.Errorf("%s(%q): expected %q; got %q", test.name, test.input, test.output, got)
+		}
+	}
+}
+
+func TestAttr(t *testing.T) {
+	tests := []struct {
+		input  []interface{}
+		output HTMLAttr
+		err    bool
+	}{
+		{[]interface{}{"class", "a b", "id", ""}, `class="a b"`, false},
+		{[]interface{}{"title", `"O'Reilly"`, "disabled", true, "checked", false}, `title="&#34;O&#39;Reilly&#34;" disabled`, false},
+		{[]interface{}{"href", "javascript:alert(1)", "alt", nil}, `href="#ZgotmplZ"`, false},
+		{[]interface{}{"src", "/a b.png", "style", "color: red"}, `src="/a%20b.png" style="color: red"`, false},
+		{[]interface{}{"value", 42}, `value="42"`, false},
+		{[]interface{}{"style", "color: red; margin: 0;"}, `style="color: red; margin: 0"`, false},
+		{[]interface{}{"style", "color: red; background: url(x)"}, `style="ZgotmplZ"`, false},
+		{[]interface{}{"style", "color"}, `style="ZgotmplZ"`, false},
+		{[]interface{}{"formaction", "javascript:alert(1)"}, `formaction="#ZgotmplZ"`, false},
+		{[]interface{}{"onclick", "alert(1)"}, "", true},
+		{[]interface{}{"OnLoad", "alert(1)"}, "", true},
+		{[]interface{}{"srcdoc", "<script>alert(1)</script>"}, "", true},
+		{[]interface{}{"http-equiv", "refresh"}, "", true},
+		{[]interface{}{"rel", "import"}, "", true},
+		{[]interface{}{"a b", "c"}, "", true},
+		{[]interface{}{"class"}, "", true},
+	}
+	for _, test := range tests {
+		got, err := Attr(test.input...)
+		if test.err {
+			if err == nil {
+				t.Errorf("Attr(%v): expected error; got %q", test.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Attr(%v): unexpected error: %v", test.input, err)
+		} else if got != test.output {
+			t.Errorf("Attr(%v): expected %q; got %q", test.input, test.output, got)
+		}
+	}
+}
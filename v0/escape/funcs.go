@@ -180,3 +180,32 @@ func URLQueryEscaper(args ...interface{}) string {
 	}
 	return url.QueryEscape(s)
 }
+
+// Sanitizers of contextual escaping. Like in templates, values of the
+// matching content type are considered safe.
+
+// JSStrEscaper returns its arguments escaped for inclusion between quotes in
+// a JavaScript string, as {{.}} is escaped in '...' in a <script> element.
+func JSStrEscaper(args ...interface{}) string {
+	return jsStrEscaper(args...)
+}
+
+// CSSValueFilter returns its arguments if they are a safe CSS value, such
+// as a quantity, a color or a keyword, or "ZgotmplZ" otherwise. Values of
+// type CSS are returned unchanged.
+func CSSValueFilter(args ...interface{}) string {
+	return cssValueFilter(args...)
+}
+
+// URLNormalizer returns its arguments as a URL, encoding the characters
+// that are not valid in URLs but keeping the existing escape sequences, as
+// for {{.}} at the end of href="/path/". Unsafe protocols are not filtered.
+func URLNormalizer(args ...interface{}) string {
+	return urlNormalizer(args...)
+}
+
+// RCDATAEscaper returns its arguments escaped for inclusion in the body of
+// a <textarea> or <title> element.
+func RCDATAEscaper(args ...interface{}) string {
+	return rcdataEscaper(args...)
+}
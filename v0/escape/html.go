@@ -7,7 +7,10 @@ package escape
 import (
 	"bytes"
 	"fmt"
+	"html"
+	"regexp"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -41,7 +44,7 @@ func rcdataEscaper(args ...interface{}) string {
 // htmlEscaper escapes for inclusion in HTML text.
 func htmlEscaper(args ...interface{}) string {
 	s, t := stringify(args...)
-	if t == contentTypeHTML {
+	if t == contentTypeHTML || t == contentTypeDoctype && isDecl(s) {
 		return s
 	}
 	return htmlReplacer(s, htmlReplacementTable, true)
@@ -214,6 +217,273 @@ func stripTags(html string) string {
 	return b.String()
 }
 
+// PlainText converts HTML to readable plain text, for example for a meta
+// description or the plain text alternative of an email. Tags, comments
+// and the content of <script> and <style> elements are removed, entities
+// are decoded and whitespace is collapsed. Block elements such as <p> and
+// <li> start a new line or paragraph.
+//
+// The input is always treated as HTML. The result is plain text, so it is
+// escaped again if printed in an HTML context.
+func PlainText(args ...interface{}) string {
+	s, _ := stringify(args...)
+	var p plainText
+	htmlSegments([]byte(s), func(seg []byte, kind segmentKind) {
+		switch kind {
+		case segmentText:
+			p.text(string(seg))
+		case segmentTag:
+			name, _ := tagName(seg)
+			p.tag(name)
+		}
+	})
+	return p.b.String()
+}
+
+// plainTextBreaks maps block elements to the number of line breaks they
+// produce in PlainText.
+var plainTextBreaks = map[string]int{
+	"address": 2, "article": 2, "aside": 2, "blockquote": 2, "dd": 1,
+	"div": 1, "dl": 2, "dt": 1, "figure": 2, "footer": 2, "form": 2,
+	"h1": 2, "h2": 2, "h3": 2, "h4": 2, "h5": 2, "h6": 2, "header": 2,
+	"hr": 2, "li": 1, "nav": 2, "ol": 2, "p": 2, "pre": 2, "section": 2,
+	"table": 2, "tr": 1, "ul": 2,
+}
+
+// plainText accumulates the output of PlainText.
+type plainText struct {
+	b bytes.Buffer
+	// space is set when whitespace is pending before the next word.
+	space bool
+	// breaks is the number of line breaks pending before the next word.
+	breaks int
+}
+
+// text adds HTML text content, collapsing whitespace.
+func (p *plainText) text(s string) {
+	for _, r := range html.UnescapeString(s) {
+		if unicode.IsSpace(r) {
+			p.space = true
+			continue
+		}
+		if p.b.Len() != 0 {
+			if p.breaks != 0 {
+				p.b.WriteString(strings.Repeat("\n", p.breaks))
+			} else if p.space {
+				p.b.WriteByte(' ')
+			}
+		}
+		p.b.WriteRune(r)
+		p.space, p.breaks = false, 0
+	}
+}
+
+// tag adds the line breaks produced by the named start or end tag.
+func (p *plainText) tag(name string) {
+	name = strings.ToLower(name)
+	if name == "br" {
+		p.breaks++
+	} else if n := plainTextBreaks[name]; n > p.breaks {
+		p.breaks = n
+	}
+}
+
+// TruncateHTML shortens HTML content to n visible characters, as in
+// {{html.truncate 200 .Body}}. Whitespace counts as one character and an
+// entity as the character it stands for. If the content is longer, it is
+// cut at the last word boundary, an ellipsis is appended and the elements
+// left open are closed, so the result stays balanced.
+//
+// Values that are not typed as HTML are escaped first.
+func TruncateHTML(n int, v interface{}) HTML {
+	s, t := stringify(v)
+	if t != contentTypeHTML {
+		s = htmlEscaper(s)
+	}
+	var b bytes.Buffer
+	var open []string
+	count, space, done := 0, false, false
+	htmlSegments([]byte(s), func(seg []byte, kind segmentKind) {
+		if done {
+			return
+		}
+		if kind == segmentTag {
+			name, end := tagName(seg)
+			switch {
+			case name == "" || voidElements[name] || bytes.HasSuffix(seg, []byte("/>")):
+			case end:
+				for i := len(open) - 1; i >= 0; i-- {
+					if open[i] == name {
+						open = open[:i]
+						break
+					}
+				}
+			default:
+				open = append(open, name)
+			}
+		}
+		if kind != segmentText {
+			b.Write(seg)
+			return
+		}
+		afterSpace := space
+		for i := 0; i < len(seg); {
+			if isHTMLSpace(seg[i]) {
+				if !space && count < n {
+					count++
+				}
+				space = true
+				i++
+				continue
+			}
+			if count == n {
+				// Cut at the last word boundary in the segment, or
+				// before it if the word started with the segment.
+				cut := i
+				if i > 0 && !isHTMLSpace(seg[i-1]) {
+					if j := bytes.LastIndexAny(seg[:i], " \t\n\f\r"); j >= 0 {
+						cut = j
+					} else if afterSpace {
+						cut = 0
+					}
+				}
+				b.Write(bytes.TrimRight(seg[:cut], " \t\n\f\r"))
+				done = true
+				return
+			}
+			count, space = count+1, false
+			i += htmlCharLen(seg[i:])
+		}
+		b.Write(seg)
+	})
+	if !done {
+		return HTML(s)
+	}
+	b.WriteString("\u2026")
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return HTML(b.String())
+}
+
+// voidElements are the HTML elements that have no end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// isHTMLSpace returns whether c is an HTML whitespace character.
+func isHTMLSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+// htmlCharLen returns the length of the character or entity at the start
+// of s, which must not be empty.
+func htmlCharLen(s []byte) int {
+	if s[0] == '&' {
+		if i := bytes.IndexByte(s, ';'); i > 1 && i < 33 && bytes.IndexAny(s[1:i], " \t\n\f\r&<") < 0 {
+			return i + 1
+		}
+		return 1
+	}
+	_, size := utf8.DecodeRune(s)
+	return size
+}
+
+// segmentKind is the kind of a part of HTML content found by htmlSegments.
+type segmentKind int
+
+const (
+	// segmentText is text content, including the content of RCDATA
+	// elements like <textarea>.
+	segmentText segmentKind = iota
+	// segmentTag is a start or end tag, attributes included.
+	segmentTag
+	// segmentOther is a comment, a declaration, or the content of a
+	// <script> or <style> element.
+	segmentOther
+)
+
+// segmentKindOf returns the kind of segment the context is in.
+func segmentKindOf(c context) segmentKind {
+	switch {
+	case c.delim != delimNone || isInTag(c.state):
+		return segmentTag
+	case c.state == stateText || c.state == stateRCDATA:
+		return segmentText
+	}
+	return segmentOther
+}
+
+// htmlSegments splits the HTML content s into text, tags and other markup,
+// and calls fn for each of them in order. As in stripTags, the context
+// transition functions are used so that `<div title="1>2">` and
+// `I <3 Ponies!` are split correctly.
+func htmlSegments(s []byte, fn func(seg []byte, kind segmentKind)) {
+	c, i, start := context{}, 0, 0
+	for i != len(s) {
+		var d context
+		var i1 int
+		if c.delim == delimNone {
+			st := c.state
+			// Use RCDATA instead of parsing into JS or CSS styles.
+			if c.element != elementNone && !isInTag(st) {
+				st = stateRCDATA
+			}
+			var nread int
+			d, nread = transitionFunc[st](c, s[i:])
+			i1 = i + nread
+		} else {
+			i1 = i + bytes.IndexAny(s[i:], delimEnds[c.delim])
+			if i1 < i {
+				break
+			}
+			if c.delim != delimSpaceOrTagEnd {
+				// Consume any quote.
+				i1++
+			}
+			d = context{state: stateTag, element: c.element}
+		}
+		if k0, k1 := segmentKindOf(c), segmentKindOf(d); k0 != k1 {
+			// Text ends at the start of the tag or comment; other
+			// segments end where the transition ends.
+			end := i1
+			if k0 == segmentText {
+				for j := i1 - 1; j >= i; j-- {
+					if s[j] == '<' {
+						end = j
+						break
+					}
+				}
+			}
+			if end > start {
+				fn(s[start:end], k0)
+			}
+			start = end
+		}
+		c, i = d, i1
+	}
+	if start < len(s) {
+		fn(s[start:], segmentKindOf(c))
+	}
+}
+
+// tagName returns the lower-case name of the start or end tag in s, and
+// whether it is an end tag.
+func tagName(s []byte) (name string, end bool) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		end, i = true, i+1
+	}
+	j, _ := eatTagName(s, i)
+	return strings.ToLower(string(s[i:j])), end
+}
+
 // htmlNameFilter accepts valid parts of an HTML attribute or tag name or
 // a known-safe HTML attribute.
 func htmlNameFilter(args ...interface{}) string {
@@ -246,6 +516,40 @@ func htmlNameFilter(args ...interface{}) string {
 	return s
 }
 
+// declFilter accepts the content of a <!DOCTYPE ...> or <?xml ...?>
+// declaration, such as a public identifier or an encoding name. It returns
+// filterFailsafe for values that would end the declaration and inject
+// markup after it.
+func declFilter(args ...interface{}) string {
+	s, _ := stringify(args...)
+	if strings.ContainsAny(s, "<>\x00") {
+		return filterFailsafe
+	}
+	return s
+}
+
+// declPatterns match a whole document type declaration, with an optional
+// public or system identifier, and a whole XML declaration.
+var declPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^<!(?i:DOCTYPE)\s+[A-Za-z][A-Za-z0-9:_-]*` +
+		`(\s+(?i:PUBLIC)\s+("[^"<>]*"|'[^'<>]*')(\s+("[^"<>]*"|'[^'<>]*'))?` +
+		`|\s+(?i:SYSTEM)\s+("[^"<>]*"|'[^'<>]*'))?\s*>$`),
+	regexp.MustCompile(`^<\?xml\s+version\s*=\s*("1\.[0-9]+"|'1\.[0-9]+')` +
+		`(\s+encoding\s*=\s*("[A-Za-z][A-Za-z0-9._-]*"|'[A-Za-z][A-Za-z0-9._-]*'))?` +
+		`(\s+standalone\s*=\s*("(yes|no)"|'(yes|no)'))?\s*\?>$`),
+}
+
+// isDecl reports whether s is a whole document type or XML declaration,
+// which can be written as is in text. See Doctype.
+func isDecl(s string) bool {
+	for _, p := range declPatterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
 // commentEscaper returns the empty string regardless of input.
 // Comment content does not correspond to any parsed structure or
 // human-readable content, so the simplest and most secure policy is to drop
@@ -69,6 +69,75 @@ func TestStripTags(t *testing.T) {
 	}
 }
 
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		input interface{}
+		want  string
+	}{
+		{"", ""},
+		{"Tom &amp; Jerry &lt;3", "Tom & Jerry <3"},
+		{HTML("<p>Hello,\n  <b>World</b>!</p><p>Bye</p>"), "Hello, World!\n\nBye"},
+		{"<ul><li>one</li><li>two</li></ul>after", "one\ntwo\n\nafter"},
+		{"a<br>b<br/><br>c", "a\nb\n\nc"},
+		{`<style>p {}</style><script>alert("<p>")</script>Text<!-- hidden -->`, "Text"},
+		{`<div title="1>2">x</div><DIV>y</DIV>`, "x\ny"},
+		{"<h1>Title</h1>\n\n<p>  body&nbsp;text </p>", "Title\n\nbody text"},
+	}
+	for _, test := range tests {
+		if got := PlainText(test.input); got != test.want {
+			t.Errorf("%q: want %q, got %q", test.input, test.want, got)
+		}
+	}
+}
+
+func TestIsDecl(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`<!DOCTYPE html>`, true},
+		{`<!doctype html>`, true},
+		{`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`, true},
+		{`<!DOCTYPE note SYSTEM 'note.dtd'>`, true},
+		{`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`, true},
+		{`<?xml version='1.1'?>`, true},
+		{`<!DOCTYPE html><script>alert(1)</script>`, false},
+		{`<!DOCTYPE html PUBLIC "a>b">`, false},
+		{`<!DOCTYPE>`, false},
+		{`<?xml version="1.0" onload="x"?>`, false},
+		{`<?xml version="1.0"?><a>`, false},
+	}
+	for _, test := range tests {
+		if got := isDecl(test.input); got != test.want {
+			t.Errorf("%q: want %v, got %v", test.input, test.want, got)
+		}
+	}
+}
+
+func TestTruncateHTML(t *testing.T) {
+	tests := []struct {
+		n     int
+		input interface{}
+		want  HTML
+	}{
+		{10, HTML("<b>short</b>"), "<b>short</b>"},
+		{12, HTML("<p>Hello, <b>brave new</b> world</p>"), "<p>Hello, <b>brave\u2026</b></p>"},
+		{11, HTML("<p>Hello, <b>brave new</b> world</p>"), "<p>Hello, <b>\u2026</b></p>"},
+		{8, HTML("<p>Hello,\n   World</p>"), "<p>Hello,\u2026</p>"},
+		{3, HTML("<p>abcdef</p>"), "<p>abc\u2026</p>"},
+		{5, HTML("Tom &amp; Jerry"), "Tom &amp;\u2026"},
+		{4, HTML(`<a href="/x?a=1&b=2" title="1>2">link text</a><br>`), "<a href=\"/x?a=1&b=2\" title=\"1>2\">link\u2026</a>"},
+		{4, HTML("<div><img src=a.png><br/><span>one two</span></div>"), "<div><img src=a.png><br/><span>one\u2026</span></div>"},
+		{2, HTML("<script>var a = '<b>';</script>ab cd"), "<script>var a = '<b>';</script>ab\u2026"},
+		{6, "<b>untrusted</b>", "&lt;b&gt;unt\u2026"},
+	}
+	for _, test := range tests {
+		if got := TruncateHTML(test.n, test.input); got != test.want {
+			t.Errorf("%d, %q: want %q, got %q", test.n, test.input, test.want, got)
+		}
+	}
+}
+
 func BenchmarkHTMLNospaceEscaper(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		htmlNospaceEscaper("The <i>quick</i>,\r\n<span style='color:brown'>brown</span> fox jumps\u2028over the <canine class=\"lazy\">dog</canine>")
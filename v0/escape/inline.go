@@ -0,0 +1,156 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// InlineCode describes the inline code of a kind in a template, which a
+// Content-Security-Policy without 'unsafe-inline' or nonces blocks.
+type InlineCode struct {
+	Template string   // The name of the template.
+	Kind     string   // "script", "style", "event handler" or "style attribute".
+	Actions  []string // The actions that write into the code, if any.
+}
+
+// FindInlineCode returns the inline scripts, styles, event handlers and
+// style attributes of the templates in the tree, with the actions that
+// write into them, sorted by template and kind. It is meant to plan the
+// adoption of a Content-Security-Policy. The tree must be inlined, and it
+// can be escaped already; it is not modified. An error is returned if it
+// can't be escaped.
+func FindInlineCode(tree parse.Tree) ([]InlineCode, error) {
+	e, err := analyze(tree)
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[string]*InlineCode)
+	var keys []string
+	add := func(name, kind, action string) {
+		name = originalName(name)
+		key := name + "\x00" + kind
+		code := codes[key]
+		if code == nil {
+			code = &InlineCode{Template: name, Kind: kind}
+			codes[key] = code
+			keys = append(keys, key)
+		}
+		if action == "" {
+			return
+		}
+		for _, a := range code.Actions {
+			if a == action {
+				return
+			}
+		}
+		code.Actions = append(code.Actions, action)
+	}
+	for _, t := range []parse.Tree{tree, e.derived} {
+		for name, define := range t {
+			inlineCodeNodes(define.List, func(n parse.Node) {
+				switch n := n.(type) {
+				case *parse.ActionNode:
+					if kind := e.actionSinks[n]; kind != "" {
+						add(name, kind, n.String())
+					}
+				case *parse.TextNode:
+					for _, kind := range e.textSinks[n] {
+						add(name, kind, "")
+					}
+				}
+			})
+		}
+	}
+	sort.Strings(keys)
+	list := make([]InlineCode, len(keys))
+	for i, key := range keys {
+		list[i] = *codes[key]
+	}
+	return list, nil
+}
+
+// analyze escapes the templates in the tree, without modifying it, and
+// returns the escaper with the results.
+func analyze(tree parse.Tree) (*escaper, error) {
+	e := newEscaper(tree)
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		// Skip the templates derived by an earlier escaping; they are
+		// analyzed from their callers.
+		if !strings.Contains(name, "$htmltemplate_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c, _ := e.escapeDefine(context{}, name, 0)
+		if c.err != nil {
+			c.err.Name = name
+			return nil, c.err
+		}
+	}
+	return e, nil
+}
+
+// originalName returns the name of the template from which the named one
+// was derived by escaping, or the name itself.
+func originalName(name string) string {
+	if i := strings.Index(name, "$htmltemplate_"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// inlineCodeKind returns the kind of inline code that contains the
+// context, or an empty string if it is not in code.
+func inlineCodeKind(c context) string {
+	js := c.state >= stateJS && c.state <= stateJSLineCmt
+	css := c.state >= stateCSS && c.state <= stateCSSLineCmt
+	switch {
+	case js && c.delim != delimNone:
+		return "event handler"
+	case css && c.delim != delimNone:
+		return "style attribute"
+	case js:
+		return "script"
+	case css:
+		return "style"
+	}
+	return ""
+}
+
+// inlineCodeNodes calls fn for each action and text node in n.
+func inlineCodeNodes(n parse.Node, fn func(parse.Node)) {
+	switch n := n.(type) {
+	case *parse.ActionNode, *parse.TextNode:
+		fn(n)
+	case *parse.CaptureNode:
+		inlineCodeNodes(n.List, fn)
+	case *parse.CustomNode:
+		inlineCodeNodes(n.List, fn)
+	case *parse.IfNode:
+		inlineCodeNodes(n.List, fn)
+		inlineCodeNodes(n.ElseList, fn)
+	case *parse.LetNode:
+		inlineCodeNodes(n.List, fn)
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, m := range n.Nodes {
+			inlineCodeNodes(m, fn)
+		}
+	case *parse.RangeNode:
+		inlineCodeNodes(n.List, fn)
+		inlineCodeNodes(n.ElseList, fn)
+	case *parse.WithNode:
+		inlineCodeNodes(n.List, fn)
+		inlineCodeNodes(n.ElseList, fn)
+	}
+}
@@ -130,6 +130,31 @@ func indirectToJSONMarshaler(a interface{}) interface{} {
 	return v.Interface()
 }
 
+// jsonReplacer escapes the characters that can end a script element or a
+// JavaScript string. In JSON they can only appear inside strings, where
+// the escapes are valid.
+var jsonReplacer = strings.NewReplacer(
+	"<", `\u003c`,
+	">", `\u003e`,
+	"&", `\u0026`,
+	"\u2028", `\u2028`,
+	"\u2029", `\u2029`,
+)
+
+// JSON returns the JSON encoding of v, typed as a JS expression so that it
+// can be embedded in a <script> element or an event handler as is:
+//     <script>var data = {{js.json .Data}};</script>
+// Characters that could end the script element or a JavaScript string,
+// such as the "<" of "</script>" and U+2028, are escaped even when v is a
+// json.Marshaler.
+func JSON(v interface{}) (JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return JS(jsonReplacer.Replace(string(b))), nil
+}
+
 // jsValEscaper escapes its inputs to a JS Expression (section 11.14) that has
 // neither side-effects nor free variables outside (NaN, Infinity).
 func jsValEscaper(args ...interface{}) string {
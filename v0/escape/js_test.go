@@ -220,6 +220,37 @@ func TestJSStrEscaper(t *testing.T) {
 	}
 }
 
+type scriptMarshaler struct{}
+
+func (scriptMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte("\"</script>\u2028\""), nil
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		x    interface{}
+		json JS
+	}{
+		{nil, `null`},
+		{42, `42`},
+		{[]string{"a", "b"}, `["a","b"]`},
+		{map[string]string{"k": "</script><!--"}, `{"k":"\u003c/script\u003e\u003c!--"}`},
+		{"Tom & Jerry\u2029", `"Tom \u0026 Jerry\u2029"`},
+		{scriptMarshaler{}, `"\u003c/script\u003e\u2028"`},
+	}
+	for _, test := range tests {
+		js, err := JSON(test.x)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.x, err)
+		} else if js != test.json {
+			t.Errorf("%v: want %q got %q", test.x, test.json, js)
+		}
+	}
+	if _, err := JSON(func() {}); err == nil {
+		t.Errorf("expected error marshalling a func")
+	}
+}
+
 func TestJSRegexpEscaper(t *testing.T) {
 	tests := []struct {
 		x   interface{}
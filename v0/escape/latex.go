@@ -0,0 +1,34 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"strings"
+)
+
+// LaTeXEscaper returns the escaped LaTeX equivalent of the textual
+// representation of its arguments, to be written as text in a LaTeX
+// document:
+//     \section{ {{latex.escape .Title}} }
+// The special characters # $ % & _ { } are escaped with a backslash, and
+// \ ^ ~ are replaced by the commands that print them. Line breaks are
+// kept, so a blank line in the value still starts a new paragraph.
+func LaTeXEscaper(args ...interface{}) string {
+	s, _ := stringify(args...)
+	return latexReplacer.Replace(s)
+}
+
+var latexReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`#`, `\#`,
+	`$`, `\$`,
+	`%`, `\%`,
+	`&`, `\&`,
+	`_`, `\_`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`^`, `\textasciicircum{}`,
+	`~`, `\textasciitilde{}`,
+)
@@ -0,0 +1,27 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"testing"
+)
+
+func TestLaTeXEscaper(t *testing.T) {
+	tests := []struct {
+		x   interface{}
+		esc string
+	}{
+		{"plain text", `plain text`},
+		{`50% of $10 & #1_a {b}`, `50\% of \$10 \& \#1\_a \{b\}`},
+		{`C:\dir ~x^2`, `C:\textbackslash{}dir \textasciitilde{}x\textasciicircum{}2`},
+		{`\input{/etc/passwd}`, `\textbackslash{}input\{/etc/passwd\}`},
+		{42, `42`},
+	}
+	for _, test := range tests {
+		if esc := LaTeXEscaper(test.x); esc != test.esc {
+			t.Errorf("%v: want %q got %q", test.x, test.esc, esc)
+		}
+	}
+}
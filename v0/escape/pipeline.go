@@ -132,6 +132,7 @@ func appendCmd(cmds []*parse.CommandNode, cmd *parse.CommandNode) []*parse.Comma
 func newIdentCmd(identifier string, pos parse.Pos) *parse.CommandNode {
 	return &parse.CommandNode{
 		NodeType: parse.NodeCommand,
+		Pos:      pos,
 		Args:     []parse.Node{parse.NewIdentifier(identifier).SetPos(pos)},
 	}
 }
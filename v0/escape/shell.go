@@ -0,0 +1,83 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"errors"
+	"strings"
+)
+
+// errShellNUL is returned when a value to quote for a shell contains a NUL
+// byte, which can't be part of a command line argument.
+var errShellNUL = errors.New("escape: NUL byte in shell argument")
+
+// ShellQuote returns its arguments quoted as words for a POSIX shell,
+// separated by spaces, so that each one is passed as a single argument
+// whatever characters it contains:
+//     cp -- {{shell.quote .Source}} {{shell.quote .Dest}}
+// An argument that is a slice of strings gives one word per element.
+// Words that only contain letters, digits and characters without special
+// meaning, such as "-" or "/", are written as is; other words are single
+// quoted.
+func ShellQuote(args ...interface{}) (string, error) {
+	return shellWords(args, shellQuote)
+}
+
+// PowerShellQuote is like ShellQuote, but quotes the words for PowerShell.
+// Words are always single quoted, since PowerShell gives a special meaning
+// to many characters in bare words.
+func PowerShellQuote(args ...interface{}) (string, error) {
+	return shellWords(args, powerShellQuote)
+}
+
+// shellWords quotes the words in args with quote, and joins them with
+// spaces.
+func shellWords(args []interface{}, quote func(string) string) (string, error) {
+	var words []string
+	for _, arg := range args {
+		if a, ok := indirect(arg).([]string); ok {
+			words = append(words, a...)
+			continue
+		}
+		s, _ := stringify(arg)
+		words = append(words, s)
+	}
+	for i, w := range words {
+		if strings.IndexByte(w, 0) >= 0 {
+			return "", errShellNUL
+		}
+		words[i] = quote(w)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// shellQuote returns s quoted for a POSIX shell. A single quote can't be
+// escaped inside single quotes, so it ends the quoted string, is escaped
+// with a backslash, and a new quoted string starts.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, shellSafe) == "" {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// shellSafe are the characters that don't need quoting in a POSIX shell
+// word.
+const shellSafe = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789@%+=:,./_-"
+
+// powerShellQuotes are the characters PowerShell reads as single quotes.
+var powerShellQuotes = strings.NewReplacer(
+	"'", "''",
+	"‘", "‘‘",
+	"’", "’’",
+	"‚", "‚‚",
+	"‛", "‛‛",
+)
+
+// powerShellQuote returns s as a PowerShell single-quoted string, where
+// quotes are escaped by doubling them.
+func powerShellQuote(s string) string {
+	return "'" + powerShellQuotes.Replace(s) + "'"
+}
@@ -0,0 +1,41 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		args     []interface{}
+		sh, pwsh string
+	}{
+		{[]interface{}{""}, `''`, `''`},
+		{[]interface{}{"a/b-c_1.txt"}, `a/b-c_1.txt`, `'a/b-c_1.txt'`},
+		{[]interface{}{"a b"}, `'a b'`, `'a b'`},
+		{[]interface{}{"it's"}, `'it'\''s'`, `'it''s'`},
+		{[]interface{}{"$(rm -rf /); `x` \"y\""}, "'$(rm -rf /); `x` \"y\"'", "'$(rm -rf /); `x` \"y\"'"},
+		{[]interface{}{"a’b"}, "'a’b'", "'a’’b'"},
+		{[]interface{}{"x", []string{"y z", "w"}, 3}, `x 'y z' w 3`, `'x' 'y z' 'w' '3'`},
+	}
+	for _, test := range tests {
+		sh, err := ShellQuote(test.args...)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.args, err)
+		} else if sh != test.sh {
+			t.Errorf("%v: want %s got %s", test.args, test.sh, sh)
+		}
+		pwsh, err := PowerShellQuote(test.args...)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.args, err)
+		} else if pwsh != test.pwsh {
+			t.Errorf("%v: want %s got %s", test.args, test.pwsh, pwsh)
+		}
+	}
+	if _, err := ShellQuote("a\x00b"); err == nil {
+		t.Errorf("expected error quoting a NUL byte")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLDialect selects the quoting rules of a database for SQLDialect.Literal
+// and SQLDialect.Identifier.
+//
+// These functions are meant to generate SQL files, such as migrations or
+// reports, from trusted templates. They are not a substitute for the
+// parameterized queries of database/sql: programs that run queries built
+// from untrusted input must pass the values as query arguments.
+type SQLDialect int
+
+const (
+	// SQLStandard follows the SQL standard, as PostgreSQL, SQLite and
+	// Oracle do: strings are quoted with ' and identifiers with ".
+	// PostgreSQL must use standard_conforming_strings, the default since
+	// version 9.1, so that backslashes in strings have no special meaning.
+	SQLStandard SQLDialect = iota
+	// SQLMySQL is for MySQL and MariaDB: strings are quoted with ', with
+	// backslash escapes, and identifiers with `. The NO_BACKSLASH_ESCAPES
+	// SQL mode must be disabled, which is the default.
+	SQLMySQL
+	// SQLServer is for Microsoft SQL Server: strings are quoted with N',
+	// so that they keep non-ASCII characters, and identifiers with [].
+	SQLServer
+)
+
+var sqlDialectNames = map[SQLDialect]string{
+	SQLStandard: "SQLStandard",
+	SQLMySQL:    "SQLMySQL",
+	SQLServer:   "SQLServer",
+}
+
+func (d SQLDialect) String() string {
+	if name, ok := sqlDialectNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("SQLDialect(%d)", int(d))
+}
+
+// errSQLNUL is returned for values that contain a NUL byte, which most
+// databases don't accept in strings and identifiers.
+var errSQLNUL = errors.New("escape: NUL byte in SQL value")
+
+// Literal returns v as a literal of the dialect:
+//     INSERT INTO users (name, age) VALUES ({{sql.literal .Name}}, {{sql.literal .Age}});
+// nil is NULL, booleans and numbers are written as is, except that negative
+// numbers are parenthesized so that a minus sign before them can't start a
+// comment, byte slices as hexadecimal strings, times as quoted RFC 3339 timestamps, and other
+// values, such as strings and values that implement fmt.Stringer, as
+// quoted strings. It is an error if v contains a NUL byte, or is a
+// floating-point number that is not finite.
+func (d SQLDialect) Literal(v interface{}) (string, error) {
+	if v == nil {
+		return "NULL", nil
+	}
+	if t, ok := indirect(v).(time.Time); ok {
+		return d.quote(t.Format(time.RFC3339Nano))
+	}
+	v = indirectToStringerOrError(v)
+	switch t := v.(type) {
+	case []byte:
+		if d == SQLServer {
+			return fmt.Sprintf("0x%x", t), nil
+		}
+		return fmt.Sprintf("X'%x'", t), nil
+	case fmt.Stringer:
+		return d.quote(t.String())
+	case error:
+		return d.quote(t.Error())
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		// Nil pointers are left by indirection.
+		return "NULL", nil
+	case reflect.Bool:
+		switch {
+		case d == SQLServer && rv.Bool():
+			return "1", nil
+		case d == SQLServer:
+			return "0", nil
+		case rv.Bool():
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sqlNumber(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("escape: %v has no SQL literal", f)
+		}
+		return sqlNumber(strconv.FormatFloat(f, 'g', -1, rv.Type().Bits())), nil
+	}
+	return d.quote(fmt.Sprint(v))
+}
+
+// sqlNumber parenthesizes the number s if it is negative, as in (-5), so
+// that it is a single term even after a minus sign: 10-{{.N}} with a
+// negative N would otherwise start a -- comment.
+func sqlNumber(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// quote returns s as a string literal of the dialect.
+func (d SQLDialect) quote(s string) (string, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return "", errSQLNUL
+	}
+	switch d {
+	case SQLMySQL:
+		return "'" + mysqlReplacer.Replace(s) + "'", nil
+	case SQLServer:
+		return "N'" + strings.Replace(s, "'", "''", -1) + "'", nil
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'", nil
+}
+
+// mysqlReplacer escapes the characters escaped by mysql_real_escape_string.
+var mysqlReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x1a", `\Z`,
+)
+
+// Identifier returns the name of a table, column or other object quoted as
+// an identifier of the dialect, so that it can't be read as a keyword or
+// end the identifier. Several names give a qualified name, as in
+//     SELECT * FROM {{sql.ident "reports" .Table}};
+// which writes "reports"."sales" in the standard dialect. It is an error if
+// a name is empty or contains a NUL byte.
+func (d SQLDialect) Identifier(names ...string) (string, error) {
+	if len(names) == 0 {
+		return "", errors.New("escape: SQL identifier needs a name")
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		if name == "" {
+			return "", errors.New("escape: empty SQL identifier")
+		}
+		if strings.IndexByte(name, 0) >= 0 {
+			return "", errSQLNUL
+		}
+		switch d {
+		case SQLMySQL:
+			quoted[i] = "`" + strings.Replace(name, "`", "``", -1) + "`"
+		case SQLServer:
+			quoted[i] = "[" + strings.Replace(name, "]", "]]", -1) + "]"
+		default:
+			quoted[i] = `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+		}
+	}
+	return strings.Join(quoted, "."), nil
+}
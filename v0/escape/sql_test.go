@@ -0,0 +1,77 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSQLLiteral(t *testing.T) {
+	var nilPtr *string
+	day := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		x                 interface{}
+		std, mysql, mssql string
+	}{
+		{nil, `NULL`, `NULL`, `NULL`},
+		{nilPtr, `NULL`, `NULL`, `NULL`},
+		{true, `TRUE`, `TRUE`, `1`},
+		{false, `FALSE`, `FALSE`, `0`},
+		{-42, `(-42)`, `(-42)`, `(-42)`},
+		{-2.5, `(-2.5)`, `(-2.5)`, `(-2.5)`},
+		{1e-7, `1e-07`, `1e-07`, `1e-07`},
+		{2.5, `2.5`, `2.5`, `2.5`},
+		{"O'Brien", `'O''Brien'`, `'O\'Brien'`, `N'O''Brien'`},
+		{`a\'; DROP TABLE x; --`, `'a\''; DROP TABLE x; --'`, `'a\\\'; DROP TABLE x; --'`, `N'a\''; DROP TABLE x; --'`},
+		{"line\nbreak", "'line\nbreak'", `'line\nbreak'`, "N'line\nbreak'"},
+		{[]byte("hi"), `X'6869'`, `X'6869'`, `0x6869`},
+		{day, `'2024-03-01T12:30:00Z'`, `'2024-03-01T12:30:00Z'`, `N'2024-03-01T12:30:00Z'`},
+		{&day, `'2024-03-01T12:30:00Z'`, `'2024-03-01T12:30:00Z'`, `N'2024-03-01T12:30:00Z'`},
+		{time.Second, `'1s'`, `'1s'`, `N'1s'`},
+	}
+	for _, test := range tests {
+		for d, want := range map[SQLDialect]string{SQLStandard: test.std, SQLMySQL: test.mysql, SQLServer: test.mssql} {
+			got, err := d.Literal(test.x)
+			if err != nil {
+				t.Errorf("%s %v: unexpected error: %v", d, test.x, err)
+			} else if got != want {
+				t.Errorf("%s %v: want %s got %s", d, test.x, want, got)
+			}
+		}
+	}
+	for _, x := range []interface{}{"a\x00b", math.NaN(), math.Inf(1)} {
+		if _, err := SQLStandard.Literal(x); err == nil {
+			t.Errorf("%q: expected error", x)
+		}
+	}
+}
+
+func TestSQLIdentifier(t *testing.T) {
+	tests := []struct {
+		names             []string
+		std, mysql, mssql string
+	}{
+		{[]string{"users"}, `"users"`, "`users`", `[users]`},
+		{[]string{"my schema", "t"}, `"my schema"."t"`, "`my schema`.`t`", `[my schema].[t]`},
+		{[]string{"a\"b`c]d"}, `"a""b` + "`" + `c]d"`, "`a\"b``c]d`", `[a"b` + "`" + `c]]d]`},
+	}
+	for _, test := range tests {
+		for d, want := range map[SQLDialect]string{SQLStandard: test.std, SQLMySQL: test.mysql, SQLServer: test.mssql} {
+			got, err := d.Identifier(test.names...)
+			if err != nil {
+				t.Errorf("%s %q: unexpected error: %v", d, test.names, err)
+			} else if got != want {
+				t.Errorf("%s %q: want %s got %s", d, test.names, want, got)
+			}
+		}
+	}
+	for _, names := range [][]string{nil, {""}, {"a", ""}, {"a\x00"}} {
+		if _, err := SQLStandard.Identifier(names...); err == nil {
+			t.Errorf("%q: expected error", names)
+		}
+	}
+}
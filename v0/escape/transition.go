@@ -37,11 +37,14 @@ var transitionFunc = [...]func(context, []byte) (context, int){
 	stateCSSURL:      tCSSStr,
 	stateCSSBlockCmt: tBlockCmt,
 	stateCSSLineCmt:  tLineCmt,
+	stateDecl:        tDecl,
 	stateError:       tError,
 }
 
 var commentStart = []byte("<!--")
 var commentEnd = []byte("-->")
+var doctypeBytes = []byte("<!DOCTYPE")
+var xmlDeclBytes = []byte("<?xml")
 
 // tText is the context transition function for the text state.
 func tText(c context, s []byte) (context, int) {
@@ -52,6 +55,10 @@ func tText(c context, s []byte) (context, int) {
 			return c, len(s)
 		} else if i+4 <= len(s) && bytes.Equal(commentStart, s[i:i+4]) {
 			return context{state: stateHTMLCmt}, i + 4
+		} else if bytes.HasPrefix(bytes.ToUpper(s[i:]), doctypeBytes) {
+			return context{state: stateDecl}, i + len(doctypeBytes)
+		} else if bytes.HasPrefix(s[i:], xmlDeclBytes) {
+			return context{state: stateDecl}, i + len(xmlDeclBytes)
 		}
 		i++
 		end := false
@@ -84,8 +91,13 @@ var elementContentType = [...]state{
 
 // tTag is the context transition function for the tag state.
 func tTag(c context, s []byte) (context, int) {
-	// Find the attribute name.
+	// Find the attribute name. A slash is ignored, as HTML parsers do, so
+	// self-closing void elements like <br/> end the tag as <br> does. The
+	// content of a <script> or other special element starts even after "/>".
 	i := eatWhiteSpace(s, 0)
+	for i < len(s) && s[i] == '/' {
+		i = eatWhiteSpace(s, i+1)
+	}
 	if i == len(s) {
 		return c, len(s)
 	}
@@ -174,6 +186,15 @@ func tBeforeValue(c context, s []byte) (context, int) {
 	return c, i
 }
 
+// tDecl is the context transition function for stateDecl.
+func tDecl(c context, s []byte) (context, int) {
+	i := bytes.IndexByte(s, '>')
+	if i == -1 {
+		return c, len(s)
+	}
+	return context{}, i + 1
+}
+
 // tHTMLCmt is the context transition function for stateHTMLCmt.
 func tHTMLCmt(c context, s []byte) (context, int) {
 	if i := bytes.Index(s, commentEnd); i != -1 {
@@ -486,7 +507,7 @@ func tError(c context, s []byte) (context, int) {
 func eatAttrName(s []byte, i int) (int, *Error) {
 	for j := i; j < len(s); j++ {
 		switch s[j] {
-		case ' ', '\t', '\n', '\f', '\r', '=', '>':
+		case ' ', '\t', '\n', '\f', '\r', '=', '>', '/':
 			return j, nil
 		case '\'', '"', '<':
 			// These result in a parse warning in HTML5 and are
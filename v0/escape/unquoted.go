@@ -0,0 +1,58 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"sort"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// UnquotedAttr describes an action that writes into an unquoted attribute
+// value, as in <a title={{.}}>. The output is escaped, but spaces and most
+// punctuation must be encoded, which makes it hard to read, and it is easy
+// to break the markup by editing the template around the action: quoting
+// the value is recommended.
+type UnquotedAttr struct {
+	Template string            // The name of the template.
+	Action   *parse.ActionNode // The action.
+}
+
+// FindUnquotedAttrs returns the actions in the templates of the tree that
+// write into unquoted attribute values, sorted by template and position.
+// The tree must be inlined and not escaped yet; it is not modified. An
+// error is returned if it can't be escaped.
+func FindUnquotedAttrs(tree parse.Tree) ([]UnquotedAttr, error) {
+	e, err := analyze(tree)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[*parse.ActionNode]bool)
+	var list []UnquotedAttr
+	for _, t := range []parse.Tree{tree, e.derived} {
+		for name, define := range t {
+			inlineCodeNodes(define.List, func(n parse.Node) {
+				if n, ok := n.(*parse.ActionNode); ok && e.unquoted[n] && !seen[n] {
+					seen[n] = true
+					list = append(list, UnquotedAttr{originalName(name), n})
+				}
+			})
+		}
+	}
+	sort.Sort(byPosition(list))
+	return list, nil
+}
+
+// byPosition sorts unquoted attributes by template and position.
+type byPosition []UnquotedAttr
+
+func (x byPosition) Len() int      { return len(x) }
+func (x byPosition) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x byPosition) Less(i, j int) bool {
+	if x[i].Template != x[j].Template {
+		return x[i].Template < x[j].Template
+	}
+	return x[i].Action.Pos < x[j].Action.Pos
+}
@@ -0,0 +1,135 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// YAML returns v encoded as a YAML scalar, to be interpolated in a YAML
+// document as a value:
+//     image: {{yaml.scalar .Image}}
+//     replicas: {{yaml.scalar .Replicas}}
+// Strings, and values that implement fmt.Stringer or error, are written as
+// double-quoted scalars, so that they can't be read as another type, such
+// as "yes" or "1e3", or change the structure of the document with
+// newlines, colons or comment markers. Booleans and numbers are written as
+// is and nil as null. Other values, such as slices, maps and structs, are
+// encoded as JSON, which is valid YAML flow syntax.
+func YAML(v interface{}) (string, error) {
+	if v == nil {
+		return "null", nil
+	}
+	v = indirectToStringerOrError(v)
+	switch t := v.(type) {
+	case string:
+		return yamlQuote(t), nil
+	case fmt.Stringer:
+		return yamlQuote(t.String()), nil
+	case error:
+		return yamlQuote(t.Error()), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case json.Marshaler:
+		return yamlJSON(t)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		// indirect stops at nil pointers.
+		return "null", nil
+	case reflect.String:
+		return yamlQuote(rv.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsNaN(f):
+			return ".nan", nil
+		case math.IsInf(f, 1):
+			return ".inf", nil
+		case math.IsInf(f, -1):
+			return "-.inf", nil
+		}
+		return strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()), nil
+	}
+	return yamlJSON(v)
+}
+
+// yamlJSON encodes v as JSON, which is a subset of YAML. Strings are
+// double-quoted and non-ASCII line breaks are escaped, so the result is
+// written on a single line.
+func yamlJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// yamlQuote returns s as a YAML double-quoted scalar. Line breaks, control
+// characters and other characters that YAML doesn't allow in documents are
+// escaped, and invalid UTF-8 bytes are replaced by U+FFFD.
+func yamlQuote(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, w := utf8.DecodeRuneInString(s[i:])
+		i += w
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case 0:
+			b.WriteString(`\0`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\v':
+			b.WriteString(`\v`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		case 0x1b:
+			b.WriteString(`\e`)
+		case 0x85:
+			b.WriteString(`\N`)
+		case 0x2028:
+			b.WriteString(`\L`)
+		case 0x2029:
+			b.WriteString(`\P`)
+		case 0xfeff:
+			b.WriteString(`\uFEFF`)
+		default:
+			switch {
+			case r < 0x20 || 0x7f <= r && r < 0xa0:
+				fmt.Fprintf(&b, `\x%02x`, r)
+			case 0xfffe <= r && r <= 0xffff:
+				fmt.Fprintf(&b, `\u%04x`, r)
+			default:
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,52 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestYAML(t *testing.T) {
+	var nilPtr *int
+	n := 7
+	tests := []struct {
+		x    interface{}
+		yaml string
+	}{
+		{nil, `null`},
+		{nilPtr, `null`},
+		{&n, `7`},
+		{true, `true`},
+		{-42, `-42`},
+		{uint8(200), `200`},
+		{1.5, `1.5`},
+		{math.Inf(-1), `-.inf`},
+		{math.NaN(), `.nan`},
+		{"yes", `"yes"`},
+		{"1e3", `"1e3"`},
+		{"a: b # c\nd", `"a: b # c\nd"`},
+		{`say "hi" \o/`, `"say \"hi\" \\o/"`},
+		{"\x00\x1b\x7f\u0085\u2028\ufeff\xff", `"\0\e\x7f\N\L\uFEFF` + "\ufffd" + `"`},
+		{"héllo", `"héllo"`},
+		{time.Second, `"1s"`},
+		{errors.New("boom"), `"boom"`},
+		{[]string{"a", "b"}, `["a","b"]`},
+		{map[string]int{"k": 1}, `{"k":1}`},
+	}
+	for _, test := range tests {
+		y, err := YAML(test.x)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.x, err)
+		} else if y != test.yaml {
+			t.Errorf("%v: want %q got %q", test.x, test.yaml, y)
+		}
+	}
+	if _, err := YAML(func() {}); err == nil {
+		t.Errorf("expected error encoding a func")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
@@ -442,6 +443,41 @@ func TestEscape(t *testing.T) {
 			`<!{{"DOCTYPE"}}`,
 			"&lt;!DOCTYPE",
 		},
+		{
+			"attr builtin",
+			`<input {{html.attr "name" .C "value" .N "checked" .T "disabled" .F}}>`,
+			`<input name="&lt;Cincinatti&gt;" value="42" checked>`,
+		},
+		{
+			"classes builtin",
+			`<div class="{{html.classes "a" (maps.dict "b" .F "d" .T) "<c>"}}">`,
+			`<div class="a d &lt;c&gt;">`,
+		},
+		{
+			"json builtin",
+			`<script>var a = {{js.json .A}};</script><button onclick="f({{js.json .H}})">`,
+			`<script>var a = ["\u003ca\u003e","\u003cb\u003e"];</script><button onclick="f(&#34;\u003cHello\u003e&#34;)">`,
+		},
+		{
+			"buildURL builtin",
+			`<a href="{{url.build "/s" (maps.dict "q" .H "n" .N)}}">`,
+			`<a href="/s?n=42&amp;q=%3CHello%3E">`,
+		},
+		{
+			"Dynamic doctype",
+			`<!DOCTYPE {{"html PUBLIC \"-//W3C//DTD XHTML 1.0 Strict//EN\""}}>Hello`,
+			`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN">Hello`,
+		},
+		{
+			"Dynamic XML declaration",
+			`<?xml version="1.0" encoding="{{"UTF-8"}}"?><feed>{{.H}}</feed>`,
+			`<?xml version="1.0" encoding="UTF-8"?><feed>&lt;Hello&gt;</feed>`,
+		},
+		{
+			"No markup injection in doctype",
+			`<!DOCTYPE {{"html><script>alert(1)</script"}}>`,
+			`<!DOCTYPE ZgotmplZ>`,
+		},
 		{
 			"Split HTML comment",
 			"<b>Hello, <!-- name of {{if .T}}city -->{{.C}}{{else}}world -->{{.W}}{{end}}</b>",
@@ -525,6 +561,11 @@ func TestEscape(t *testing.T) {
 			"<!--{{.}}--><script>/*{{.}}*///{{.}}\n</script><style>/*{{.}}*///{{.}}\n</style><a onclick='/*{{.}}*///{{.}}' style='/*{{.}}*///{{.}}'>",
 			"<script> \n</script><style> \n</style><a onclick='/**///' style='/**///'>",
 		},
+		{
+			"self-closing tag in branch",
+			`<br{{if .T}} /{{end}}><input {{if .T}}checked/{{end}}>`,
+			`<br /><input checked/>`,
+		},
 		{
 			"typed HTML in text",
 			`{{.W}}`,
@@ -876,16 +917,21 @@ func TestErrors(t *testing.T) {
 		{
 			// Missing quote in the else branch.
 			`{{if .Cond}}<a href="foo">{{else}}<a href="bar>{{end}}`,
-			map[string]string{"z": "z:1: {{if}} branches"},
+			map[string]string{"z": "z:1: {{if}} branches end in different contexts: " +
+				"the {{if}} branch ends in text, but the {{else}} branch ends in a URL in a double-quoted attribute, " +
+				"with an unclosed quote at z:1:57"},
 		},
 		{
 			// Different kind of attribute: href implies a URL.
 			"<a {{if .Cond}}href='{{else}}title='{{end}}{{.X}}'>",
-			map[string]string{"z": "z:1: {{if}} branches"},
+			map[string]string{"z": "z:1: {{if}} branches end in different contexts: " +
+				"the {{if}} branch ends in a URL in a single-quoted attribute, with an unclosed quote at z:1:34, " +
+				"but the {{else}} branch ends in a single-quoted attribute value, with an unclosed quote at z:1:49"},
 		},
 		{
 			"\n{{with .X}}<a{{end}}",
-			map[string]string{"z": "z:2: {{with}} branches"},
+			map[string]string{"z": "z:2: {{with}} branches end in different contexts: " +
+				"the {{with}} branch ends in a tag, with an unclosed tag at z:2:11, but the implicit {{else}} branch ends in text"},
 		},
 		{
 			"\n{{with .X}}<a>{{else}}<a{{end}}",
@@ -933,7 +979,7 @@ func TestErrors(t *testing.T) {
 			// where `i.test(x)` is a method call of reference i,
 			// or `/-1\.5/i.test(x)` which is a method call on a
 			// case insensitive regular expression.
-			`<script>{{if false}}var x = 1{{end}}/-{{"1.5"}}/i.test(x)</script>`,
+			`<script>{{if .}}var x = 1{{end}}/-{{"1.5"}}/i.test(x)</script>`,
 			map[string]string{"z": `'/' could start a division or regexp: "/-"`},
 		},
 		{
@@ -1076,12 +1122,44 @@ func TestIndirectPrint(t *testing.T) {
 	}
 }
 
+func TestDoctype(t *testing.T) {
+	set := Must(new(Set).Parse(`{{define "t"}}{{.}}<p>{{.}}</p>{{end}}`)).Escape()
+	tests := []struct {
+		input  escape.Doctype
+		output string
+	}{
+		{`<!DOCTYPE html>`, `<!DOCTYPE html><p><!DOCTYPE html></p>`},
+		{`<?xml version="1.0" encoding="UTF-8"?>`, `<?xml version="1.0" encoding="UTF-8"?><p><?xml version="1.0" encoding="UTF-8"?></p>`},
+		{`<!DOCTYPE html><script>`, `&lt;!DOCTYPE html&gt;&lt;script&gt;<p>&lt;!DOCTYPE html&gt;&lt;script&gt;</p>`},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := set.Execute(&b, "t", test.input); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.input, err)
+		} else if b.String() != test.output {
+			t.Errorf("%s: expected %q, got %q", test.input, test.output, b.String())
+		}
+	}
+}
+
 // This is a test for issue 3272.
 func TestEmptyTemplate(t *testing.T) {
-	page := Must(new(Set).ParseFiles(os.DevNull))
-	if err := page.Execute(os.Stdout, "page", "nothing"); err == nil {
+	_, err := new(Set).ParseFiles(os.DevNull)
+	if err == nil {
 		t.Fatal("expected error")
 	}
+	if want := os.DevNull + ": file defines no templates"; !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, want it to contain %q", err, want)
+	}
+	_, err = new(Set).ParseFiles("testdata/comment.tmpl")
+	if err == nil || !strings.Contains(err.Error(), "testdata/comment.tmpl: file defines no templates") {
+		t.Errorf("comment-only file: got error %v", err)
+	}
+	// With AutoDefine, an empty file defines an empty template.
+	page := Must(new(Set).AutoDefine().ParseFiles(os.DevNull))
+	if err := page.Execute(ioutil.Discard, os.DevNull, nil); err != nil {
+		t.Error(err)
+	}
 }
 
 func BenchmarkEscapedExecute(b *testing.B) {
@@ -70,3 +70,31 @@ Josie
 	// Best wishes,
 	// Josie
 }
+
+// This example exports a report as CSV. The set is not escaped, since the
+// output is not HTML, and the csv functions quote the fields that contain
+// commas, quotes or line breaks.
+func ExampleTemplate_csv() {
+	const report = `{{define "report"}}{{csv.row "Customer" "Item" "Amount"}}
+{{range .}}{{csv.row .Customer .Item .Amount}}
+{{end}}{{end}}`
+
+	type Order struct {
+		Customer, Item string
+		Amount         float64
+	}
+	var orders = []Order{
+		{"Acme, Inc.", "anvil", 120},
+		{"Wile E. Coyote", `rocket "express"`, 99.5},
+	}
+
+	t := template.Must(new(template.Set).Parse(report))
+	if err := t.Execute(os.Stdout, "report", orders); err != nil {
+		log.Println("executing template:", err)
+	}
+
+	// Output:
+	// Customer,Item,Amount
+	// "Acme, Inc.",anvil,120
+	// Wile E. Coyote,"rocket ""express""",99.5
+}
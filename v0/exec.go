@@ -5,13 +5,18 @@
 package template
 
 import (
+	"database/sql/driver"
+	"encoding"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"reflect"
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/gorilla/template/v0/escape"
 	"github.com/gorilla/template/v0/parse"
 )
 
@@ -19,23 +24,33 @@ import (
 // template so that multiple executions of the same template
 // can execute in parallel.
 type state struct {
-	set  *Set
+	snap *Snapshot // templates, functions and options for the execution.
 	tmpl *parse.DefineNode
 	wr   io.Writer
 	name string     // name of the template being executed.
 	node parse.Node // current node, for errors
 	vars []variable // push-down stack of variable values.
+	dry  bool       // evaluate pipelines but don't produce output.
+	// stack of profiled nodes being evaluated, if profiling.
+	stack []string
+	// errors recovered so far, in a lenient execution.
+	errs *[]error
+	// path of dot in the data, when recording accesses.
+	dotPath string
+	// names of the templates calling the one being executed, if debugging.
+	callers []string
 }
 
 // variable holds the dynamic value of a variable such as $, $x etc.
 type variable struct {
 	name  string
 	value reflect.Value
+	path  string // path of the value in the data, when recording accesses.
 }
 
 // push pushes a new variable on the stack.
 func (s *state) push(name string, value reflect.Value) {
-	s.vars = append(s.vars, variable{name, value})
+	s.vars = append(s.vars, variable{name, value, unknownPath})
 }
 
 // mark returns the length of the variable stack.
@@ -60,6 +75,9 @@ func (s *state) varValue(name string) reflect.Value {
 			return s.vars[i].value
 		}
 	}
+	if v, ok := s.snap.consts[name]; ok {
+		return v
+	}
 	s.errorf("undefined variable: %s", name)
 	return zero
 }
@@ -110,25 +128,75 @@ func errRecover(errp *error) {
 
 // Execute applies the template associated with t that has the given name
 // to the specified data object and writes the output to wr.
-func (s *Set) Execute(wr io.Writer, name string, data interface{}) (err error) {
-	defer errRecover(&err)
+func (s *Set) Execute(wr io.Writer, name string, data interface{}) error {
+	return s.execute(wr, name, data, false)
+}
+
+// DryRun applies the template that has the given name to the specified data
+// object like Execute, but discards the output. Pipelines are evaluated, so
+// missing fields, bad function arguments and the like are reported, but text
+// is not written and values are not escaped or formatted. It is a cheap way
+// to check in tests that some data is compatible with a template.
+func (s *Set) DryRun(name string, data interface{}) error {
+	return s.execute(ioutil.Discard, name, data, true)
+}
+
+// execute applies the named template to data. If dry is true the output is
+// discarded early, see DryRun.
+func (s *Set) execute(wr io.Writer, name string, data interface{}, dry bool) error {
 	// Inline and escape.
-	if _, err = s.Compile(); err != nil {
-		panic(err)
+	if _, err := s.Compile(); err != nil {
+		return err
 	}
-	// Now the real execution.
+	// Take the current templates, which may be replaced by Swap.
+	s.mutex.Lock()
+	snap := s.current()
+	s.mutex.Unlock()
+	return snap.execute(wr, name, data, dry)
+}
+
+// execute applies the named template from the snapshot to data.
+func (s *Snapshot) execute(wr io.Writer, name string, data interface{}, dry bool) error {
 	tmpl := s.tree[name]
 	if tmpl == nil {
-		return fmt.Errorf("template: no template %q in the set", name)
+		return notFound(s.tree, name)
+	}
+	if msg, ok := s.deprecated[name]; ok {
+		s.log.logf(LogWarn, "template: executing deprecated template %q: %s", name, msg)
+	}
+	return s.executeTemplate(wr, tmpl, data, dry)
+}
+
+// executeTemplate applies the template from the snapshot to data.
+func (s *Snapshot) executeTemplate(wr io.Writer, tmpl *parse.DefineNode, data interface{}, dry bool) (err error) {
+	defer errRecover(&err)
+	if s.log.slow > 0 {
+		defer s.log.logSlow(tmpl.Name, time.Now())
+	}
+	if !dry {
+		wr = newLineWriter(wr, s.lineEnding)
 	}
 	value := reflect.ValueOf(data)
 	state := &state{
-		set:  s,
+		snap: s,
 		tmpl: tmpl,
 		wr:   wr,
-		vars: []variable{{"$", value}},
+		vars: []variable{{"$", value, ""}},
+		dry:  dry,
+	}
+	if s.lenient {
+		state.errs = new([]error)
+	}
+	if s.indent && !dry {
+		state.wr = newIndentWriter(wr, nil)
 	}
 	state.walk(value, tmpl.List)
+	if err := flushLines(wr); err != nil {
+		return err
+	}
+	if state.errs != nil && len(*state.errs) > 0 {
+		return ExecErrors(*state.errs)
+	}
 	return
 }
 
@@ -136,6 +204,14 @@ func (s *Set) Execute(wr io.Writer, name string, data interface{}) (err error) {
 // generating output as they go.
 func (s *state) walk(dot reflect.Value, node parse.Node) {
 	s.at(node)
+	if s.snap.profile != nil {
+		if end := s.profileNode(node); end != nil {
+			defer end()
+		}
+	}
+	if s.errs != nil && lenientNode(node) {
+		defer s.recoverNode()
+	}
 	switch node := node.(type) {
 	case *parse.ActionNode:
 		// Do not pop variables so they persist until next end.
@@ -144,8 +220,12 @@ func (s *state) walk(dot reflect.Value, node parse.Node) {
 		if len(node.Pipe.Decl) == 0 {
 			s.printValue(node, val)
 		}
+	case *parse.CaptureNode:
+		s.walkCapture(dot, node)
 	case *parse.IfNode:
 		s.walkIfOrWith(parse.NodeIf, dot, node.Pipe, node.List, node.ElseList)
+	case *parse.LetNode:
+		s.walkLet(dot, node)
 	case *parse.ListNode:
 		for _, node := range node.Nodes {
 			s.walk(dot, node)
@@ -155,11 +235,16 @@ func (s *state) walk(dot reflect.Value, node parse.Node) {
 	case *parse.TemplateNode:
 		s.walkTemplate(dot, node)
 	case *parse.TextNode:
+		if s.dry {
+			break
+		}
 		if _, err := s.wr.Write(node.Text); err != nil {
 			s.errorf("%s", err)
 		}
 	case *parse.WithNode:
 		s.walkIfOrWith(parse.NodeWith, dot, node.Pipe, node.List, node.ElseList)
+	case *parse.CustomNode:
+		s.walkCustom(dot, node)
 	default:
 		s.errorf("unknown node: %s", node)
 	}
@@ -169,13 +254,16 @@ func (s *state) walk(dot reflect.Value, node parse.Node) {
 // are identical in behavior except that 'with' sets dot.
 func (s *state) walkIfOrWith(typ parse.NodeType, dot reflect.Value, pipe *parse.PipeNode, list, elseList *parse.ListNode) {
 	defer s.pop(s.mark())
-	val := s.evalPipeline(dot, pipe)
-	truth, ok := isTrue(val)
+	val := s.evalLazy(s.evalPipeline(dot, pipe))
+	truth, ok := s.snap.truths.isTrue(val)
 	if !ok {
 		s.errorf("if/with can't use %v", val)
 	}
 	if truth {
 		if typ == parse.NodeWith {
+			if s.snap.recorder != nil {
+				defer s.setDotPath(s.pipePath(pipe))()
+			}
 			s.walk(val, list)
 		} else {
 			s.walk(dot, list)
@@ -187,7 +275,18 @@ func (s *state) walkIfOrWith(typ parse.NodeType, dot reflect.Value, pipe *parse.
 
 // isTrue returns whether the value is 'true', in the sense of not the zero of its type,
 // and whether the value has a meaningful truth value.
+//
+// The nullable types from database/sql, such as sql.NullString, are true when
+// they are valid, that is, not NULL. A pointer to a boolean, number or string
+// is true when its target is. Values that implement Truther define their own
+// truth value.
 func isTrue(val reflect.Value) (truth, ok bool) {
+	if v, null := sqlNullValue(val); null {
+		return v.IsValid(), true
+	}
+	if val.IsValid() && val.Type().Implements(trutherType) && !isNil(val) {
+		return val.Interface().(Truther).IsTrue(), true
+	}
 	if !val.IsValid() {
 		// Something like var x interface{}, never set. It's a form of nil.
 		return false, true
@@ -199,7 +298,16 @@ func isTrue(val reflect.Value) (truth, ok bool) {
 		truth = val.Bool()
 	case reflect.Complex64, reflect.Complex128:
 		truth = val.Complex() != 0
-	case reflect.Chan, reflect.Func, reflect.Ptr, reflect.Interface:
+	case reflect.Ptr:
+		if val.IsNil() {
+			return false, true
+		}
+		elem := val.Elem()
+		if _, null := sqlNullValue(elem); null || isScalar(elem.Kind()) {
+			return isTrue(elem)
+		}
+		truth = true
+	case reflect.Chan, reflect.Func, reflect.Interface:
 		truth = !val.IsNil()
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		truth = val.Int() != 0
@@ -215,10 +323,56 @@ func isTrue(val reflect.Value) (truth, ok bool) {
 	return truth, true
 }
 
+// isScalar returns whether k is the kind of a boolean, number or string.
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// sqlNullValue returns the value held by v if v is one of the nullable types
+// from database/sql, such as sql.NullString or sql.Null[T], and whether it
+// is. The value is invalid if v is not valid, that is, if it holds NULL.
+func sqlNullValue(v reflect.Value) (reflect.Value, bool) {
+	if !v.IsValid() || v.Kind() != reflect.Struct || v.Type().PkgPath() != "database/sql" ||
+		!v.Type().Implements(valuerType) {
+		return v, false
+	}
+	value, err := v.Interface().(driver.Valuer).Value()
+	if err != nil || value == nil {
+		return reflect.Value{}, true
+	}
+	return reflect.ValueOf(value), true
+}
+
+// walkLet walks a 'let' node. The pipeline is evaluated once, computing a
+// lazy result, and the variable it declares is in scope only in the body.
+func (s *state) walkLet(dot reflect.Value, l *parse.LetNode) {
+	defer s.pop(s.mark())
+	s.setVar(1, s.evalLazy(s.evalPipeline(dot, l.Pipe)))
+	s.walk(dot, l.List)
+}
+
 func (s *state) walkRange(dot reflect.Value, r *parse.RangeNode) {
 	s.at(r)
 	defer s.pop(s.mark())
-	val, _ := indirect(s.evalPipeline(dot, r.Pipe))
+	val, _ := indirect(s.evalLazy(s.evalPipeline(dot, r.Pipe)))
+	if s.snap.recorder != nil {
+		// The elements are recorded as path[].
+		path := s.pipePath(r.Pipe) + "[]"
+		if len(r.Pipe.Decl) > 0 {
+			s.vars[len(s.vars)-1].path = path
+		}
+		if len(r.Pipe.Decl) > 1 {
+			s.vars[len(s.vars)-2].path = unknownPath
+		}
+		defer s.setDotPath(path)()
+	}
 	// mark top of stack before any variables in the body are pushed.
 	mark := s.mark()
 	oneIteration := func(index, elem reflect.Value) {
@@ -278,7 +432,7 @@ func (s *state) walkRange(dot reflect.Value, r *parse.RangeNode) {
 
 func (s *state) walkTemplate(dot reflect.Value, t *parse.TemplateNode) {
 	s.at(t)
-	tmpl := s.set.tree[t.Name]
+	tmpl := s.snap.tree[t.Name]
 	if tmpl == nil {
 		s.errorf("template %q not defined", t.Name)
 	}
@@ -286,8 +440,15 @@ func (s *state) walkTemplate(dot reflect.Value, t *parse.TemplateNode) {
 	dot = s.evalPipeline(dot, t.Pipe)
 	newState := *s
 	newState.tmpl = tmpl
+	newState.wr = callWriter(s.wr)
+	if s.snap.debug {
+		newState.callers = append(append([]string(nil), s.callers...), s.tmpl.Name)
+	}
+	if s.snap.recorder != nil {
+		newState.dotPath = s.pipePath(t.Pipe)
+	}
 	// No dynamic scoping: template invocations inherit no variables.
-	newState.vars = []variable{{"$", dot}}
+	newState.vars = []variable{{"$", dot, newState.dotPath}}
 	newState.walk(dot, tmpl.List)
 }
 
@@ -304,7 +465,19 @@ func (s *state) evalPipeline(dot reflect.Value, pipe *parse.PipeNode) (value ref
 		return
 	}
 	s.at(pipe)
+	escaping := false
 	for _, cmd := range pipe.Cmds {
+		if isEscaper(cmd) {
+			if s.dry {
+				// Escaping only builds strings; nothing to check.
+				continue
+			}
+			if !escaping {
+				// The value is about to be printed.
+				value = s.printableValue(pipe, value)
+			}
+			escaping = true
+		}
 		value = s.evalCommand(dot, cmd, value) // previous value is this one's final arg.
 		// If the object has type interface{}, dig down one level to the thing inside.
 		if value.Kind() == reflect.Interface && value.Type().NumMethod() == 0 {
@@ -313,10 +486,22 @@ func (s *state) evalPipeline(dot reflect.Value, pipe *parse.PipeNode) (value ref
 	}
 	for _, variable := range pipe.Decl {
 		s.push(variable.Ident[0], value)
+		if s.snap.recorder != nil {
+			s.vars[len(s.vars)-1].path = s.pipePath(pipe)
+		}
 	}
 	return value
 }
 
+// isEscaper returns whether the command calls one of the functions inserted
+// by contextual escaping.
+func isEscaper(cmd *parse.CommandNode) bool {
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		return escape.FuncMap[id.Ident] != nil
+	}
+	return false
+}
+
 func (s *state) notAFunction(args []parse.Node, final reflect.Value) {
 	if len(args) > 1 || final.IsValid() {
 		s.errorf("can't give argument to non-function %s", args[0])
@@ -385,6 +570,9 @@ func (s *state) idealConstant(constant *parse.NumberNode) reflect.Value {
 
 func (s *state) evalFieldNode(dot reflect.Value, field *parse.FieldNode, args []parse.Node, final reflect.Value) reflect.Value {
 	s.at(field)
+	if s.snap.recorder != nil {
+		s.snap.recorder.field(joinPath(s.dotPath, field.Ident))
+	}
 	return s.evalFieldChain(dot, dot, field, field.Ident, args, final)
 }
 
@@ -395,6 +583,9 @@ func (s *state) evalChainNode(dot reflect.Value, chain *parse.ChainNode, args []
 	if len(chain.Field) == 0 {
 		s.errorf("internal error: no fields in evalChainNode")
 	}
+	if s.snap.recorder != nil {
+		s.snap.recorder.field(joinPath(s.argPath(chain.Node), chain.Field))
+	}
 	return s.evalFieldChain(dot, pipe, chain, chain.Field, args, final)
 }
 
@@ -404,8 +595,16 @@ func (s *state) evalVariableNode(dot reflect.Value, variable *parse.VariableNode
 	value := s.varValue(variable.Ident[0])
 	if len(variable.Ident) == 1 {
 		s.notAFunction(args, final)
+		if s.snap.recorder != nil {
+			if path := s.varPath(variable.Ident[0]); path != "" {
+				s.snap.recorder.field(path)
+			}
+		}
 		return value
 	}
+	if s.snap.recorder != nil {
+		s.snap.recorder.field(joinPath(s.varPath(variable.Ident[0]), variable.Ident[1:]))
+	}
 	return s.evalFieldChain(dot, value, variable, variable.Ident[1:], args, final)
 }
 
@@ -424,10 +623,16 @@ func (s *state) evalFieldChain(dot, receiver reflect.Value, node parse.Node, ide
 func (s *state) evalFunction(dot reflect.Value, node *parse.IdentifierNode, cmd parse.Node, args []parse.Node, final reflect.Value) reflect.Value {
 	s.at(node)
 	name := node.Ident
-	function, ok := findFunction(name, s.set)
+	function, ok := findFunction(name, s.snap.funcs, s.snap.truths)
 	if !ok {
 		s.errorf("%q is not a defined function", name)
 	}
+	if s.snap.recorder != nil {
+		s.snap.recorder.funcCall(name)
+	}
+	if s.snap.debug && len(args) == 1 && !final.IsValid() && function.Pointer() == debugFunc.Pointer() {
+		return reflect.ValueOf(s.debug(dot))
+	}
 	return s.evalCall(dot, function, cmd, name, args, final)
 }
 
@@ -435,6 +640,7 @@ func (s *state) evalFunction(dot reflect.Value, node *parse.IdentifierNode, cmd
 // The 'final' argument represents the return value from the preceding
 // value of the pipeline, if any.
 func (s *state) evalField(dot reflect.Value, fieldName string, node parse.Node, args []parse.Node, final, receiver reflect.Value) reflect.Value {
+	receiver = s.evalLazy(receiver)
 	if !receiver.IsValid() {
 		return zero
 	}
@@ -485,8 +691,11 @@ func (s *state) evalField(dot reflect.Value, fieldName string, node parse.Node,
 }
 
 var (
-	errorType       = reflect.TypeOf((*error)(nil)).Elem()
-	fmtStringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	fmtStringerType   = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	htmlerType        = reflect.TypeOf((*HTMLer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	valuerType        = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
 )
 
 // evalCall executes a function or method call. If it's a method, fun already has the receiver bound, so
@@ -536,6 +745,9 @@ func (s *state) evalCall(dot, fun reflect.Value, node parse.Node, name string, a
 		}
 		argv[i] = s.validateType(final, t)
 	}
+	if s.snap.audit != nil && len(argv) > 0 {
+		s.auditCall(node, name, argv[len(argv)-1])
+	}
 	result := fun.Call(argv)
 	// If we have an error that is not nil, stop execution and return that error to the caller.
 	if len(result) == 2 && !result[1].IsNil() {
@@ -564,6 +776,13 @@ func (s *state) validateType(value reflect.Value, typ reflect.Type) reflect.Valu
 		s.errorf("invalid value; expected %s", typ)
 	}
 	if typ != nil && !value.Type().AssignableTo(typ) {
+		value = s.evalLazy(value)
+		if !value.IsValid() {
+			return s.validateType(value, typ)
+		}
+		if value.Type().AssignableTo(typ) {
+			return value
+		}
 		if value.Kind() == reflect.Interface && !value.IsNil() {
 			value = value.Elem()
 			if value.Type().AssignableTo(typ) {
@@ -733,10 +952,64 @@ func indirect(v reflect.Value) (rv reflect.Value, isNil bool) {
 	return v, false
 }
 
+// HTMLer is implemented by values that render themselves as HTML. When
+// printed, the result of the HTML method is used instead of the value, and
+// contextual escaping treats it as known safe content.
+type HTMLer interface {
+	HTML() escape.HTML
+}
+
+// isPrinter returns whether the type controls how it is printed.
+func isPrinter(typ reflect.Type) bool {
+	return typ.Implements(htmlerType) || typ.Implements(errorType) ||
+		typ.Implements(fmtStringerType) || typ.Implements(textMarshalerType)
+}
+
+// printableValue returns the value to print in place of v. It computes
+// lazy values, unwraps the nullable types from database/sql, applies the
+// nil policy, and replaces values that implement HTMLer or, unless they
+// implement error or fmt.Stringer which fmt honors, encoding.TextMarshaler
+// by their rendering.
+func (s *state) printableValue(n parse.Node, v reflect.Value) reflect.Value {
+	v = s.evalLazy(v)
+	w, _ := indirect(v)
+	if w.Kind() == reflect.Interface {
+		w = w.Elem()
+	}
+	if value, ok := sqlNullValue(w); ok {
+		v, w = value, value
+	}
+	if isNil(w) {
+		if s.snap.nilPolicy != NilPrint {
+			return s.printNil(n)
+		}
+		return v
+	}
+	v = w
+	if v.CanAddr() && !isPrinter(v.Type()) && isPrinter(reflect.PtrTo(v.Type())) {
+		v = v.Addr()
+	}
+	switch typ := v.Type(); {
+	case typ.Implements(htmlerType):
+		return reflect.ValueOf(v.Interface().(HTMLer).HTML())
+	case typ.Implements(errorType), typ.Implements(fmtStringerType):
+		return v
+	case typ.Implements(textMarshalerType):
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			s.at(n)
+			s.errorf("error marshaling %s: %s", typ, err)
+		}
+		return reflect.ValueOf(string(text))
+	}
+	return v
+}
+
 // printValue writes the textual representation of the value to the output of
 // the template.
 func (s *state) printValue(n parse.Node, v reflect.Value) {
 	s.at(n)
+	v = s.printableValue(n, v)
 	if v.Kind() == reflect.Ptr {
 		v, _ = indirect(v) // fmt.Fprint handles nil.
 	}
@@ -755,9 +1028,46 @@ func (s *state) printValue(n parse.Node, v reflect.Value) {
 			}
 		}
 	}
+	if s.dry {
+		return
+	}
 	fmt.Fprint(s.wr, v.Interface())
 }
 
+// NilPolicy defines how nil values are printed. See Set.PrintNil.
+type NilPolicy int
+
+const (
+	// NilPrint prints nil values as the fmt package does, as "<nil>", or
+	// as "<no value>" for missing values. This is the default.
+	NilPrint NilPolicy = iota
+	// NilEmpty prints nil values as an empty string.
+	NilEmpty
+	// NilError stops the execution with an error.
+	NilError
+)
+
+// isNil reports whether v is a missing value or a nil pointer or interface.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// printNil applies the nil policy to a nil value about to be printed by
+// node n, and returns the value to print instead.
+func (s *state) printNil(n parse.Node) reflect.Value {
+	if s.snap.nilPolicy == NilError {
+		s.at(n)
+		s.errorf("nil value printed")
+	}
+	return reflect.ValueOf("")
+}
+
 // Types to help sort the keys in a map for reproducible output.
 
 type rvs []reflect.Value
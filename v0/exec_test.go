@@ -6,11 +6,14 @@ package template
 
 import (
 	"bytes"
+	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -335,6 +338,7 @@ var execTests = []execTest{
 
 	// Pipelines.
 	{"pipeline", "-{{.Method0 | .Method2 .U16}}-", "-Method2: 16 M0-", tVal, true},
+	{"pipeline filter arguments", "{{.X | printf:\"%v-%v\":.I | strings.upper}}", "17-X", tVal, true},
 	{"pipeline func", "-{{call .VariadicFunc `llo` | call .VariadicFunc `he` }}-", "-<he+<llo>>-", tVal, true},
 
 	// Parenthesized expressions
@@ -401,6 +405,24 @@ var execTests = []execTest{
 	{"or", "{{or 0 0}} {{or 1 0}} {{or 0 true}} {{or 1 1}}", "0 1 true 1", nil, true},
 	{"boolean if", "{{if and true 1 `hi`}}TRUE{{else}}FALSE{{end}}", "TRUE", tVal, true},
 	{"boolean if not", "{{if and true 1 `hi` | not}}TRUE{{else}}FALSE{{end}}", "FALSE", nil, true},
+	{"classes", "{{html.classes `btn` (maps.dict `active` .True `hidden` false) `` `big  wide`}}", "btn active big wide", tVal, true},
+	{"classes string condition", "{{html.classes `btn` (maps.dict `active` `` `on` `yes`) `wide`}}", "btn on wide", tVal, true},
+	{"classes string after name", "{{html.classes `btn` `active`}}", "btn active", tVal, true},
+	{"classes map order", "{{html.classes (maps.dict `b` 1 `a` 1) .MSIone}}", "a b one", tVal, true},
+	{"classes bad name", "{{html.classes 1}}", "", tVal, false},
+
+	// HTML.
+	{"truncateHTML", "{{`one two three` | html.truncate 6}}", "one\u2026", tVal, true},
+	{"plaintext", "{{html.plaintext `<p>a</p><p>b &amp; c</p>`}}", "a\n\nb & c", tVal, true},
+
+	// URLs.
+	{"buildURL", "{{url.build `/search?x=1` (maps.dict `q` `a b&c` `page` 2 `tag` .SI `none` .Empty0)}}", "/search?x=1&page=2&q=a+b%26c&tag=3&tag=4&tag=5", tVal, true},
+	{"buildURL base query", "{{url.build `/s?b=1&a=%2f&a` (maps.dict `q` .X)}}", "/s?b=1&a=%2f&a&q=x", tVal, true},
+	{"buildURL empty query", "{{url.build `/s?` (maps.dict `q` .X)}} {{url.build `/s?b=2` (maps.dict)}}", "/s?q=x /s?b=2", tVal, true},
+	{"buildURL absolute", "{{url.build `https://example.com/` (maps.dict `q` .X)}}", "https://example.com/?q=x", tVal, true},
+	{"buildURL unsafe scheme", "{{url.build `javascript:alert(1)` (maps.dict)}}", "", tVal, false},
+	{"dict odd arguments", "{{maps.dict `q`}}", "", tVal, false},
+	{"dict non-string key", "{{maps.dict 1 2}}", "", tVal, false},
 
 	// Indexing.
 	{"slice[0]", "{{index .SI 0}}", "3", tVal, true},
@@ -438,6 +460,13 @@ var execTests = []execTest{
 	{"with empty interface, struct field", "{{with .Empty4}}{{.V}}{{end}}", "UinEmpty", tVal, true},
 	{"with $x int", "{{with $x := .I}}{{$x}}{{end}}", "17", tVal, true},
 	{"with $x struct.U.V", "{{with $x := $}}{{$x.U.V}}{{end}}", "v", tVal, true},
+
+	// Let.
+	{"let", "{{let $x := .I}}{{$x}}{{$x}}{{end}}", "1717", tVal, true},
+	{"let empty", "{{let $x := .Empty0}}{{$x}}{{end}}", "<no value>", tVal, true},
+	{"let keeps dot", "{{let $x := .U}}{{.X}}{{$x.V}}{{end}}", "xv", tVal, true},
+	{"let nested", "{{let $x := .X}}{{let $y := .I}}{{$x}}{{$y}}{{end}}{{$x}}{{end}}", "x17x", tVal, true},
+	{"let shadows", "{{$x := 1}}{{let $x := 2}}{{$x}}{{end}}{{$x}}", "21", tVal, true},
 	{"with variable and action", "{{with $x := $}}{{$y := $.U.V}}{{$y}}{{end}}", "v", tVal, true},
 
 	// Range.
@@ -623,12 +652,67 @@ func TestExecute(t *testing.T) {
 	testExecute(execTests, nil, t)
 }
 
+var namespaceTests = []execTest{
+	{"strings.upper", "{{strings.upper .X}}", "X", tVal, true},
+	{"strings in pipeline", `{{.X | strings.repeat "-" | printf "%s"}}`, "", tVal, false},
+	{"strings.replace", `{{strings.replace "a-b-c" "-" "+"}}`, "a+b+c", nil, true},
+	{"strings.truncate", `{{strings.truncate 3 "gopher"}} {{strings.truncate 6 "gopher"}} {{strings.truncate 1 "été"}}`, "gop… gopher é…", nil, true},
+	{"math.add", "{{math.add 1 2}}", "3", nil, true},
+	{"html.attr", "{{html.attr `class` `a`}}", `class="a"`, nil, true},
+	{"yaml.scalar", "{{yaml.scalar `yes`}}", `"yes"`, nil, true},
+	{"shell.quote", "{{shell.quote `a b`}}", "'a b'", nil, true},
+	{"latex.escape", "{{latex.escape `50%`}}", `50\%`, nil, true},
+}
+
+func TestNamespaces(t *testing.T) {
+	testExecute(namespaceTests, nil, t)
+}
+
+func TestFlatBuiltins(t *testing.T) {
+	if _, err := new(Set).Parse(`{{define "t"}}{{upper .}}{{end}}`); err == nil {
+		t.Errorf("expected error using flat alias without FlatBuiltins")
+	}
+	set := Must(new(Set).Funcs(FuncMap{"lower": strings.ToUpper}).FlatBuiltins().Parse(
+		`{{define "t"}}{{upper .}} {{strings.upper .}} {{lower .}} {{strings.lower .}}{{end}}`))
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "t", "Ab"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "AB AB AB ab"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	// Short names resolve to plain string functions, which can be piped.
+	set = Must(new(Set).FlatBuiltins().Parse(`{{define "t"}}{{.Name | truncate:3 | upper}}{{end}}`))
+	b.Reset()
+	if err := set.Execute(b, "t", map[string]string{"Name": "gopher"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "GOP\u2026"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	// The short names must be unique, or one of the functions would be
+	// hidden, unless the one from "strings" is meant to win.
+	short := make(map[string]string)
+	for name := range builtins {
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			if other, ok := short[name[i+1:]]; ok && !strings.HasPrefix(name, "strings.") && !strings.HasPrefix(other, "strings.") {
+				t.Errorf("%s and %s have the same short name", name, other)
+			}
+			short[name[i+1:]] = name
+		}
+	}
+}
+
 var delimPairs = []string{
 	"", "", // default
 	"{{", "}}", // same as default
 	"<<", ">>", // distinct
 	"|", "|", // same
 	"(日)", "(本)", // peculiar
+	"<%", "%>", // ERB style
+	"[[[", "]]", // asymmetric lengths
+	"«", "»", // multi-byte runes
+	"⟦⟦%", "%⟧", // long multi-byte
 }
 
 func TestDelims(t *testing.T) {
@@ -789,3 +873,181 @@ func TestTree(t *testing.T) {
 		t.Errorf("expected %q got %q", expect, result)
 	}
 }
+
+func TestDryRun(t *testing.T) {
+	tmpl := Must(new(Set).Escape().Parse(`
+	{{define "ok"}}<a href="{{.X}}">{{.U.V}}</a>{{range .SI}}{{.}}{{end}}{{end}}
+	{{define "missing"}}<p>{{.Missing}}</p>{{end}}
+	{{define "method"}}{{.MyError true}}{{end}}`))
+	if err := tmpl.DryRun("ok", tVal); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := tmpl.DryRun("missing", tVal); err == nil {
+		t.Errorf("expected error for missing field")
+	}
+	if err := tmpl.DryRun("method", tVal); err == nil {
+		t.Errorf("expected error from method")
+	}
+	if err := tmpl.DryRun("undefined", tVal); err == nil {
+		t.Errorf("expected error for undefined template")
+	}
+}
+
+func TestPrintNil(t *testing.T) {
+	const text = `{{define "t"}}[{{.NIL}}][{{.Empty0}}][{{.MSI.missing}}][{{.I}}]{{end}}`
+	tests := []struct {
+		policy NilPolicy
+		escape bool
+		output string
+		ok     bool
+	}{
+		{NilPrint, false, "[<nil>][<no value>][<no value>][17]", true},
+		{NilEmpty, false, "[][][][17]", true},
+		{NilEmpty, true, "[][][][17]", true},
+		{NilError, false, "[", false},
+		{NilError, true, "[", false},
+	}
+	for _, test := range tests {
+		set := Must(new(Set).PrintNil(test.policy).Parse(text))
+		if test.escape {
+			set.Escape()
+		}
+		b := new(bytes.Buffer)
+		err := set.Execute(b, "t", tVal)
+		if test.ok && err != nil {
+			t.Errorf("policy %d: unexpected error: %s", test.policy, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("policy %d: expected error", test.policy)
+		}
+		if got := b.String(); got != test.output {
+			t.Errorf("policy %d: expected %q, got %q", test.policy, test.output, got)
+		}
+	}
+}
+
+func TestReplaceFunc(t *testing.T) {
+	set := Must(new(Set).Funcs(FuncMap{
+		"date": func(s string) string { return "old " + s },
+	}).Parse(`{{define "a"}}{{date .}}{{end}}`)).Escape()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := new(bytes.Buffer)
+			if err := set.Execute(b, "a", "x"); err != nil {
+				t.Error(err)
+			} else if s := b.String(); s != "old x" && s != "new x" {
+				t.Errorf("unexpected output %q", s)
+			}
+		}()
+	}
+	if err := set.ReplaceFunc("date", func(s string) string { return "new " + s }); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "a", "x"); err != nil || b.String() != "new x" {
+		t.Errorf("expected %q; got %q, %v", "new x", b.String(), err)
+	}
+	if err := set.ReplaceFunc("date", func(i int) string { return "" }); err == nil {
+		t.Errorf("expected error replacing a function by one of a different type")
+	}
+	if err := set.ReplaceFunc("missing", func() string { return "" }); err == nil {
+		t.Errorf("expected error replacing a missing function")
+	}
+	if err := set.ReplaceFunc("html_template_htmlescaper", func(args ...interface{}) string { return "" }); err == nil {
+		t.Errorf("expected error replacing an escaping function")
+	}
+}
+
+func TestNullableValues(t *testing.T) {
+	data := map[string]interface{}{
+		"Name":     sql.NullString{String: "gopher", Valid: true},
+		"NoName":   sql.NullString{},
+		"Empty":    sql.NullString{Valid: true},
+		"Count":    sql.NullInt64{Int64: 7, Valid: true},
+		"PCount":   &sql.NullInt64{Int64: 7, Valid: true},
+		"PNoCount": &sql.NullInt64{},
+		"PZero":    newInt(0),
+		"POne":     newInt(1),
+		"PFalse":   new(bool),
+	}
+	tests := []struct {
+		input, output string
+	}{
+		{`{{.Name}}`, `gopher`},
+		{`{{.NoName}}`, `<no value>`},
+		{`{{.Count}}`, `7`},
+		{`{{.PCount}}`, `7`},
+		{`{{if .Name}}y{{else}}n{{end}}`, `y`},
+		{`{{if .NoName}}y{{else}}n{{end}}`, `n`},
+		{`{{if .Empty}}y{{else}}n{{end}}`, `y`},
+		{`{{if .PCount}}y{{else}}n{{end}}`, `y`},
+		{`{{if .PNoCount}}y{{else}}n{{end}}`, `n`},
+		{`{{if .PZero}}y{{else}}n{{end}}`, `n`},
+		{`{{if .POne}}y{{else}}n{{end}}`, `y`},
+		{`{{if .PFalse}}y{{else}}n{{end}}`, `n`},
+		{`{{with .Name}}{{.}}{{end}}`, `gopher`},
+		{`{{if and .Name .NoName}}y{{else}}n{{end}}`, `n`},
+	}
+	for _, test := range tests {
+		set, err := new(Set).Parse(`{{define "t"}}` + test.input + `{{end}}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := set.Execute(&b, "t", data); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.input, err)
+		} else if b.String() != test.output {
+			t.Errorf("%s: expected %q, got %q", test.input, test.output, b.String())
+		}
+	}
+}
+
+func TestReservedFuncs(t *testing.T) {
+	for _, name := range []string{"html", "js", "urlquery", "html_template_htmlescaper", "html_template_new"} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if err == nil || !strings.Contains(err.Error(), "reserved") {
+					t.Errorf("%s: expected reserved name error; got %v", name, err)
+				}
+			}()
+			new(Set).Funcs(FuncMap{name: func(s string) string { return s }})
+		}()
+	}
+	// Other builtins can be overridden.
+	set := Must(new(Set).Funcs(FuncMap{"len": func(s string) int { return 42 }}).Parse(`{{define "a"}}{{len "x"}}{{end}}`))
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "a", nil); err != nil || b.String() != "42" {
+		t.Errorf("expected %q; got %q, %v", "42", b.String(), err)
+	}
+}
+
+func TestTemplateNotFound(t *testing.T) {
+	set := Must(new(Set).Parse(`{{define "header"}}h{{end}}{{define "headers"}}hs{{end}}{{define "footer"}}<p>{{.}}</p>{{end}}`)).Escape()
+	tests := []struct {
+		name       string
+		candidates []string
+		msg        string
+	}{
+		{"heder", []string{"header", "headers"}, `template: no template "heder" in the set; did you mean "header" or "headers"?`},
+		{"Footer", []string{"footer"}, `template: no template "Footer" in the set; did you mean "footer"?`},
+		{"sidebar", nil, `template: no template "sidebar" in the set`},
+	}
+	for _, test := range tests {
+		err := set.Execute(ioutil.Discard, test.name, nil)
+		e, ok := err.(*ErrTemplateNotFound)
+		if !ok {
+			t.Errorf("%s: expected ErrTemplateNotFound; got %v", test.name, err)
+			continue
+		}
+		if e.Name != test.name || !reflect.DeepEqual(e.Candidates, test.candidates) {
+			t.Errorf("%s: expected candidates %q; got %q", test.name, test.candidates, e.Candidates)
+		}
+		if e.Error() != test.msg {
+			t.Errorf("%s: expected message\n\t%s\ngot\n\t%s", test.name, test.msg, e.Error())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"github.com/gorilla/template/v0/parse"
+)
+
+// The {{variant}} action renders one of several versions of a part of a
+// template, according to the assignment of an A/B experiment:
+//
+//	{{variant "signup-button"}}
+//		{{case "green"}}<button class="green">Sign up</button>{{end}}
+//		{{case "large"}}<button class="large">Sign up now</button>{{end}}
+//	{{else}}
+//		<button>Sign up</button>
+//	{{end}}
+//
+// The variant is chosen by the ExperimentChooser of the set, given the
+// name of the experiment and the data of the execution ($). If it matches
+// no case, or if the set has no chooser, the optional else branch is
+// rendered.
+
+// ExperimentChooser assigns the variants of experiments to executions. It
+// must be safe to call concurrently.
+type ExperimentChooser interface {
+	// Variant returns the variant of the experiment assigned to the
+	// execution with the given data, or "" for none.
+	Variant(experiment string, data interface{}) string
+}
+
+// ExperimentChooserFunc is an adapter to use a function as an
+// ExperimentChooser.
+type ExperimentChooserFunc func(experiment string, data interface{}) string
+
+// Variant returns f(experiment, data).
+func (f ExperimentChooserFunc) Variant(experiment string, data interface{}) string {
+	return f(experiment, data)
+}
+
+// ExposureFunc is called each time a {{variant}} action renders one of its
+// cases, with the name of the experiment, the variant and the data of the
+// execution. It is meant to log exposures for the analysis of experiments.
+// It must be safe to call concurrently.
+type ExposureFunc func(experiment, variant string, data interface{})
+
+// Experiments sets the chooser consulted by {{variant}} actions, and the
+// function called when they render a case, which can be nil. The return
+// value is the set, so calls can be chained.
+func (s *Set) Experiments(chooser ExperimentChooser, exposed ExposureFunc) *Set {
+	return s.addFuncs(FuncMap{
+		parse.VariantFunc: func(experiment string, data interface{}, cases ...string) string {
+			variant := chooser.Variant(experiment, data)
+			for _, c := range cases {
+				if c == variant {
+					if exposed != nil {
+						exposed(experiment, variant, data)
+					}
+					return variant
+				}
+			}
+			return ""
+		},
+	})
+}
+
+// noVariant is the function called by {{variant}} actions when the set
+// has no chooser: the else branch is rendered.
+func noVariant(experiment string, data interface{}, cases ...string) string {
+	return ""
+}
+
+// variantEq reports whether the variant chosen by a {{variant}} action is
+// the named case.
+func variantEq(variant, name string) bool {
+	return variant == name
+}
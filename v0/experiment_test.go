@@ -0,0 +1,94 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVariant(t *testing.T) {
+	const text = `{{define "t"}}[{{variant "button"}}
+	{{case "green"}}G{{.}}{{end}}
+	{{case "large"}}L{{.}}{{end}}
+{{else}}C{{.}}{{end}}]{{end}}`
+	var exposures []string
+	chooser := ExperimentChooserFunc(func(experiment string, data interface{}) string {
+		return map[string]string{"ann": "green", "bob": "large", "cat": "blue"}[data.(string)]
+	})
+	s := Must(new(Set).Experiments(chooser, func(experiment, variant string, data interface{}) {
+		exposures = append(exposures, fmt.Sprintf("%s=%s %v", experiment, variant, data))
+	}).Parse(text))
+	tests := []struct {
+		data, output string
+	}{
+		{"ann", "[Gann]"},
+		{"bob", "[Lbob]"},
+		{"cat", "[Ccat]"},
+		{"dan", "[Cdan]"},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := s.Execute(&b, "t", test.data); err != nil {
+			t.Errorf("%s: %s", test.data, err)
+			continue
+		}
+		if b.String() != test.output {
+			t.Errorf("%s: got %q, want %q", test.data, b.String(), test.output)
+		}
+	}
+	if want := []string{"button=green ann", "button=large bob"}; !reflect.DeepEqual(exposures, want) {
+		t.Errorf("got exposures %q, want %q", exposures, want)
+	}
+	// Without a chooser, the else branch is rendered.
+	var b bytes.Buffer
+	if err := Must(new(Set).Parse(text)).Execute(&b, "t", "ann"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[Cann]" {
+		t.Errorf("got %q, want %q", b.String(), "[Cann]")
+	}
+	// The cases are escaped.
+	b.Reset()
+	s = Must(new(Set).Escape().Experiments(chooser, nil).Parse(text))
+	if err := s.Execute(&b, "t", "ann"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[Gann]" {
+		t.Errorf("got %q, want %q", b.String(), "[Gann]")
+	}
+	// The cases don't depend on a user function named eq.
+	b.Reset()
+	s = Must(new(Set).Funcs(FuncMap{
+		"eq": func(a, b interface{}) bool { return false },
+	}).Experiments(chooser, nil).Parse(text))
+	if err := s.Execute(&b, "t", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[Lbob]" {
+		t.Errorf("got %q, want %q", b.String(), "[Lbob]")
+	}
+}
+
+func TestVariantErrors(t *testing.T) {
+	tests := []struct {
+		input, err string
+	}{
+		{`{{variant "x"}}{{end}}`, `variant "x" has no cases`},
+		{`{{variant "x"}}{{case "a"}}{{end}}{{case "a"}}{{end}}{{end}}`, `duplicate case "a"`},
+		{`{{variant "x"}}a{{case "a"}}{{end}}{{end}}`, `unexpected text in variant`},
+		{`{{variant "x"}}{{if 1}}{{end}}{{end}}`, `unexpected <if> in variant`},
+		{`{{case "a"}}{{end}}`, `unexpected <case>`},
+	}
+	for _, test := range tests {
+		_, err := new(Set).Parse(`{{define "t"}}` + test.input + `{{end}}`)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.input, err, test.err)
+		}
+	}
+}
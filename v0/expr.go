@@ -0,0 +1,71 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io/ioutil"
+	"reflect"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Expr is an expression parsed by ParseExpr: the pipeline of an action,
+// without a template around it. It can be evaluated concurrently.
+type Expr struct {
+	define *parse.DefineNode
+	snap   *Snapshot
+}
+
+// ParseExpr parses text as the pipeline of an action, without the
+// delimiters, for programs that want template expressions without
+// templates, such as configuration systems:
+//
+//	e, err := template.ParseExpr(`.Env.REGION | strings.upper`, nil)
+//	...
+//	region, err := e.Eval(data)
+//
+// The expression can call the builtins and the functions in funcs, which
+// are added as by Set.Funcs, so ParseExpr panics if a value in funcs is not
+// a suitable function.
+func ParseExpr(text string, funcs FuncMap) (*Expr, error) {
+	s := new(Set).Funcs(funcs)
+	define, err := parse.ParseExpr("expression", text, builtins, s.parseFuncs)
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{define: define, snap: s.current()}, nil
+}
+
+// Eval evaluates the expression with data as dot and $, and returns the
+// result. Missing values, such as missing map keys, give nil.
+func (e *Expr) Eval(data interface{}) (result interface{}, err error) {
+	defer errRecover(&err)
+	value := reflect.ValueOf(data)
+	state := &state{
+		snap: e.snap,
+		tmpl: e.define,
+		wr:   ioutil.Discard,
+		vars: []variable{{"$", value, ""}},
+	}
+	v := state.evalPipeline(value, e.define.List.Nodes[0].(*parse.ActionNode).Pipe)
+	if !v.IsValid() {
+		return nil, nil
+	}
+	return v.Interface(), nil
+}
+
+// String returns the text of the expression.
+func (e *Expr) String() string {
+	return e.define.List.Nodes[0].(*parse.ActionNode).Pipe.String()
+}
+
+// Eval parses and evaluates an expression, as ParseExpr and Expr.Eval do.
+func Eval(text string, data interface{}, funcs FuncMap) (interface{}, error) {
+	e, err := ParseExpr(text, funcs)
+	if err != nil {
+		return nil, err
+	}
+	return e.Eval(data)
+}
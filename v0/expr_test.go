@@ -0,0 +1,62 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	data := map[string]interface{}{
+		"Env":   map[string]string{"REGION": "eu-west"},
+		"Count": 3,
+		"Tags":  []string{"a", "b"},
+	}
+	funcs := FuncMap{"double": func(n int) int { return 2 * n }}
+	tests := []struct {
+		expr   string
+		result interface{}
+	}{
+		{`.Env.REGION | strings.upper`, "EU-WEST"},
+		{`double .Count`, 6},
+		{`and (gt .Count 2) (len .Tags)`, 2},
+		{`eq $.Count 3`, true},
+		{`index .Tags 1`, "b"},
+		{`"a}}b"`, "a}}b"},
+		{`.Missing`, nil},
+	}
+	for _, test := range tests {
+		result, err := Eval(test.expr, data, funcs)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.expr, err)
+		} else if !reflect.DeepEqual(result, test.result) {
+			t.Errorf("%s: got %#v, want %#v", test.expr, result, test.result)
+		}
+	}
+	for _, expr := range []string{
+		``,
+		`.X}}{{.Y`,
+		`if .X`,
+		`$x := 1`,
+		`undefined .X`,
+	} {
+		if _, err := ParseExpr(expr, funcs); err == nil {
+			t.Errorf("%q: expected parse error", expr)
+		}
+	}
+	e, err := ParseExpr(`double .Count`, funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.String() != "double .Count" {
+		t.Errorf("got %q", e.String())
+	}
+	_, err = e.Eval(map[string]interface{}{"Count": "x"})
+	if err == nil || !strings.Contains(err.Error(), "expression:") {
+		t.Errorf("got error %v, want an error located in the expression", err)
+	}
+}
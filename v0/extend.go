@@ -0,0 +1,74 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Extend adds templates to the set by calling fn with a set to parse them
+// into, for example to load the templates of plugins in a long-running
+// service:
+//
+//	err := set.Extend(func(s *template.Set) error {
+//		_, err := s.ParseGlob("plugins/*.html")
+//		return err
+//	})
+//
+// Before the set is compiled, fn is called with the set itself. After, when
+// templates can't be added to it anymore, fn is called with a new set with
+// the same options, where the parse calls that built the set are replayed
+// first, reading its files again. The new set is compiled, and its
+// templates replace the ones of the set, as Swap does: executions in
+// progress finish with the previous templates. If fn or the compilation
+// fails, the set is unchanged.
+func (s *Set) Extend(fn func(*Set) error) error {
+	s.rebuilding.Lock()
+	defer s.rebuilding.Unlock()
+	s.mutex.Lock()
+	compiled := s.compiled || s.compileErr != nil
+	s.mutex.Unlock()
+	if !compiled {
+		return fn(s)
+	}
+	return s.rebuild(fn)
+}
+
+// rebuild replays the parse calls of the set into a new set with the same
+// options, calls fn with it if not nil, compiles it and replaces the
+// templates of the set by its ones. The caller must hold rebuilding.
+func (s *Set) rebuild(fn func(*Set) error) error {
+	ns, err := s.Clone()
+	if err != nil {
+		return err
+	}
+	// Keep the options, start over with the templates.
+	ns.tree = make(parse.Tree)
+	ns.parsed = parseUsage{}
+	ns.imported, ns.read, ns.globs = nil, nil, nil
+	ns.provenance, ns.consts = nil, nil
+	ns.compiled, ns.compileErr = false, nil
+	for _, source := range ns.sources {
+		if _, err = source(ns); err != nil {
+			return err
+		}
+	}
+	if fn != nil {
+		if err = fn(ns); err != nil {
+			return err
+		}
+	}
+	snap, err := ns.Snapshot()
+	if err != nil {
+		return err
+	}
+	s.Swap(snap)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sources = ns.sources
+	s.imported, s.read, s.globs = ns.imported, ns.read, ns.globs
+	s.provenance = ns.provenance
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExtend(t *testing.T) {
+	set := Must(new(Set).Escape().Parse(`{{define "page"}}<p>{{template "plugin" .}}</p>{{end}}`))
+	parsePlugin := func(text string) func(*Set) error {
+		return func(s *Set) error {
+			_, err := s.ParseTemplate("plugin", text)
+			return err
+		}
+	}
+	// Before compilation, templates are added to the set.
+	if err := set.Extend(parsePlugin(`v1 {{.}}`)); err != nil {
+		t.Fatal(err)
+	}
+	check := func(want string) {
+		var b bytes.Buffer
+		if err := set.Execute(&b, "page", "<x>"); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		} else if b.String() != want {
+			t.Errorf("got %q, want %q", b.String(), want)
+		}
+	}
+	check("<p>v1 &lt;x&gt;</p>")
+	if _, err := set.Parse(`{{define "other"}}{{end}}`); err == nil {
+		t.Errorf("expected error parsing after execution")
+	}
+	// After, the set is rebuilt, still escaped.
+	if err := set.Extend(func(s *Set) error {
+		_, err := s.Parse(`{{define "extra"}}[{{template "page" .}}]{{end}}`)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := set.Execute(&b, "extra", "<y>"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[<p>v1 &lt;y&gt;</p>]"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+	// The set is unchanged if the new templates fail.
+	if err := set.Extend(parsePlugin(`{{.}`)); err == nil {
+		t.Errorf("expected parse error")
+	}
+	if err := set.Extend(parsePlugin(`v2`)); err == nil {
+		t.Errorf("expected error for a duplicate template")
+	}
+	check("<p>v1 &lt;x&gt;</p>")
+}
@@ -0,0 +1,235 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo
+// +build !tinygo
+
+package template
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The file system is only used by the functions in this file, which are not
+// built with TinyGo, and by {{import}} and {{include}}.
+
+// readFile returns the contents of the named file.
+func readFile(filename string) ([]byte, error) {
+	return ioutil.ReadFile(filename)
+}
+
+// readTemplateFile returns the contents of the named template file,
+// reading no more than the maximum template size of the set.
+func (s *Set) readTemplateFile(filename string) ([]byte, error) {
+	max := s.limits.MaxTemplateSize
+	if max <= 0 {
+		return readFile(filename)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return s.readTemplate(filename, f)
+}
+
+// readTemplate returns the contents of the named template file from r,
+// reading no more than the maximum template size of the set.
+func (s *Set) readTemplate(filename string, r io.Reader) ([]byte, error) {
+	max := s.limits.MaxTemplateSize
+	if max <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r, int64(max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSize(filename, len(b), max); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ParseFiles parses the named files and adds the resulting templates to the
+// set. There must be at least one file. Unless AutoDefine is enabled, each
+// file must define at least one template or constant: an empty or
+// comment-only file is an error. If an error occurs, parsing stops and the
+// returned set is nil; otherwise it is s.
+func (s *Set) ParseFiles(filenames ...string) (*Set, error) {
+	return s.record(func(s *Set) (*Set, error) {
+		return s.parseFiles(filenames)
+	})
+}
+
+// parseFiles implements ParseFiles.
+func (s *Set) parseFiles(filenames []string) (*Set, error) {
+	if len(filenames) == 0 {
+		// Not really a problem, but be consistent.
+		return nil, fmt.Errorf(
+			"template: ParseFiles must be called with at least one filename")
+	}
+	for _, filename := range filenames {
+		s.mutex.Lock()
+		s.addRead(filename)
+		s.mutex.Unlock()
+		if b, err := s.readTemplateFile(filename); err != nil {
+			return nil, err
+		} else if _, err = s.parseFile(string(b), filename); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ParseGlob parses the template definitions in the files identified by the
+// pattern and adds the resulting templates to the set. The pattern is
+// processed by filepath.Glob and must match at least one file. ParseGlob is
+// like calling s.ParseFiles with the list of files matched by the pattern,
+// except that it parses all the files even if some fail: the error is then
+// a ParseErrors with a FileError for each failing file, and the returned
+// set is nil; otherwise it is s.
+func (s *Set) ParseGlob(pattern string) (*Set, error) {
+	return s.record(func(s *Set) (*Set, error) {
+		return s.parseGlob(pattern)
+	})
+}
+
+// parseGlob implements ParseGlob.
+func (s *Set) parseGlob(pattern string) (*Set, error) {
+	s.mutex.Lock()
+	s.globs = append(s.globs, pattern)
+	s.mutex.Unlock()
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	filenames = s.files.filter(pattern, filenames)
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf(
+			"template: pattern doesn't match any files: %#q", pattern)
+	}
+	var errs ParseErrors
+	for _, filename := range filenames {
+		if _, err := s.parseFiles([]string{filename}); err != nil {
+			errs = append(errs, newFileError(filename, err))
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return s, nil
+}
+
+// filter returns the files matched by the pattern that the policy allows.
+func (p FilePolicy) filter(pattern string, filenames []string) []string {
+	var allowed []string
+	for _, filename := range filenames {
+		if p.allows(pattern, filename) {
+			allowed = append(allowed, filename)
+		}
+	}
+	return allowed
+}
+
+// allows returns whether the policy allows the file matched by the pattern.
+// Directories are never allowed.
+func (p FilePolicy) allows(pattern, filename string) bool {
+	if !p.allowsName(filepath.Clean(pattern), filepath.Clean(filename), string(filepath.Separator)) {
+		return false
+	}
+	fi, err := os.Lstat(filename)
+	if err != nil {
+		// Reported when the file is read.
+		return true
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if !p.FollowSymlinks {
+			return false
+		}
+		if fi, err = os.Stat(filename); err != nil {
+			return true
+		}
+	}
+	return !fi.IsDir()
+}
+
+// allowsName returns whether the policy allows the file name matched by the
+// pattern, both cleaned and using sep as separator, by its extension and
+// the hidden elements of its path.
+func (p FilePolicy) allowsName(pattern, filename, sep string) bool {
+	if len(p.Extensions) != 0 {
+		ext, found := filepath.Ext(filename), false
+		for _, e := range p.Extensions {
+			found = found || e == ext
+		}
+		if !found {
+			return false
+		}
+	}
+	if !p.Hidden {
+		// Glob matches one name for each element of the pattern.
+		patterns := strings.Split(pattern, sep)
+		names := strings.Split(filename, sep)
+		for i, name := range names {
+			if strings.HasPrefix(name, ".") && name != "." && name != ".." &&
+				(i >= len(patterns) || !strings.HasPrefix(patterns[i], ".")) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FileError is an error reading or parsing a file.
+type FileError struct {
+	Path string // The absolute path of the file, or its path in the FS given to ParseFS.
+	Err  error
+}
+
+// newFileError returns a FileError for the named file.
+func newFileError(filename string, err error) *FileError {
+	path, absErr := filepath.Abs(filename)
+	if absErr != nil {
+		path = filename
+	}
+	return &FileError{path, err}
+}
+
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// ParseErrors holds the errors of the files that failed to parse, in the
+// order of the files. See Set.ParseGlob.
+type ParseErrors []*FileError
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ParseFiles adds the templates in the named files, as Set.ParseFiles
+// does. The return value is the builder, so calls can be chained.
+func (b *SetBuilder) ParseFiles(filenames ...string) *SetBuilder {
+	return b.add(func(s *Set) (*Set, error) {
+		return s.ParseFiles(filenames...)
+	})
+}
+
+// ParseGlob adds the templates in the files identified by the pattern, as
+// Set.ParseGlob does. The return value is the builder, so calls can be
+// chained.
+func (b *SetBuilder) ParseGlob(pattern string) *SetBuilder {
+	return b.add(func(s *Set) (*Set, error) {
+		return s.ParseGlob(pattern)
+	})
+}
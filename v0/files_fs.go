@@ -0,0 +1,104 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16 && !tinygo
+// +build go1.16,!tinygo
+
+package template
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// ParseFS is like ParseGlob, but reads the files from fsys, such as an
+// embed.FS, and accepts several patterns, with the syntax of fs.Glob.
+// A file matched by several patterns is parsed once. File names are paths
+// in fsys: they are used to report errors and, with AutoDefine, to name
+// the templates. The FilePolicy of the set applies, except that symbolic
+// links are resolved as fsys does. Files read by {{include}} and {{import}}
+// actions are still read from the operating system.
+func (s *Set) ParseFS(fsys fs.FS, patterns ...string) (*Set, error) {
+	return s.record(func(s *Set) (*Set, error) {
+		return s.parseFS(fsys, patterns)
+	})
+}
+
+// parseFS implements ParseFS.
+func (s *Set) parseFS(fsys fs.FS, patterns []string) (*Set, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf(
+			"template: ParseFS must be called with at least one pattern")
+	}
+	var filenames []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		matches = s.files.filterFS(fsys, pattern, matches)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf(
+				"template: pattern doesn't match any files: %#q", pattern)
+		}
+		for _, filename := range matches {
+			if !seen[filename] {
+				seen[filename] = true
+				filenames = append(filenames, filename)
+			}
+		}
+	}
+	var errs ParseErrors
+	for _, filename := range filenames {
+		if err := s.parseFSFile(fsys, filename); err != nil {
+			errs = append(errs, &FileError{filename, err})
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return s, nil
+}
+
+// parseFSFile parses the named file from fsys.
+func (s *Set) parseFSFile(fsys fs.FS, filename string) error {
+	f, err := fsys.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := s.readTemplate(filename, f)
+	if err != nil {
+		return err
+	}
+	_, err = s.parseFile(string(b), filename)
+	return err
+}
+
+// filterFS returns the files of fsys matched by the pattern that the
+// policy allows. Directories are never allowed.
+func (p FilePolicy) filterFS(fsys fs.FS, pattern string, filenames []string) []string {
+	var allowed []string
+	for _, filename := range filenames {
+		if !p.allowsName(path.Clean(pattern), path.Clean(filename), "/") {
+			continue
+		}
+		if fi, err := fs.Stat(fsys, filename); err == nil && fi.IsDir() {
+			continue
+		}
+		allowed = append(allowed, filename)
+	}
+	return allowed
+}
+
+// ParseFS adds the templates in the files of fsys identified by the
+// patterns, as Set.ParseFS does. The return value is the builder, so calls
+// can be chained.
+func (b *SetBuilder) ParseFS(fsys fs.FS, patterns ...string) *SetBuilder {
+	return b.add(func(s *Set) (*Set, error) {
+		return s.ParseFS(fsys, patterns...)
+	})
+}
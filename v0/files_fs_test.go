@@ -0,0 +1,57 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16 && !tinygo
+// +build go1.16,!tinygo
+
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`[{{slot "body"}}{{end}}]`)},
+		"pages/home.html":   {Data: []byte(`{{extends "layouts/base.html"}}{{fill "body"}}home{{end}}`)},
+		"pages/.draft.html": {Data: []byte(`{{.}`)},
+		"pages/notes.txt":   {Data: []byte(`notes`)},
+		"pages/sub/x.html":  {Data: []byte(`x`)},
+	}
+	set, err := new(Set).AutoDefine().Files(FilePolicy{Extensions: []string{".html"}}).
+		ParseFS(fsys, "layouts/*.html", "pages/*", "pages/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(set.Names(), " "), "layouts/base.html pages/home.html"; got != want {
+		t.Errorf("got templates %q, want %q", got, want)
+	}
+	var b bytes.Buffer
+	if err = set.Execute(&b, "pages/home.html", nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[home]" {
+		t.Errorf("got %q, want %q", b.String(), "[home]")
+	}
+
+	fsys = fstest.MapFS{
+		"pages/a.html": {Data: []byte(`{{define "a"}}{{.}{{end}}`)},
+		"pages/b.html": {Data: []byte(`{{define "b"}}{{end}`)},
+		"pages/c.html": {Data: []byte(`{{define "c"}}{{end}}`)},
+	}
+	_, err = new(Set).ParseFS(fsys, "pages/*.html")
+	errs, ok := err.(ParseErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("got error %v, want 2 file errors", err)
+	}
+	if errs[0].Path != "pages/a.html" || errs[1].Path != "pages/b.html" {
+		t.Errorf("got paths %q and %q", errs[0].Path, errs[1].Path)
+	}
+	if _, err = new(Set).ParseFS(fsys, "missing/*.html"); err == nil {
+		t.Errorf("expected error for a pattern matching no files")
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build tinygo
+// +build tinygo
+
+package template
+
+import (
+	"errors"
+)
+
+// readTemplateFile returns an error: TinyGo targets such as WebAssembly
+// have no file system, so ParseFiles and ParseGlob are not built, and
+// templates can't be imported with {{import}} or included with {{include}}.
+func (s *Set) readTemplateFile(filename string) ([]byte, error) {
+	return nil, errors.New("template: " + filename + ": files can't be read in this build")
+}
@@ -0,0 +1,151 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"reflect"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// foldTree removes the branches of {{if}} actions that can never execute
+// because their pipeline is a constant, as in {{if false}} or {{if $DEBUG}}
+// where $DEBUG is defined by {{set}}. It runs after inlining, so the removed
+// branches are not escaped either.
+func foldTree(tree parse.Tree, consts map[string]reflect.Value) {
+	for _, define := range tree {
+		foldList(define.List, consts)
+	}
+}
+
+// foldList folds the {{if}} actions in the list and in its children. The
+// variables declared in the list hide the constants with the same names,
+// and it returns the constants still visible after the list.
+//
+// May contain child actions:
+// CaptureNode: n.List
+// IfNode:     n.List, n.ElseList
+// LetNode:    n.List
+// ListNode:   n.Nodes
+// RangeNode:  n.List, n.ElseList
+// WithNode:   n.List, n.ElseList
+func foldList(l *parse.ListNode, consts map[string]reflect.Value) map[string]reflect.Value {
+	if l == nil {
+		return consts
+	}
+	// The nodes of a live branch may take more room than the action they
+	// replace, so the result is built in a new slice.
+	nodes := make([]parse.Node, 0, len(l.Nodes))
+	for _, n := range l.Nodes {
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			consts = hideConsts(consts, n.Pipe.Decl...)
+		case *parse.CaptureNode:
+			foldList(n.List, consts)
+			consts = hideConsts(consts, n.Variable)
+		case *parse.IfNode:
+			inner := hideConsts(consts, n.Pipe.Decl...)
+			foldList(n.List, inner)
+			foldList(n.ElseList, inner)
+			if truth, ok := constantTruth(n.Pipe, consts); ok {
+				branch := n.ElseList
+				if truth {
+					branch = n.List
+				}
+				if branch != nil && declares(branch) {
+					// The variables must stay scoped to the action,
+					// so only the dead branch is removed.
+					if truth {
+						n.ElseList = nil
+					} else {
+						n.List = &parse.ListNode{NodeType: parse.NodeList, Pos: n.List.Pos}
+					}
+					break
+				}
+				// Replace the action by the nodes of the live branch.
+				if branch != nil {
+					nodes = append(nodes, branch.Nodes...)
+				}
+				continue
+			}
+		case *parse.LetNode:
+			foldList(n.List, hideConsts(consts, n.Pipe.Decl...))
+		case *parse.ListNode:
+			consts = foldList(n, consts)
+		case *parse.RangeNode:
+			inner := hideConsts(consts, n.Pipe.Decl...)
+			foldList(n.List, inner)
+			foldList(n.ElseList, inner)
+		case *parse.WithNode:
+			inner := hideConsts(consts, n.Pipe.Decl...)
+			foldList(n.List, inner)
+			foldList(n.ElseList, inner)
+		}
+		nodes = append(nodes, n)
+	}
+	l.Nodes = nodes
+	return consts
+}
+
+// hideConsts returns consts without the declared variables.
+func hideConsts(consts map[string]reflect.Value, decl ...*parse.VariableNode) map[string]reflect.Value {
+	var m map[string]reflect.Value
+	for _, v := range decl {
+		if _, found := consts[v.Ident[0]]; !found {
+			continue
+		}
+		if m == nil {
+			m = make(map[string]reflect.Value, len(consts))
+			for name, value := range consts {
+				m[name] = value
+			}
+		}
+		delete(m, v.Ident[0])
+	}
+	if m == nil {
+		return consts
+	}
+	return m
+}
+
+// declares returns whether the list declares variables in its own scope,
+// which would leak into the enclosing one if the list was inlined.
+func declares(l *parse.ListNode) bool {
+	for _, n := range l.Nodes {
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			if len(n.Pipe.Decl) > 0 {
+				return true
+			}
+		case *parse.CaptureNode:
+			return true
+		case *parse.ListNode:
+			if declares(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// constantTruth returns the truth value of a pipeline that is a single
+// boolean or string constant, or a constant defined by {{set}}, and whether
+// the pipeline is such a constant.
+func constantTruth(pipe *parse.PipeNode, consts map[string]reflect.Value) (truth, ok bool) {
+	if len(pipe.Decl) != 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false, false
+	}
+	switch n := pipe.Cmds[0].Args[0].(type) {
+	case *parse.BoolNode:
+		return n.True, true
+	case *parse.StringNode:
+		return len(n.Text) > 0, true
+	case *parse.VariableNode:
+		if v, found := consts[n.Ident[0]]; found && len(n.Ident) == 1 {
+			return isTrue(v)
+		}
+	}
+	return false, false
+}
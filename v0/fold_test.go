@@ -0,0 +1,85 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+)
+
+var foldTests = []struct {
+	name   string
+	input  string
+	output string
+}{
+	{"true", `{{if true}}a{{end}}`, `a`},
+	{"false", `{{if false}}a{{end}}`, ``},
+	{"true else", `{{if true}}a{{else}}b{{end}}`, `a`},
+	{"false else", `{{if false}}a{{else}}b{{end}}`, `b`},
+	{"string", `{{if "x"}}a{{else}}b{{end}}`, `a`},
+	{"empty string", `{{if ""}}a{{else}}b{{end}}`, `b`},
+	{"nested", `{{range .}}{{if false}}a{{else}}{{if true}}{{.}}{{end}}{{end}}{{end}}`, `{{range .}}{{.}}{{end}}`},
+	{"not constant", `{{if .}}a{{end}}`, `{{if .}}a{{end}}`},
+	{"declaration", `{{if $x := true}}{{$x}}{{end}}`, `{{if $x := true}}{{$x}}{{end}}`},
+	{"pipeline", `{{if true | not}}a{{end}}`, `{{if true | not}}a{{end}}`},
+	{"siblings", `{{if true}}A{{.}}{{end}}B{{.}}C`, `A{{.}}B{{.}}C`},
+	{"siblings else", `{{if false}}a{{else}}A{{.}}{{end}}B{{.}}C`, `A{{.}}B{{.}}C`},
+	{"branch declaration", `{{if true}}{{$x := 2}}{{$x}}{{else}}b{{end}}{{.}}`, `{{if true}}{{$x := 2}}{{$x}}{{end}}{{.}}`},
+	{"else declaration", `{{if false}}a{{else}}{{$x := 2}}{{end}}`, `{{if false}}{{else}}{{$x := 2}}{{end}}`},
+	{"capture declaration", `{{if true}}{{capture $x}}a{{end}}{{end}}`, `{{if true}}{{capture $x}}a{{end}}{{end}}`},
+}
+
+func TestFold(t *testing.T) {
+	for _, test := range foldTests {
+		set, err := new(Set).Parse(`{{define "t"}}` + test.input + `{{end}}`)
+		if err != nil {
+			t.Errorf("%s: parse error: %s", test.name, err)
+			continue
+		}
+		if _, err = set.Compile(); err != nil {
+			t.Errorf("%s: compile error: %s", test.name, err)
+			continue
+		}
+		if s := set.tree["t"].List.String(); s != test.output {
+			t.Errorf("%s: expected %q; got %q", test.name, test.output, s)
+		}
+	}
+}
+
+func TestFoldExecute(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{`{{if true}}A{{.}}{{end}}B{{.}}C`, `AxBxC`},
+		{`{{if true}}{{if true}}A{{.}}{{end}}B{{end}}{{.}}C{{.}}`, `AxBxCx`},
+		{`{{$x := 1}}{{if true}}{{$x := 2}}{{$x}}{{end}}{{$x}}`, `21`},
+		{`{{$x := 1}}{{if false}}a{{else}}{{$x := 2}}{{end}}{{$x}}`, `1`},
+	}
+	for _, test := range tests {
+		set := Must(new(Set).Parse(`{{define "t"}}` + test.input + `{{end}}`))
+		b := new(bytes.Buffer)
+		if err := set.Execute(b, "t", "x"); err != nil {
+			t.Errorf("%q: %s", test.input, err)
+			continue
+		}
+		if s := b.String(); s != test.output {
+			t.Errorf("%q: expected %q; got %q", test.input, test.output, s)
+		}
+	}
+}
+
+func TestFoldSkipsEscaping(t *testing.T) {
+	// The dead branch ends in a different context, which is an error
+	// unless it is removed before escaping.
+	set := Must(new(Set).Parse(`{{define "t"}}{{if false}}<a href="{{end}}{{.}}{{end}}`))
+	b := new(bytes.Buffer)
+	if err := set.Escape().Execute(b, "t", "<b>"); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != "&lt;b&gt;" {
+		t.Errorf("expected %q; got %q", "&lt;b&gt;", s)
+	}
+}
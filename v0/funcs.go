@@ -6,9 +6,13 @@ package template
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/gorilla/template/v0/escape"
+	"github.com/gorilla/template/v0/parse"
 )
 
 // FuncMap is the type of the map defining the mapping from names to functions.
@@ -18,23 +22,123 @@ import (
 // Execute returns that error.
 type FuncMap map[string]interface{}
 
+// builtins are the functions available to all templates.
+//
+// Besides the core functions, builtins are organized in namespaces to avoid
+// collisions with user functions: they are called using the namespace as a
+// prefix, as in {{strings.upper .Name}} or {{math.add 1 2}}. Set.FlatBuiltins
+// makes them also available by their short names.
 var builtins = FuncMap{
-	"and":      and,
+	"and":      truthFuncs(nil).and,
 	"call":     call,
+	"debug":    debugBuiltin,
+	"dump":     dump,
+	"eq":       eq,
+	"ge":       ge,
+	"gt":       gt,
 	"html":     escape.HTMLEscaper,
 	"index":    index,
 	"js":       escape.JSEscaper,
+	"le":       le,
 	"len":      length,
-	"not":      not,
-	"or":       or,
+	"lt":       lt,
+	"ne":       ne,
+	"not":      truthFuncs(nil).not,
+	"or":       truthFuncs(nil).or,
 	"print":    fmt.Sprint,
 	"printf":   fmt.Sprintf,
 	"println":  fmt.Sprintln,
 	"urlquery": escape.URLQueryEscaper,
+	// Namespace "strings". Arguments follow the order of the functions
+	// from the Go package strings.
+	"strings.contains":  strings.Contains,
+	"strings.hasPrefix": strings.HasPrefix,
+	"strings.hasSuffix": strings.HasSuffix,
+	"strings.join":      strings.Join,
+	"strings.lower":     strings.ToLower,
+	"strings.repeat":    strings.Repeat,
+	"strings.replace":   replaceAll,
+	"strings.split":     strings.Split,
+	"strings.title":     strings.Title,
+	"strings.trim":      strings.TrimSpace,
+	"strings.truncate":  truncate,
+	"strings.upper":     strings.ToUpper,
+	// Namespace "html".
+	"html.attr":      escape.Attr,
+	"html.classes":   truthFuncs(nil).classes,
+	"html.plaintext": escape.PlainText,
+	"html.truncate":  escape.TruncateHTML,
+	// Namespace "js".
+	"js.json": escape.JSON,
+	// Namespace "url".
+	"url.build": buildURL,
+	// Namespace "maps".
+	"maps.dict": dict,
+	// Namespace "yaml".
+	"yaml.scalar": escape.YAML,
+	// Namespace "shell".
+	"shell.psquote": escape.PowerShellQuote,
+	"shell.quote":   escape.ShellQuote,
+	// Namespace "latex".
+	"latex.escape": escape.LaTeXEscaper,
+	// Namespace "csv".
+	"csv.field":    csvField,
+	"csv.row":      csvRow,
+	"csv.tsvField": tsvField,
+	"csv.tsvRow":   tsvRow,
+	// Namespace "math".
+	"math.add": mathAdd,
+	"math.div": mathDiv,
+	"math.mod": mathMod,
+	"math.mul": mathMul,
+	"math.sub": mathSub,
+	// Namespace "sql", see Set.SQLDialect.
+	"sql.ident":   escape.SQLStandard.Identifier,
+	"sql.literal": escape.SQLStandard.Literal,
+	// Called by {{variant}} actions, see Set.Experiments.
+	parse.VariantFunc:   noVariant,
+	parse.VariantEqFunc: variantEq,
 }
 
 var builtinFuncs = createValueFuncs(builtins)
 
+// flatBuiltins maps the short names of namespaced builtins to the functions,
+// as in "upper" for "strings.upper".
+var flatBuiltins = createFlatBuiltins(builtins)
+
+// createFlatBuiltins returns a FuncMap with the namespaced functions from
+// funcMap keyed by their names without namespace. If two functions have the
+// same short name, the one from the "strings" namespace wins: it works on
+// plain strings, so it can be piped into other functions.
+func createFlatBuiltins(funcMap FuncMap) FuncMap {
+	m := make(FuncMap)
+	for name, fn := range funcMap {
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			if _, ok := funcMap["strings"+name[i:]]; ok && name[:i] != "strings" {
+				continue
+			}
+			m[name[i+1:]] = fn
+		}
+	}
+	return m
+}
+
+// reservedFuncs are the builtins that contextual escaping relies on.
+var reservedFuncs = map[string]bool{
+	"html":     true,
+	"js":       true,
+	"urlquery": true,
+}
+
+// checkReservedFunc returns an error if a function with the given name
+// can't be added to a set, because escaping relies on it.
+func checkReservedFunc(name string) error {
+	if reservedFuncs[name] || strings.HasPrefix(name, "html_template_") {
+		return fmt.Errorf("template: function %q is reserved for escaping and can't be overridden", name)
+	}
+	return nil
+}
+
 // createValueFuncs turns a FuncMap into a map[string]reflect.Value
 func createValueFuncs(funcMap FuncMap) map[string]reflect.Value {
 	m := make(map[string]reflect.Value)
@@ -76,12 +180,14 @@ func goodFunc(typ reflect.Type) bool {
 	return false
 }
 
-// findFunction looks for a function in the template, and global map.
-func findFunction(name string, set *Set) (reflect.Value, bool) {
-	if set != nil {
-		if fn := set.execFuncs[name]; fn.IsValid() {
-			return fn, true
-		}
+// findFunction looks for a function in the set's map, and global map. The
+// builtins that depend on truth values use the set's truth functions.
+func findFunction(name string, funcs map[string]reflect.Value, truths truthFuncs) (reflect.Value, bool) {
+	if fn := funcs[name]; fn.IsValid() {
+		return fn, true
+	}
+	if fn, ok := truths.builtin(name); ok {
+		return fn, true
 	}
 	if fn := builtinFuncs[name]; fn.IsValid() {
 		return fn, true
@@ -136,6 +242,25 @@ func index(item interface{}, indices ...interface{}) (interface{}, error) {
 	return v.Interface(), nil
 }
 
+// Maps.
+
+// dict returns a map built from the given key/value pairs, as in
+// {{maps.dict "q" .Query "page" .Page}}.
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("maps.dict needs key/value pairs, got %d arguments", len(pairs))
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("maps.dict key must be a string, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
 // Length
 
 // length returns the length of the item, with an error if it has no defined length.
@@ -203,20 +328,15 @@ func call(fn interface{}, args ...interface{}) (interface{}, error) {
 
 // Boolean logic.
 
-func truth(a interface{}) bool {
-	t, _ := isTrue(reflect.ValueOf(a))
-	return t
-}
-
 // and computes the Boolean AND of its arguments, returning
 // the first false argument it encounters, or the last argument.
-func and(arg0 interface{}, args ...interface{}) interface{} {
-	if !truth(arg0) {
+func (t truthFuncs) and(arg0 interface{}, args ...interface{}) interface{} {
+	if !t.truth(arg0) {
 		return arg0
 	}
 	for i := range args {
 		arg0 = args[i]
-		if !truth(arg0) {
+		if !t.truth(arg0) {
 			break
 		}
 	}
@@ -225,13 +345,13 @@ func and(arg0 interface{}, args ...interface{}) interface{} {
 
 // or computes the Boolean OR of its arguments, returning
 // the first true argument it encounters, or the last argument.
-func or(arg0 interface{}, args ...interface{}) interface{} {
-	if truth(arg0) {
+func (t truthFuncs) or(arg0 interface{}, args ...interface{}) interface{} {
+	if t.truth(arg0) {
 		return arg0
 	}
 	for i := range args {
 		arg0 = args[i]
-		if truth(arg0) {
+		if t.truth(arg0) {
 			break
 		}
 	}
@@ -239,7 +359,98 @@ func or(arg0 interface{}, args ...interface{}) interface{} {
 }
 
 // not returns the Boolean negation of its argument.
-func not(arg interface{}) (truth bool) {
-	truth, _ = isTrue(reflect.ValueOf(arg))
-	return !truth
+func (t truthFuncs) not(arg interface{}) bool {
+	return !t.truth(arg)
+}
+
+// Strings.
+
+// classes returns the space-separated list of class names in args, for the
+// class attribute. A string argument is always a class name. A map with
+// string keys holds conditional classes, each included only if its value
+// is true, in the order of the keys, as in
+// {{html.classes "btn" (maps.dict "active" .IsActive)}}. Empty names are
+// skipped.
+func (t truthFuncs) classes(args ...interface{}) (string, error) {
+	var names []string
+	for _, arg := range args {
+		if name, ok := arg.(string); ok {
+			names = append(names, strings.Fields(name)...)
+			continue
+		}
+		m := reflect.ValueOf(arg)
+		if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+			return "", fmt.Errorf("html.classes: expected class name or map of conditions; got %T", arg)
+		}
+		keys := make([]string, 0, m.Len())
+		for _, k := range m.MapKeys() {
+			if t.truth(m.MapIndex(k).Interface()) {
+				keys = append(keys, k.String())
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			names = append(names, strings.Fields(k)...)
+		}
+	}
+	return strings.Join(names, " "), nil
+}
+
+// replaceAll returns a copy of s with all occurrences of old replaced by new.
+func replaceAll(s, old, new string) string {
+	return strings.Replace(s, old, new, -1)
+}
+
+// truncate shortens s to n characters, appending an ellipsis if it was
+// longer. See html.truncate for HTML content.
+func truncate(n int, s string) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i] + "\u2026"
+		}
+		n--
+	}
+	return s
+}
+
+// URLs.
+
+// buildURL returns base with the given parameters added to its query
+// string, as in {{url.build "/search" (maps.dict "q" .Query "page" .Page)}}.
+// The query of base is kept as is, and the parameters are appended to it,
+// sorted by key. Parameters with a nil value are left out, and slices add
+// one value per element. The result is typed as a safe URL, so base must be a relative
+// URL or use the http, https or mailto scheme.
+func buildURL(base string, params map[string]interface{}) (escape.URL, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("url.build: %s", err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+	default:
+		return "", fmt.Errorf("url.build: unsafe URL scheme %q", u.Scheme)
+	}
+	query := make(url.Values)
+	for key, value := range params {
+		v, isNil := indirect(reflect.ValueOf(value))
+		if isNil || !v.IsValid() {
+			continue
+		}
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				query.Add(key, fmt.Sprint(v.Index(i).Interface()))
+			}
+		default:
+			query.Add(key, fmt.Sprint(v.Interface()))
+		}
+	}
+	if encoded := query.Encode(); encoded != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += encoded
+	}
+	return escape.URL(u.String()), nil
 }
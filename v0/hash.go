@@ -0,0 +1,74 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Hash compiles the set and returns a hash of the named template, as a
+// hexadecimal string. The hash covers the compiled template, the templates
+// it calls and the constants of the set, using the canonical encoding of
+// parse.Encode, so it changes only when the template can produce a
+// different output. It is useful to detect template changes in build
+// systems, or to version caches keyed on template content.
+//
+// The functions of the set are not part of the hash.
+func (s *Set) Hash(name string) (string, error) {
+	if _, err := s.Compile(); err != nil {
+		return "", err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.tree[name] == nil {
+		return "", notFound(s.tree, name)
+	}
+	reachable := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		define := s.tree[name]
+		if define == nil || reachable[name] {
+			return
+		}
+		reachable[name] = true
+		templateCalls(define.List, func(n *parse.TemplateNode) {
+			visit(n.Name)
+		})
+	}
+	visit(name)
+	names := make([]string, 0, len(reachable))
+	for name := range reachable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n", name)
+	for _, name := range names {
+		fmt.Fprintf(h, "%q %s\n", name, parse.Encode(s.tree[name].List))
+	}
+	consts := make([]string, 0, len(s.consts))
+	for name := range s.consts {
+		consts = append(consts, name)
+	}
+	sort.Strings(consts)
+	for _, name := range consts {
+		fmt.Fprintf(h, "%s %#v\n", name, valueInterface(s.consts[name]))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// valueInterface returns the value held by v, or nil if v is invalid.
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
@@ -0,0 +1,97 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"github.com/gorilla/template/v0/parse"
+)
+
+// loadImports parses the files imported by the templates in tree with
+// {{import "path" as alias}}, and adds their templates to tree under names
+// qualified by the path, so that they don't collide with other templates.
+// Paths are relative to the working directory, as in ParseFiles. A file is
+// parsed once per set, even if it is imported many times, and only if some
+// of its templates are used. Imported files count towards the limits of
+// the set. The caller must hold the mutex.
+func (s *Set) loadImports(tree parse.Tree) error {
+	loaded := make(map[string]bool)
+	pending := importPaths(tree)
+	for len(pending) > 0 {
+		path := pending[0]
+		pending = pending[1:]
+		if s.imported[path] || loaded[path] {
+			continue
+		}
+		loaded[path] = true
+		b, err := s.readTemplateFile(path)
+		if err != nil {
+			return err
+		}
+		if err = s.checkLimits(path, string(b), true); err != nil {
+			return err
+		}
+		imported, err := parse.Parse(path, string(b), s.leftDelim, s.rightDelim,
+			builtins, s.parseFuncs)
+		if err != nil {
+			return err
+		}
+		qualifyTree(path, imported)
+		pending = append(pending, importPaths(imported)...)
+		for _, define := range imported {
+			if err = tree.Add(define); err != nil {
+				return err
+			}
+		}
+	}
+	if s.imported == nil {
+		s.imported = make(map[string]bool)
+	}
+	for path := range loaded {
+		s.imported[path] = true
+	}
+	return nil
+}
+
+// importPaths returns the paths of the files imported by the templates in
+// tree.
+func importPaths(tree parse.Tree) []string {
+	var paths []string
+	for _, define := range tree {
+		templateCalls(define.List, func(n *parse.TemplateNode) {
+			if path, _, ok := parse.SplitImportName(n.Name); ok {
+				paths = append(paths, path)
+			}
+		})
+	}
+	return paths
+}
+
+// qualifyTree renames the templates in tree, parsed from the file at path,
+// with their import names, and updates the references between them.
+func qualifyTree(path string, tree parse.Tree) {
+	qualify := func(name string) string {
+		if tree[name] == nil {
+			return name
+		}
+		return parse.ImportName(path, name)
+	}
+	defines := make([]*parse.DefineNode, 0, len(tree))
+	for _, define := range tree {
+		if define.IsConstant() {
+			// Constants are visible to all templates.
+			continue
+		}
+		defines = append(defines, define)
+		define.Parent = qualify(define.Parent)
+		templateCalls(define.List, func(n *parse.TemplateNode) {
+			n.Name = qualify(n.Name)
+		})
+	}
+	for _, define := range defines {
+		delete(tree, define.Name)
+		define.Name = parse.ImportName(path, define.Name)
+		tree[define.Name] = define
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxIncludeDepth limits the nesting of {{include}} actions.
+const maxIncludeDepth = 100
+
+// trimSpace is the white space removed by trim markers.
+const trimSpace = " \t\r\n"
+
+// includePatterns caches the include patterns by delimiters.
+var includePatterns sync.Map
+
+// includePattern returns the regular expression matching the {{include}}
+// actions and the comments of a text with the given delimiters. The
+// submatches of an action are the left trim marker, the quoted path and
+// the right trim marker; a comment has none, so that the actions in
+// comments are skipped.
+func includePattern(left, right string) *regexp.Regexp {
+	key := left + "\x00" + right
+	if re, ok := includePatterns.Load(key); ok {
+		return re.(*regexp.Regexp)
+	}
+	l, r := regexp.QuoteMeta(left), regexp.QuoteMeta(right)
+	re := regexp.MustCompile(`(?s)` + l + `/\*.*?\*/` + r + `|` +
+		l + `(?:(-)\s)?\s*include\s+("(?:[^"\\\n]|\\.)*"|` + "`[^`]*`" + `)\s*(?:\s(-))?` + r)
+	includePatterns.Store(key, re)
+	return re
+}
+
+// expandIncludes replaces the {{include "path"}} actions in text, parsed
+// under the given name, by the contents of the files at path, which can
+// include other files. Unlike {{template}}, which calls another template
+// when executed, an include is resolved textually before parsing, so the
+// contents of the file become part of the including template and share its
+// scope: variables, fills and so on. Paths are relative to the working
+// directory, as in ParseFiles.
+//
+// The included text is surrounded by line directives, so that errors are
+// reported against the included file and the including one. Trim markers,
+// as in {{- include "path" -}}, remove the white space before or after the
+// action. Actions in comments are left alone. The size
+// limits of the set are checked as files are included, so that a text
+// including the same file many times fails before it is expanded.
+// The caller must hold the mutex.
+func (s *Set) expandIncludes(name, text string) (string, error) {
+	left, right := s.leftDelim, s.rightDelim
+	if left == "" {
+		left = "{{"
+	}
+	if right == "" {
+		right = "}}"
+	}
+	re := includePattern(left, right)
+	size := len(text) // of the text and the files included so far.
+	var expand func(name, text string, stack []string) (string, error)
+	expand = func(name, text string, stack []string) (string, error) {
+		matches := re.FindAllStringSubmatchIndex(text, -1)
+		if matches == nil {
+			return text, nil
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			if m[4] < 0 {
+				// A comment.
+				continue
+			}
+			line := 1 + strings.Count(text[:m[0]], "\n")
+			path, err := strconv.Unquote(text[m[4]:m[5]])
+			if err != nil {
+				return "", fmt.Errorf("template: %s:%d: include: %s", name, line, err)
+			}
+			for _, p := range stack {
+				if p == path {
+					return "", fmt.Errorf("template: %s:%d: include cycle: %s -> %s",
+						name, line, strings.Join(stack, " -> "), path)
+				}
+			}
+			if len(stack) >= maxIncludeDepth {
+				return "", fmt.Errorf("template: %s:%d: includes nested too deeply", name, line)
+			}
+			s.addRead(path)
+			contents, err := s.readTemplateFile(path)
+			if err != nil {
+				return "", fmt.Errorf("template: %s:%d: include: %s", name, line, err)
+			}
+			size += len(contents)
+			if err = s.checkGrowth(stack[0], size); err != nil {
+				return "", err
+			}
+			included, err := expand(path, string(contents), append(stack, path))
+			if err != nil {
+				return "", err
+			}
+			before, end := text[last:m[0]], m[1]
+			if m[2] >= 0 {
+				before = strings.TrimRight(before, trimSpace)
+			}
+			if m[6] >= 0 {
+				end = len(text) - len(strings.TrimLeft(text[end:], trimSpace))
+			}
+			b.WriteString(before)
+			fmt.Fprintf(&b, "%s/*line %s:1*/%s%s", left, path, right, included)
+			fmt.Fprintf(&b, "%s/*line %s:%d*/%s", left, name, 1+strings.Count(text[:end], "\n"), right)
+			last = end
+		}
+		b.WriteString(text[last:])
+		return b.String(), nil
+	}
+	return expand(name, text, []string{name})
+}
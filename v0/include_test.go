@@ -0,0 +1,68 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInclude(t *testing.T) {
+	set, err := new(Set).Parse(`{{define "page"}}{{$title := .Title}}
+{{include "testdata/include/header.part"}}
+<p>{{$title}}</p>{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	data := map[string]interface{}{"Title": "Home", "Links": []string{"a", "b"}}
+	if err = set.Execute(&b, "page", data); err != nil {
+		t.Fatal(err)
+	}
+	want := "\n<h1>Home</h1>\n<nav>a | b</nav>\n\n<p>Home</p>"
+	if b.String() != want {
+		t.Errorf("expected %q, got %q", want, b.String())
+	}
+	// Errors are reported against the included file and the including one.
+	set = Must(new(Set).Parse(`{{define "a"}}
+{{include "testdata/include/broken.part"}}
+{{.Other.Field}}{{end}}`))
+	err = set.Execute(&b, "a", map[string]interface{}{"Missing": 1, "Other": 1})
+	if err == nil || !strings.Contains(err.Error(), "testdata/include/broken.part:2:") {
+		t.Errorf("expected error in broken.part:2, got %v", err)
+	}
+	set = Must(new(Set).Parse(`{{define "a"}}
+{{include "testdata/include/broken.part"}}
+{{.Other.Field}}{{end}}`))
+	err = set.Execute(&b, "a", map[string]interface{}{"Missing": map[string]int{}, "Other": 1})
+	if err == nil || !strings.Contains(err.Error(), "template string:3:") {
+		t.Errorf("expected error in line 3, got %v", err)
+	}
+	// Comments are skipped, and trim markers remove the white space
+	// around the action.
+	set = Must(new(Set).Parse(`{{define "a"}}{{/* {{include "testdata/include/missing.part"}} */}}
+[  {{- include "testdata/include/nav.part" -}}
+  ]{{end}}`))
+	b.Reset()
+	if err = set.Execute(&b, "a", data); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\n[<nav>a | b</nav>]"; b.String() != want {
+		t.Errorf("expected %q, got %q", want, b.String())
+	}
+	tests := []struct {
+		input, err string
+	}{
+		{`{{define "a"}}{{include "testdata/include/missing.part"}}{{end}}`, "template string:1: include:"},
+		{`{{define "a"}}{{include "testdata/include/cycle1.part"}}{{end}}`, "include cycle: template string -> testdata/include/cycle1.part -> testdata/include/cycle2.part -> testdata/include/cycle1.part"},
+	}
+	for _, test := range tests {
+		_, err := new(Set).Parse(test.input)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q, got %v", test.input, test.err, err)
+		}
+	}
+}
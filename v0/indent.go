@@ -0,0 +1,89 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io"
+)
+
+// IndentTemplates makes {{template}} actions that are preceded only by
+// spaces and tabs on their line re-indent the output of the called
+// template, so that each of its lines starts with the same indentation as
+// the first one. It is useful to generate indentation-sensitive text, such
+// as YAML documents or source code, from templates written without
+// indentation:
+//
+//	{{define "labels"}}app: web
+//	tier: {{.Tier}}
+//	{{end}}
+//	{{define "deployment"}}metadata:
+//	  labels:
+//	    {{template "labels" .}}
+//	{{end}}
+//
+// Calls can be nested, and the indentations add up. Empty lines are not
+// indented. The output of LiveRender is not re-indented. The return value
+// is the set, so calls can be chained.
+func (s *Set) IndentTemplates() *Set {
+	s.indent = true
+	return s
+}
+
+// indentWriter is a writer that adds an indentation to the lines written
+// to it after the first one. It tracks the indentation of the current line
+// for the {{template}} actions executed while writing to it.
+type indentWriter struct {
+	w      io.Writer
+	indent []byte // added to the lines after the first; can be empty
+	start  bool   // a line was ended, the indentation is not written yet
+	line   []byte // spaces and tabs written since the start of the line
+	blank  bool   // only spaces and tabs were written in the current line
+	buf    []byte // reused by writes
+}
+
+func newIndentWriter(w io.Writer, indent []byte) *indentWriter {
+	return &indentWriter{w: w, indent: indent, blank: true}
+}
+
+func (w *indentWriter) Write(p []byte) (int, error) {
+	out := w.buf[:0]
+	for _, c := range p {
+		if w.start && c != '\n' && c != '\r' {
+			out = append(out, w.indent...)
+			w.start = false
+		}
+		out = append(out, c)
+		switch {
+		case c == '\n':
+			w.start = len(w.indent) > 0
+			w.line = w.line[:0]
+			w.blank = true
+		case w.blank && (c == ' ' || c == '\t'):
+			w.line = append(w.line, c)
+		case c != '\r':
+			w.blank = false
+		}
+	}
+	w.buf = out
+	if _, err := w.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// callWriter returns the writer for the output of a {{template}} action
+// writing to wr. If wr tracks indentation and the current line is blank,
+// the output is indented like the line.
+func callWriter(wr io.Writer) io.Writer {
+	w, ok := wr.(*indentWriter)
+	if !ok {
+		return wr
+	}
+	var indent []byte
+	if w.blank {
+		indent = append(indent, w.line...)
+	}
+	return newIndentWriter(w, indent)
+}
@@ -0,0 +1,50 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIndentTemplates(t *testing.T) {
+	const text = `
+{{define "labels"}}app: web
+tier: {{.}}
+
+{{end}}
+{{define "meta"}}name: x
+labels:
+  {{template "labels" .}}{{end}}
+{{define "deployment"}}metadata:
+  {{template "meta" .}}
+spec: {{template "labels" .}}
+{{capture $c}}
+    {{template "labels" .}}{{end}}{{$c}}{{end}}`
+	const want = `metadata:
+  name: x
+  labels:
+    app: web
+    tier: db
+
+
+spec: app: web
+tier: db
+
+
+
+    app: web
+    tier: db
+
+`
+	s := Must(new(Set).IndentTemplates().Parse(text))
+	var b bytes.Buffer
+	if err := s.Execute(&b, "deployment", "db"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
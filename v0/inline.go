@@ -112,7 +112,9 @@ func inlineDefine(tree parse.Tree, name string) error {
 	define.List = parent.List.CopyList()
 	define.Parent = parent.Parent
 	// Replace FillNode's and SlotNode's from parent.
-	applyFillers(define.List, fillers, unused)
+	if err := applyFillers(define.List, fillers, unused); err != nil {
+		return fmt.Errorf("template: %q: %s", name, err)
+	}
 	// Add extra fillers.
 	for k, v := range unused {
 		if v {
@@ -124,53 +126,97 @@ func inlineDefine(tree parse.Tree, name string) error {
 }
 
 // applyFillers replaces slot and fill nodes by their filler counterparts.
-func applyFillers(n parse.Node, fillers map[string]*parse.FillNode, unused map[string]bool) {
+// It returns an error if a filler targets a constant block.
+func applyFillers(n parse.Node, fillers map[string]*parse.FillNode, unused map[string]bool) error {
 	switch n := n.(type) {
 	case *parse.IfNode:
-		applyFillers(n.List, fillers, unused)
-		applyFillers(n.ElseList, fillers, unused)
+		if err := applyFillers(n.List, fillers, unused); err != nil {
+			return err
+		}
+		return applyFillers(n.ElseList, fillers, unused)
 	case *parse.ListNode:
 		if n == nil {
-			return
+			return nil
 		}
 		for k, v := range n.Nodes {
 			switch v := v.(type) {
+			case *parse.ConstNode:
+				if fillers[v.Name] != nil {
+					return fmt.Errorf("fill %q overrides a constant block", v.Name)
+				}
 			case *parse.SlotNode:
 				// Replace the slot by the list of nodes from the filler.
 				if filler := fillers[v.Name]; filler != nil {
-					n.Nodes[k] = filler.List.CopyList()
+					n.Nodes[k] = fillList(v.List, filler)
 				}
 			case *parse.FillNode:
-				// Replace the fill by the new filler.
+				// Replace the fill by the new filler. A filler that adds to
+				// the fill adds to the slot the same way the fill did.
 				if filler := fillers[v.Name]; filler != nil {
-					n.Nodes[k] = filler.CopyFill()
+					f := filler.CopyFill()
+					if filler.Mode != parse.FillReplace {
+						f.List = fillList(v.List, filler)
+						f.Mode = v.Mode
+					}
+					n.Nodes[k] = f
 					unused[v.Name] = false
 				}
 			default:
-				applyFillers(v, fillers, unused)
+				if err := applyFillers(v, fillers, unused); err != nil {
+					return err
+				}
 			}
 		}
+	case *parse.CaptureNode:
+		return applyFillers(n.List, fillers, unused)
+	case *parse.LetNode:
+		return applyFillers(n.List, fillers, unused)
 	case *parse.RangeNode:
-		applyFillers(n.List, fillers, unused)
-		applyFillers(n.ElseList, fillers, unused)
+		if err := applyFillers(n.List, fillers, unused); err != nil {
+			return err
+		}
+		return applyFillers(n.ElseList, fillers, unused)
 	case *parse.WithNode:
-		applyFillers(n.List, fillers, unused)
-		applyFillers(n.ElseList, fillers, unused)
+		if err := applyFillers(n.List, fillers, unused); err != nil {
+			return err
+		}
+		return applyFillers(n.ElseList, fillers, unused)
+	}
+	return nil
+}
+
+// fillList returns the nodes that result from applying filler to a slot or
+// fill with the given contents.
+func fillList(contents *parse.ListNode, filler *parse.FillNode) *parse.ListNode {
+	var nodes []parse.Node
+	switch filler.Mode {
+	case parse.FillAppend:
+		nodes = []parse.Node{contents.CopyList(), filler.List.CopyList()}
+	case parse.FillPrepend:
+		nodes = []parse.Node{filler.List.CopyList(), contents.CopyList()}
+	default:
+		return filler.List.CopyList()
 	}
+	return &parse.ListNode{NodeType: parse.NodeList, Pos: filler.List.Pos, Nodes: nodes}
 }
 
-// cleanupSlot removes slot and fill nodes.
+// cleanupSlot removes slot, const and fill nodes.
 //
 // May contain child actions:
+// CaptureNode: n.List
+// ConstNode:  n.List
 // SlotNode:  n.List
 // DefineNode: n.List
 // FillNode:   n.List
 // IfNode:     n.List, n.ElseList
+// LetNode:    n.List
 // ListNode:   n.Nodes
 // RangeNode:  n.List, n.ElseList
 // WithNode:   n.List, n.ElseList
 func cleanupSlot(n parse.Node) {
 	switch n := n.(type) {
+	case *parse.CaptureNode:
+		cleanupSlot(n.List)
 	case *parse.IfNode:
 		cleanupSlot(n.List)
 		cleanupSlot(n.ElseList)
@@ -182,6 +228,10 @@ func cleanupSlot(n parse.Node) {
 		for k < len(n.Nodes) {
 			v := n.Nodes[k]
 			switch v := v.(type) {
+			case *parse.ConstNode:
+				// Replace the constant block by its list of nodes.
+				n.Nodes[k] = v.List
+				continue
 			case *parse.SlotNode:
 				// Replace the slot by its list of nodes.
 				n.Nodes[k] = v.List
@@ -195,6 +245,8 @@ func cleanupSlot(n parse.Node) {
 			}
 			k++
 		}
+	case *parse.LetNode:
+		cleanupSlot(n.List)
 	case *parse.RangeNode:
 		cleanupSlot(n.List)
 		cleanupSlot(n.ElseList)
@@ -0,0 +1,56 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"reflect"
+)
+
+// Lazy is implemented by values that are computed on demand. A template
+// calls Eval only when it uses the value: to print it, to test it in an
+// if or with action, to range over it, to evaluate a field or method of
+// it, or to pass it as an argument of a function that expects a
+// different type.
+//
+// Struct fields and map elements that hold functions with no arguments
+// returning one value, or a value and an error, are treated the same
+// way. Passing such a function to the call builtin still calls it
+// explicitly. A lazy value is computed each time it is used; bind it
+// with {{let $x := pipeline}} ... {{end}} to compute it once.
+type Lazy interface {
+	Eval() (interface{}, error)
+}
+
+var lazyType = reflect.TypeOf((*Lazy)(nil)).Elem()
+
+// evalLazy returns the value computed by v if v holds a lazy value.
+// Otherwise it returns v.
+func (s *state) evalLazy(v reflect.Value) reflect.Value {
+	w := v
+	if w.Kind() == reflect.Interface && !w.IsNil() {
+		w = w.Elem()
+	}
+	if !w.IsValid() {
+		return v
+	}
+	switch {
+	case w.Type().Implements(lazyType):
+		if isNil(w) {
+			return v
+		}
+		result, err := w.Interface().(Lazy).Eval()
+		if err != nil {
+			s.errorf("error evaluating lazy value of type %s: %s", w.Type(), err)
+		}
+		return reflect.ValueOf(result)
+	case w.Kind() == reflect.Func && !w.IsNil() && w.Type().NumIn() == 0 && goodFunc(w.Type()):
+		result := w.Call(nil)
+		if len(result) == 2 && !result[1].IsNil() {
+			s.errorf("error evaluating lazy value of type %s: %s", w.Type(), result[1].Interface().(error))
+		}
+		return result[0]
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"errors"
+	"testing"
+)
+
+type lazyCount struct {
+	n *int
+	v interface{}
+}
+
+func (l lazyCount) Eval() (interface{}, error) {
+	*l.n++
+	return l.v, nil
+}
+
+type lazyData struct {
+	Name    func() string
+	Items   func() []int
+	Inner   func() *T
+	Fail    func() (string, error)
+	Counted Lazy
+	Unused  func() string
+}
+
+func TestLazy(t *testing.T) {
+	var evals, unused int
+	data := &lazyData{
+		Name:    func() string { return "gopher" },
+		Items:   func() []int { return []int{1, 2, 3} },
+		Inner:   func() *T { return tVal },
+		Fail:    func() (string, error) { return "", errors.New("boom") },
+		Counted: lazyCount{&evals, "counted"},
+		Unused:  func() string { unused++; return "unused" },
+	}
+	tests := []execTest{
+		{"print", "{{.Name}}", "gopher", data, true},
+		{"field of result", "{{.Inner.X}}", "x", data, true},
+		{"range", "{{range .Items}}{{.}}{{end}}", "123", data, true},
+		{"if", "{{if .Name}}yes{{end}}", "yes", data, true},
+		{"with", "{{with .Name}}{{.}}{{end}}", "gopher", data, true},
+		{"typed argument", "{{oneArg .Name}}", "oneArg=gopher", data, true},
+		{"call", "{{call .Name}}", "gopher", data, true},
+		{"interface", "{{.Counted}}", "counted", data, true},
+		{"map", "{{.F}}", "mapped", map[string]interface{}{"F": func() string { return "mapped" }}, true},
+		{"error", "{{.Fail}}", "", data, false},
+	}
+	testExecute(tests, nil, t)
+	if evals != 1 {
+		t.Errorf("Lazy evaluated %d times; expected 1", evals)
+	}
+	if unused != 0 {
+		t.Errorf("unused lazy field evaluated %d times", unused)
+	}
+}
+
+func TestLetLazy(t *testing.T) {
+	var evals int
+	data := map[string]interface{}{"V": lazyCount{&evals, "v"}}
+	tests := []execTest{
+		{"let lazy", "{{let $v := .V}}{{$v}}{{$v}}{{end}}", "vv", data, true},
+	}
+	testExecute(tests, nil, t)
+	if evals != 1 {
+		t.Errorf("Lazy evaluated %d times; expected 1", evals)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io"
+	"runtime"
+	"strings"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Lenient makes executions of the set continue after an error in an
+// action, such as a missing field or a function returning an error. The
+// failed action writes the placeholder instead of its output, and control
+// structures whose pipeline fails write it instead of their contents. The
+// errors are returned together as ExecErrors once the execution finishes.
+// It is meant for previews, where a partial page is more useful than none.
+//
+// The placeholder is written verbatim, without escaping. The return value
+// is the set, so calls can be chained.
+func (s *Set) Lenient(placeholder string) *Set {
+	s.lenient = true
+	s.placeholder = placeholder
+	return s
+}
+
+// ExecErrors holds the errors of a lenient execution, in the order they
+// occurred. See Set.Lenient.
+type ExecErrors []error
+
+func (e ExecErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// lenientNode returns whether an error in node is recovered by a lenient
+// execution.
+func lenientNode(node parse.Node) bool {
+	switch node.(type) {
+	case *parse.ActionNode, *parse.CustomNode, *parse.IfNode, *parse.LetNode,
+		*parse.RangeNode, *parse.TemplateNode, *parse.WithNode:
+		return true
+	}
+	return false
+}
+
+// recoverNode is deferred by a lenient execution to record an error in the
+// evaluation of a node and write the placeholder in place of its output.
+func (s *state) recoverNode() {
+	e := recover()
+	if e == nil {
+		return
+	}
+	err, ok := e.(error)
+	if _, fatal := e.(runtime.Error); !ok || fatal {
+		panic(e)
+	}
+	*s.errs = append(*s.errs, err)
+	if !s.dry {
+		io.WriteString(s.wr, s.snap.placeholder)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLenient(t *testing.T) {
+	set := Must(new(Set).Funcs(FuncMap{
+		"fail": func() (string, error) { return "", errors.New("failed") },
+	}).Lenient("[?]").Parse(`
+{{define "page"}}a{{.Missing}}b{{fail}}c{{range .Missing}}x{{end}}d{{template "part" .}}e{{end}}
+{{define "part"}}<{{.I}}{{.I.Bad}}>{{end}}`))
+	b := new(bytes.Buffer)
+	err := set.Execute(b, "page", tVal)
+	if want := "a[?]b[?]c[?]d<17[?]>e"; b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+	errs, ok := err.(ExecErrors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("expected 4 errors; got %v", err)
+	}
+	if !strings.Contains(errs[1].Error(), "failed") {
+		t.Errorf("unexpected error %q", errs[1])
+	}
+	// A successful execution returns no error.
+	set = Must(new(Set).Lenient("[?]").Parse(`{{define "ok"}}{{.I}}{{end}}`))
+	if err := set.Execute(new(bytes.Buffer), "ok", tVal); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+)
+
+// Limits bounds the templates parsed by a set, to protect services that
+// parse template bundles uploaded by users from exhausting memory. A zero
+// field sets no limit.
+type Limits struct {
+	// MaxTemplateSize is the maximum size in bytes of a text passed to
+	// Parse, ParseTemplate or ParseUntrusted, or of a file, after its
+	// includes are expanded. It also bounds the size of each included or
+	// imported file.
+	MaxTemplateSize int
+	// MaxTotalSize is the maximum total size in bytes of the texts parsed
+	// by the set, including the files imported with {{import}}.
+	MaxTotalSize int
+	// MaxFiles is the maximum number of files parsed by the set with
+	// ParseFiles, ParseGlob and {{import}}.
+	MaxFiles int
+}
+
+// parseUsage holds the totals checked against the limits of a set.
+type parseUsage struct {
+	bytes int // size of the texts parsed.
+	files int // number of files parsed.
+}
+
+// Limit sets the limits of the templates parsed by the set. Parsing a text
+// or a file that exceeds them is an error. The return value is the set, so
+// calls can be chained.
+func (s *Set) Limit(l Limits) *Set {
+	s.limits = l
+	return s
+}
+
+// checkLimits returns an error if parsing the named text, which is a file
+// if file is true, would exceed the limits of the set, and otherwise
+// accounts for it. The caller must hold the mutex.
+func (s *Set) checkLimits(name, text string, file bool) error {
+	l := s.limits
+	if l.MaxFiles > 0 && file && s.parsed.files >= l.MaxFiles {
+		return fmt.Errorf("template: %s: more than %d files parsed", name, l.MaxFiles)
+	}
+	if err := s.checkGrowth(name, len(text)); err != nil {
+		return err
+	}
+	s.parsed.bytes += len(text)
+	if file {
+		s.parsed.files++
+	}
+	return nil
+}
+
+// checkGrowth returns an error if a text of the given size, parsed under
+// the given name, would exceed the size limits of the set. It is also used
+// while includes are expanded, before the text is complete. The caller
+// must hold the mutex.
+func (s *Set) checkGrowth(name string, size int) error {
+	l := s.limits
+	if err := checkSize(name, size, l.MaxTemplateSize); err != nil {
+		return err
+	}
+	if l.MaxTotalSize > 0 && s.parsed.bytes+size > l.MaxTotalSize {
+		return fmt.Errorf("template: %s: more than %d bytes parsed in total", name, l.MaxTotalSize)
+	}
+	return nil
+}
+
+// checkSize returns an error if the size of the named template exceeds
+// max, unless max is zero.
+func checkSize(name string, size, max int) error {
+	if max > 0 && size > max {
+		return fmt.Errorf("template: %s: template is larger than %d bytes", name, max)
+	}
+	return nil
+}
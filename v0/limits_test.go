@@ -0,0 +1,117 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	const text = `{{define "t"}}hello{{end}}` // 26 bytes.
+	tests := []struct {
+		limits Limits
+		texts  int    // number of times the text is parsed.
+		err    string // error of the last parse, if any.
+	}{
+		{Limits{}, 3, ""},
+		{Limits{MaxTemplateSize: 26}, 1, ""},
+		{Limits{MaxTemplateSize: 25}, 1, "template is larger than 25 bytes"},
+		{Limits{MaxTotalSize: 52}, 2, ""},
+		{Limits{MaxTotalSize: 52}, 3, "more than 52 bytes parsed in total"},
+	}
+	for i, test := range tests {
+		s := new(Set).Limit(test.limits)
+		var err error
+		for j := 0; j < test.texts; j++ {
+			_, err = s.Parse(strings.Replace(text, `"t"`, `"`+string(rune('a'+j))+`"`, 1))
+		}
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%d: unexpected error: %s", i, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%d: got error %v, want %q", i, err, test.err)
+		}
+	}
+}
+
+func TestLimitFiles(t *testing.T) {
+	if _, err := new(Set).Limit(Limits{MaxFiles: 2}).ParseGlob("testdata/file*.tmpl"); err != nil {
+		t.Error(err)
+	}
+	_, err := new(Set).Limit(Limits{MaxFiles: 1}).ParseGlob("testdata/file*.tmpl")
+	if err == nil || !strings.Contains(err.Error(), "more than 1 files parsed") {
+		t.Errorf("got error %v, want too many files", err)
+	}
+	_, err = new(Set).Limit(Limits{MaxTemplateSize: 10}).ParseFiles("testdata/file1.tmpl")
+	if err == nil || !strings.Contains(err.Error(), "testdata/file1.tmpl: template is larger than 10 bytes") {
+		t.Errorf("got error %v, want file too large", err)
+	}
+}
+
+func TestLimitImports(t *testing.T) {
+	const text = `{{import "testdata/import/helpers.tmpl" as h}}{{define "t"}}{{h.button .}}{{end}}`
+	tests := []struct {
+		limits Limits
+		err    string
+	}{
+		{Limits{}, ""},
+		{Limits{MaxTemplateSize: 90}, "testdata/import/helpers.tmpl: template is larger than 90 bytes"},
+		{Limits{MaxTotalSize: 150}, "testdata/import/helpers.tmpl: more than 150 bytes parsed in total"},
+		{Limits{MaxFiles: 1}, ""},
+	}
+	for i, test := range tests {
+		_, err := new(Set).Limit(test.limits).Parse(text)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%d: unexpected error: %s", i, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%d: got error %v, want %q", i, err, test.err)
+		}
+	}
+	s := new(Set).Limit(Limits{MaxFiles: 1})
+	_, err := s.ParseFiles("testdata/file1.tmpl")
+	if err == nil {
+		_, err = s.Parse(text)
+	}
+	if err == nil || !strings.Contains(err.Error(), "more than 1 files parsed") {
+		t.Errorf("got error %v, want too many files", err)
+	}
+}
+
+func TestLimitIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Each file includes the next one twice, so that the expanded text
+	// doubles with each level.
+	const levels = 40
+	for i := 0; i < levels; i++ {
+		text := "x"
+		if i+1 < levels {
+			next := filepath.ToSlash(filepath.Join(dir, fmt.Sprint(i+1)))
+			text = fmt.Sprintf("{{include %q}}{{include %q}}", next, next)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprint(i)), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := filepath.ToSlash(filepath.Join(dir, "0"))
+	text := fmt.Sprintf(`{{define "t"}}{{include %q}}{{end}}`, first)
+	_, err = new(Set).Limit(Limits{MaxTemplateSize: 10000}).Parse(text)
+	if err == nil || !strings.Contains(err.Error(), "template is larger than 10000 bytes") {
+		t.Errorf("got error %v, want template too large", err)
+	}
+	_, err = new(Set).Limit(Limits{MaxTotalSize: 10000}).Parse(text)
+	if err == nil || !strings.Contains(err.Error(), "more than 10000 bytes parsed in total") {
+		t.Errorf("got error %v, want too many bytes", err)
+	}
+}
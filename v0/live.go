@@ -0,0 +1,188 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// LiveRender renders a template repeatedly with data that changes a little
+// each time, as in the live preview of an editor. It remembers the output
+// of each top-level part of the template and the fields of the data the
+// part used, and only executes again the parts whose fields changed.
+//
+// LiveRender is experimental. Functions are assumed to return the same
+// results given the same arguments, and parts that use values that don't
+// come from fields, such as constants or the results of functions followed
+// by fields, are always executed. A LiveRender can't be used concurrently.
+type LiveRender struct {
+	snap  *Snapshot
+	tmpl  *parse.DefineNode
+	parts []*livePart
+	data  reflect.Value
+	out   bytes.Buffer
+	count int // number of parts executed by the last Render.
+}
+
+// livePart is a top-level part of a live rendered template.
+type livePart struct {
+	node   parse.Node
+	output []byte
+	fields []string // fields used by the last execution.
+	always bool     // whether the part must always be executed.
+}
+
+// LiveRender compiles the set and returns a LiveRender for the named
+// template. Like a prepared template, it keeps using the templates current
+// when it was created.
+func (s *Set) LiveRender(name string) (*LiveRender, error) {
+	p, err := s.Prepare(name)
+	if err != nil {
+		return nil, err
+	}
+	r := &LiveRender{snap: p.snap, tmpl: p.tmpl}
+	var split func(l *parse.ListNode)
+	split = func(l *parse.ListNode) {
+		for _, n := range l.Nodes {
+			if l, ok := n.(*parse.ListNode); ok {
+				// Such as the contents of a fill.
+				split(l)
+				continue
+			}
+			r.parts = append(r.parts, &livePart{node: n, always: true})
+		}
+	}
+	split(r.tmpl.List)
+	return r, nil
+}
+
+// Render executes the parts of the template affected by the differences
+// between data and the data of the previous call, all of them the first
+// time, and returns the output of the whole template. The returned slice
+// is only valid until the next call.
+func (r *LiveRender) Render(data interface{}) (out []byte, err error) {
+	defer func() {
+		if err != nil {
+			// Start over.
+			for _, part := range r.parts {
+				part.always = true
+			}
+		}
+	}()
+	defer errRecover(&err)
+	value := reflect.ValueOf(data)
+	snap := *r.snap
+	s := &state{
+		snap: &snap,
+		tmpl: r.tmpl,
+		vars: []variable{{"$", value, ""}},
+	}
+	r.count = 0
+	r.out.Reset()
+	for _, part := range r.parts {
+		if part.always || r.changed(part.fields, value) {
+			var b bytes.Buffer
+			rec := new(AccessRecorder)
+			snap.recorder = rec
+			s.wr = newLineWriter(&b, snap.lineEnding)
+			s.walk(value, part.node)
+			if err := flushLines(s.wr); err != nil {
+				return nil, err
+			}
+			part.output = b.Bytes()
+			part.fields = rec.Fields()
+			part.always = false
+			switch n := part.node.(type) {
+			case *parse.ActionNode:
+				// The variables are needed by the parts that follow.
+				part.always = len(n.Pipe.Decl) > 0
+			case *parse.CaptureNode:
+				part.always = true
+			}
+			for _, f := range part.fields {
+				if strings.HasPrefix(f, unknownPath) {
+					part.always = true
+				}
+			}
+			r.count++
+		}
+		r.out.Write(part.output)
+	}
+	r.data = value
+	return r.out.Bytes(), nil
+}
+
+// WriteTo writes the output of the last call to Render to w.
+func (r *LiveRender) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(r.out.Bytes())
+	return int64(n), err
+}
+
+// changed returns whether one of the fields has a different value in data
+// than in the previous data. Fields iterated by {{range}} are compared as a
+// whole.
+func (r *LiveRender) changed(fields []string, data reflect.Value) bool {
+	for _, f := range fields {
+		if i := strings.Index(f, "[]"); i >= 0 {
+			f = f[:i]
+		}
+		a, okA := lookupPath(r.data, f)
+		b, okB := lookupPath(data, f)
+		if !okA || !okB || !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupPath returns the value of the field with the given path in v, as
+// evaluated by a template, and whether it could be found.
+func lookupPath(v reflect.Value, path string) (reflect.Value, bool) {
+	if path == "" {
+		return v, v.IsValid() && v.CanInterface()
+	}
+	for _, name := range strings.Split(path, ".") {
+		if !v.IsValid() {
+			return v, false
+		}
+		if v.Kind() != reflect.Interface && v.CanAddr() {
+			v = v.Addr()
+		}
+		if m := v.MethodByName(name); m.IsValid() {
+			if m.Type().NumIn() != 0 || m.Type().NumOut() == 0 {
+				return v, false
+			}
+			v = m.Call(nil)[0]
+			continue
+		}
+		v, _ = indirect(v)
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := v.Type().FieldByName(name)
+			if !ok || f.PkgPath != "" {
+				return v, false
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Map:
+			key := reflect.ValueOf(name)
+			if !key.Type().AssignableTo(v.Type().Key()) {
+				return v, false
+			}
+			v = v.MapIndex(key)
+		default:
+			return v, false
+		}
+	}
+	if !v.IsValid() {
+		// A missing key in a map.
+		return reflect.ValueOf((*struct{})(nil)), true
+	}
+	return v, v.CanInterface()
+}
@@ -0,0 +1,52 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLiveRender(t *testing.T) {
+	type doc struct {
+		Title string
+		Body  string
+		Tags  []string
+	}
+	set := Must(new(Set).Parse(`
+{{define "layout"}}<h1>{{slot "title"}}{{end}}</h1>{{slot "main"}}{{end}}{{end}}
+{{define "doc" "layout"}}{{fill "title"}}{{.Title}}{{end}}{{fill "main"}}<p>{{.Body}}</p>{{$n := len .Tags}}{{range .Tags}}[{{.}}]{{end}}{{$n}}{{end}}{{end}}`))
+	r, err := set.LiveRender("doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		data   doc
+		output string
+		count  int
+	}{
+		{doc{"A", "x", []string{"t"}}, "<h1>A</h1><p>x</p>[t]1", 9},
+		// Nothing changed: only the declaration and its use run.
+		{doc{"A", "x", []string{"t"}}, "<h1>A</h1><p>x</p>[t]1", 2},
+		{doc{"B", "x", []string{"t"}}, "<h1>B</h1><p>x</p>[t]1", 3},
+		{doc{"B", "y", []string{"t", "u"}}, "<h1>B</h1><p>y</p>[t][u]2", 4},
+	}
+	for i, test := range tests {
+		out, err := r.Render(test.data)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if string(out) != test.output {
+			t.Errorf("%d: expected %q, got %q", i, test.output, out)
+		}
+		if r.count != test.count {
+			t.Errorf("%d: expected %d parts executed, got %d", i, test.count, r.count)
+		}
+		var b bytes.Buffer
+		if _, err := r.WriteTo(&b); err != nil || b.String() != test.output {
+			t.Errorf("%d: WriteTo wrote %q, %v", i, b.String(), err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gorilla/template/v0/escape"
+	"github.com/gorilla/template/v0/parse"
+)
+
+// LogLevel is the severity of a message logged by a set.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = map[LogLevel]string{
+	LogDebug: "DEBUG",
+	LogInfo:  "INFO",
+	LogWarn:  "WARN",
+	LogError: "ERROR",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// Logger receives the messages logged by a set. It must be safe to call
+// concurrently.
+type Logger interface {
+	Log(level LogLevel, msg string)
+}
+
+// LoggerFunc is an adapter to use a function as a Logger.
+type LoggerFunc func(level LogLevel, msg string)
+
+// Log calls f(level, msg).
+func (f LoggerFunc) Log(level LogLevel, msg string) {
+	f(level, msg)
+}
+
+// logConfig holds the logging options of a set.
+type logConfig struct {
+	logger Logger
+	level  LogLevel      // minimum level of the messages logged.
+	slow   time.Duration // minimum duration of the executions logged.
+}
+
+// logf logs a message if it has at least the configured level.
+func (c logConfig) logf(level LogLevel, format string, args ...interface{}) {
+	if c.logger != nil && level >= c.level {
+		c.logger.Log(level, fmt.Sprintf(format, args...))
+	}
+}
+
+// Logger sets the logger that receives the messages of the set with at
+// least the given level. By default nothing is logged. Messages include:
+//
+//   - warnings for problems found when the set is compiled that don't
+//     prevent it from executing: calls to undefined templates, which fail
+//     only if executed, and fills that match no slot;
+//   - warnings for actions that write unquoted attribute values, as in
+//     <a title={{.}}>, when the set is escaped;
+//   - warnings for slow executions, see LogSlowExecutions.
+//
+// The return value is the set, so calls can be chained.
+func (s *Set) Logger(logger Logger, level LogLevel) *Set {
+	s.log.logger, s.log.level = logger, level
+	return s
+}
+
+// LogSlowExecutions makes the set log a warning for each execution that
+// takes at least the given duration. The return value is the set, so calls
+// can be chained.
+func (s *Set) LogSlowExecutions(threshold time.Duration) *Set {
+	s.log.slow = threshold
+	return s
+}
+
+// lint logs warnings for the problems in the tree that don't prevent it
+// from compiling. It runs before inlining.
+func lint(tree parse.Tree, log logConfig) {
+	if log.logger == nil || log.level > LogWarn {
+		return
+	}
+	for _, problem := range undefinedTemplates(tree, nil) {
+		log.logf(LogWarn, "template: %s", problem)
+	}
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		define := tree[name]
+		if define.Parent == "" {
+			continue
+		}
+		chain, err := parentList(tree, define.Parent)
+		if err != nil {
+			continue
+		}
+		slots := make(map[string]bool)
+		for _, slot := range chainSlots(tree, chain) {
+			slots[slot] = true
+		}
+		for _, n := range define.List.Nodes {
+			if f, ok := n.(*parse.FillNode); ok && !slots[f.Name] {
+				location, _ := define.ErrorContext(f)
+				log.logf(LogWarn, "template: %s: fill %q matches no slot of %q", location, f.Name, define.Parent)
+			}
+		}
+	}
+}
+
+// lintEscaping logs warnings for the problems found by the escaper in the
+// inlined tree that don't prevent it from being escaped.
+func lintEscaping(tree parse.Tree, log logConfig) {
+	if log.logger == nil || log.level > LogWarn {
+		return
+	}
+	attrs, err := escape.FindUnquotedAttrs(tree)
+	if err != nil {
+		// Reported by escaping.
+		return
+	}
+	for _, attr := range attrs {
+		location := attr.Template
+		if define := tree[attr.Template]; define != nil {
+			location, _ = define.ErrorContext(attr.Action)
+		}
+		log.logf(LogWarn, "template: %s: %s writes an unquoted attribute value; quote it", location, attr.Action)
+	}
+}
+
+// logSlow logs a warning if an execution of the named template that
+// started at the given time was slow.
+func (c logConfig) logSlow(name string, start time.Time) {
+	if d := time.Since(start); d >= c.slow {
+		c.logf(LogWarn, "template: slow execution of %q: %s", name, d)
+	}
+}
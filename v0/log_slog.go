@@ -0,0 +1,28 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package template
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger returns a Logger that logs to l, with the matching slog
+// levels.
+func SlogLogger(l *slog.Logger) Logger {
+	return LoggerFunc(func(level LogLevel, msg string) {
+		l.Log(context.Background(), slogLevels[level], msg)
+	})
+}
+
+var slogLevels = map[LogLevel]slog.Level{
+	LogDebug: slog.LevelDebug,
+	LogInfo:  slog.LevelInfo,
+	LogWarn:  slog.LevelWarn,
+	LogError: slog.LevelError,
+}
@@ -0,0 +1,92 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logRecorder is a Logger that records the messages.
+type logRecorder struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *logRecorder) Log(level LogLevel, msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, level.String()+" "+msg)
+}
+
+func TestLogger(t *testing.T) {
+	const text = `
+{{define "page"}}{{slot "body"}}{{end}}{{end}}
+{{define "home" "page"}}{{fill "body"}}{{template "missing"}}{{end}}{{fill "sidebar"}}{{end}}{{end}}`
+	r := &logRecorder{}
+	if _, err := Must(new(Set).Logger(r, LogInfo).Parse(text)).Compile(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`WARN template: home:3:50: no such template "missing"`,
+		`WARN template: home:3:75: fill "sidebar" matches no slot of "page"`,
+	}
+	if !reflect.DeepEqual(r.msgs, want) {
+		t.Errorf("got messages\n%q\nwant\n%q", r.msgs, want)
+	}
+	// Warnings are not logged above their level.
+	r = &logRecorder{}
+	if _, err := Must(new(Set).Logger(r, LogError).Parse(text)).Compile(); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.msgs) != 0 {
+		t.Errorf("got messages %q, want none", r.msgs)
+	}
+}
+
+func TestLogSlowExecutions(t *testing.T) {
+	r := &logRecorder{}
+	s, err := new(Set).Logger(r, LogWarn).LogSlowExecutions(time.Hour).
+		Parse(`{{define "t"}}hello{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Execute(ioutil.Discard, "t", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.msgs) != 0 {
+		t.Errorf("got messages %q, want none", r.msgs)
+	}
+	s.LogSlowExecutions(time.Nanosecond)
+	if err := s.Execute(ioutil.Discard, "t", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.msgs) != 1 {
+		t.Fatalf("got messages %q, want one", r.msgs)
+	}
+	if want := `WARN template: slow execution of "t": `; len(r.msgs[0]) < len(want) || r.msgs[0][:len(want)] != want {
+		t.Errorf("got message %q, want prefix %q", r.msgs[0], want)
+	}
+}
+
+func TestLogUnquotedAttrs(t *testing.T) {
+	r := &logRecorder{}
+	s := Must(new(Set).Escape().Logger(r, LogWarn).Parse(`
+{{define "a"}}<a b=1 c={{.}} title="{{.}}">{{template "b" .}}{{end}}
+{{define "b"}}<img alt={{.}}>{{end}}`))
+	if _, err := s.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`WARN template: a:2:25: {{.}} writes an unquoted attribute value; quote it`,
+		`WARN template: b:3:25: {{.}} writes an unquoted attribute value; quote it`,
+	}
+	if !reflect.DeepEqual(r.msgs, want) {
+		t.Errorf("got messages\n%q\nwant\n%q", r.msgs, want)
+	}
+}
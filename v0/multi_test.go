@@ -9,7 +9,13 @@ package template
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gorilla/template/v0/parse"
@@ -273,3 +279,615 @@ func TestRedefinition(t *testing.T) {
 		t.Fatalf("expected redefinition error; got %v", err)
 	}
 }
+
+func TestStub(t *testing.T) {
+	set := Must(new(Set).Escape().Parse(`
+	{{define "page"}}<p>{{template "heavy" .}}</p><a title="{{template "heavy" .}}">{{end}}
+	{{define "heavy"}}{{.}}{{end}}`))
+	restore, err := set.Stub("heavy", "STUB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "page", "<real>"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), `<p>STUB</p><a title="STUB">`; got != want {
+		t.Errorf("stubbed: expected %q, got %q", want, got)
+	}
+	restore()
+	b.Reset()
+	if err := set.Execute(b, "page", "<real>"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), `<p>&lt;real&gt;</p><a title="&lt;real&gt;">`; got != want {
+		t.Errorf("restored: expected %q, got %q", want, got)
+	}
+	if _, err := set.Stub("missing", ""); err == nil {
+		t.Errorf("expected error stubbing undefined template")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	set := Must(new(Set).Parse(`
+{{define "base"}}[{{slot "body"}}{{end}}]{{end}}
+{{define "page" "base"}}{{fill "body"}}{{template "header"}}{{end}}{{end}}
+{{define "header"}}h{{template "logo"}}{{end}}
+{{define "logo"}}l{{end}}
+{{define "unused"}}u{{template "logo"}}{{end}}
+{{define "page@v2"}}{{template "footer"}}{{end}}
+{{define "footer"}}f{{end}}
+`))
+	snap, err := set.Escape().Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Prune("page"); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range set.tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expect := []string{"footer", "header", "logo", "page", "page@v2"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("expected templates %v; got %v", expect, names)
+	}
+	// Snapshots taken before keep all templates.
+	if err := snap.Execute(new(bytes.Buffer), "unused", nil); err != nil {
+		t.Errorf("unexpected error executing a snapshot: %v", err)
+	}
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "page", nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[hl]" {
+		t.Errorf("expected %q; got %q", "[hl]", b.String())
+	}
+	if _, err := set.Prune("missing"); err == nil {
+		t.Errorf("expected error pruning from missing root")
+	}
+}
+
+func TestRetainSource(t *testing.T) {
+	text := `{{define "t"}}` + strings.Repeat("x", 1000) + "\n\nabc{{.Missing}}{{end}}"
+	for _, test := range []struct {
+		policy   SourcePolicy
+		location string
+	}{
+		{SourceKeep, `template: t:3:5: executing "t"`},
+		{SourceDrop, `template: t: executing "t"`},
+		{SourceLines, `template: t:3:5: executing "t"`},
+	} {
+		set := Must(new(Set).RetainSource(test.policy).Parse(text))
+		before := set.Size()
+		if before < len(text) {
+			t.Errorf("%d: size %d smaller than the text", test.policy, before)
+		}
+		err := set.Execute(ioutil.Discard, "t", 1)
+		if err == nil {
+			t.Errorf("%d: expected error", test.policy)
+		} else if !strings.HasPrefix(err.Error(), test.location) {
+			t.Errorf("%d: expected error starting with %q; got %q", test.policy, test.location, err)
+		}
+		after := set.Size()
+		if test.policy != SourceKeep && after > before-len(text)/2 {
+			t.Errorf("%d: size %d not reduced from %d after dropping text", test.policy, after, before)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	set := Must(new(Set).Parse(`{{define "a"}}{{template "b"}}{{end}}{{define "b"}}b{{end}}`))
+	if err := set.Verify(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	set = Must(new(Set).Parse(`{{define "a"}}{{template "x"}}
+{{if .}}{{template "y"}}{{end}}{{end}}
+{{define "b" "z"}}{{fill "f"}}{{template "x"}}{{end}}{{end}}`))
+	expect := `template: undefined templates:
+	a:1:25: no such template "x"
+	a:2:19: no such template "y"
+	b: extends undefined template "z"
+	b:3:41: no such template "x"`
+	if err := set.Verify(); err == nil {
+		t.Errorf("expected error")
+	} else if err.Error() != expect {
+		t.Errorf("expected error\n%s\ngot\n%s", expect, err)
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{{define "a"}}{{template "a"}}{{end}}`, `template: infinite recursion: a -> a`},
+		{`{{define "a"}}x{{template "b"}}{{end}}{{define "b"}}{{let $x := 1}}{{template "a"}}{{end}}{{end}}`,
+			`template: infinite recursion: a -> b -> a`},
+		{`{{define "a"}}{{if true}}{{template "a"}}{{end}}{{end}}`, `template: infinite recursion: a -> a`},
+		{`{{define "a"}}{{if .}}{{template "a" .Next}}{{end}}{{end}}`, ``},
+		{`{{define "a"}}{{range .}}{{template "a" .}}{{end}}{{end}}`, ``},
+		{`{{define "a"}}{{template "b"}}{{template "b"}}{{end}}{{define "b"}}b{{end}}`, ``},
+	}
+	for _, test := range tests {
+		_, err := Must(new(Set).Parse(test.input)).Compile()
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.err {
+			t.Errorf("%s: expected error %q; got %q", test.input, test.err, got)
+		}
+	}
+}
+
+func TestAutoDefine(t *testing.T) {
+	set, err := new(Set).AutoDefine().ParseFiles("testdata/auto.tmpl", "testdata/file1.tmpl", "testdata/file2.tmpl")
+	if err != nil {
+		t.Fatalf("error parsing files: %v", err)
+	}
+	// Files with defines are parsed as usual.
+	if set.tree["testdata/file1.tmpl"] != nil {
+		t.Errorf("file with defines was wrapped in a template")
+	}
+	testExecute(multiExecTests, set, t)
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "testdata/auto.tmpl", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "auto x!\n" {
+		t.Errorf("expected %q; got %q", "auto x!\n", b.String())
+	}
+	// Without the option actions outside defines are an error.
+	if _, err := new(Set).ParseFiles("testdata/auto.tmpl"); err == nil {
+		t.Errorf("expected error parsing file without defines")
+	}
+	// Parse doesn't name templates.
+	set = Must(new(Set).AutoDefine().Parse("no define"))
+	if len(set.tree) != 0 {
+		t.Errorf("expected no templates; got %d", len(set.tree))
+	}
+}
+
+func TestFileInheritance(t *testing.T) {
+	set, err := new(Set).AutoDefine().FileInheritance().ParseGlob("testdata/inherit/*.html")
+	if err != nil {
+		t.Fatalf("error parsing files: %v", err)
+	}
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{"testdata/inherit/layout.html", "[base]"},
+		{"testdata/inherit/article.html", "[article]"},
+		{"testdata/inherit/news.html", "[news]"},
+	}
+	for _, test := range tests {
+		b := new(bytes.Buffer)
+		if err := set.Execute(b, test.name, "news"); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else if b.String() != test.output {
+			t.Errorf("%s: expected %q; got %q", test.name, test.output, b.String())
+		}
+	}
+}
+
+func TestExtends(t *testing.T) {
+	set := Must(new(Set).Parse(`
+{{define "layout"}}[{{slot "body"}}base{{end}}]{{end}}
+{{define "page"}}
+	{{extends "layout"}}
+	{{fill "body"}}page{{end}}
+{{end}}`))
+	Must(set.ParseTemplate("news", `{{extends "page"}}{{fill "body"}}{{.}}{{end}}`))
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{"page", "[page]"},
+		{"news", "[news]"},
+	}
+	for _, test := range tests {
+		b := new(bytes.Buffer)
+		if err := set.Execute(b, test.name, "news"); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else if b.String() != test.output {
+			t.Errorf("%s: expected %q; got %q", test.name, test.output, b.String())
+		}
+	}
+}
+
+func TestSyntaxJinja(t *testing.T) {
+	set := new(Set).Syntax(SyntaxJinja).Escape().Funcs(FuncMap{"upper": strings.ToUpper})
+	Must(set.ParseTemplate("base.html", `<title>{% block title %}Site{% endblock %}</title>
+{%- block body %}{% endblock %}`))
+	Must(set.ParseTemplate("item.html", `<li>{{ item|upper }}</li>`))
+	Must(set.ParseTemplate("page.html", `{% extends "base.html" %}
+{% block title %}{{ title }}{% endblock %}
+{% block body %}<ul>{% for item in items %}{% include "item.html" %}{% endfor %}</ul>{% endblock %}`))
+	b := new(bytes.Buffer)
+	data := map[string]interface{}{"title": "a<b", "items": []string{"x"}, "item": "y"}
+	if err := set.Execute(b, "page.html", data); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<title>a&lt;b</title><ul><li>Y</li></ul>"; b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+	if _, err := new(Set).Syntax(SyntaxJinja).Parse("x"); err == nil {
+		t.Errorf("expected error parsing without a template name")
+	}
+}
+
+func TestSyntaxMustache(t *testing.T) {
+	set := new(Set).Syntax(SyntaxMustache).Escape()
+	Must(set.ParseTemplate("item", `<li>{{name}}</li>`))
+	Must(set.ParseTemplate("page", `<ul>
+{{#items}}
+{{> item}}
+{{/items}}
+{{^items}}
+<li>none</li>
+{{/items}}
+</ul>{{#user}}{{{bio}}}{{/user}}`))
+	tests := []struct {
+		data   interface{}
+		output string
+	}{
+		{map[string]interface{}{
+			"items": []map[string]string{{"name": "a<b"}, {"name": "c"}},
+			"user":  map[string]string{"bio": "<b>hi</b>"},
+		}, "<ul>\n<li>a&lt;b</li><li>c</li></ul><b>hi</b>"},
+		{map[string]interface{}{"items": []int{}, "user": false}, "<ul>\n<li>none</li>\n</ul>"},
+	}
+	for _, test := range tests {
+		b := new(bytes.Buffer)
+		if err := set.Execute(b, "page", test.data); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.output {
+			t.Errorf("expected %q; got %q", test.output, b.String())
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	set, err := new(Set).Parse(`{{import "testdata/import/helpers.tmpl" as h}}
+{{define "button"}}[{{.}}]{{end}}
+{{define "page"}}{{template "button" "a"}}{{h.button "OK"}}{{h.icon}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err = set.Execute(b, "page", nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[a]<button>*OK</button>*"; b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+	// Aliases are local to the text that declares them.
+	if _, err = set.Parse(`{{define "other"}}{{h.button "OK"}}{{end}}`); err == nil {
+		t.Errorf("expected error using an undeclared alias")
+	}
+	if _, err = new(Set).Parse(`{{import "testdata/import/missing.tmpl" as h}}{{define "x"}}{{h.b}}{{end}}`); err == nil {
+		t.Errorf("expected error importing a missing file")
+	}
+}
+
+func TestSetConstants(t *testing.T) {
+	set := Must(new(Set).Funcs(FuncMap{"upper": strings.ToUpper}).Parse(`
+{{set $SITE_NAME := "Acme"}}
+{{set $TITLE := upper $SITE_NAME | printf "%s!"}}
+{{set $DEBUG := false}}
+{{define "page"}}{{$TITLE}} {{template "footer" .}}{{if $DEBUG}}{{.Missing.Field}}{{end}}{{end}}`))
+	Must(set.Parse(`{{define "footer"}}(c) {{$SITE_NAME}} {{.}}{{end}}`))
+	if names := set.Names(); !reflect.DeepEqual(names, []string{"footer", "page"}) {
+		t.Errorf("unexpected names %q", names)
+	}
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "page", 2011); err != nil {
+		t.Fatal(err)
+	}
+	if want := "ACME! (c) Acme 2011"; b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+	// The dead branch was removed.
+	if strings.Contains(set.tree["page"].String(), "Missing") {
+		t.Errorf("branch of a false constant was not removed")
+	}
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"self reference", `{{set $A := $B}}{{set $B := $A}}`},
+		{"undefined constant", `{{set $A := $B}}`},
+		{"execution error", `{{set $A := index 1 2}}`},
+	}
+	for _, test := range tests {
+		set, err := new(Set).Parse(test.text)
+		if err != nil {
+			t.Errorf("%s: unexpected parse error: %v", test.name, err)
+		} else if _, err = set.Compile(); err == nil {
+			t.Errorf("%s: expected compile error", test.name)
+		}
+	}
+	for _, text := range []string{
+		`{{set $lower := 1}}`,
+		`{{set $A.B := 1}}`,
+		`{{set $A := 1}}{{set $A := 2}}`,
+		`{{define "x"}}{{set $A := 1}}{{end}}`,
+		`{{define "x"}}{{$a}}{{end}}`,
+	} {
+		if _, err := new(Set).Parse(text); err == nil {
+			t.Errorf("%s: expected parse error", text)
+		}
+	}
+	// Variables named like constants can still be declared, and hide the
+	// constants; using one that is neither is an error when compiling.
+	set = Must(new(Set).Parse(`{{set $DEBUG := true}}
+{{define "local"}}{{$X := 1}}{{$X}}{{$DEBUG := false}}{{if $DEBUG}}debug{{end}}{{end}}
+{{define "capture"}}{{capture $DEBUG}}{{end}}{{if $DEBUG}}debug{{end}}{{end}}
+{{define "range"}}{{range $DEBUG := .}}{{if $DEBUG}}{{$DEBUG}}{{end}}{{end}}{{end}}`))
+	for name, want := range map[string]string{"local": "1", "capture": "", "range": "ab"} {
+		b.Reset()
+		if err := set.Execute(b, name, []string{"a", "", "b"}); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		} else if b.String() != want {
+			t.Errorf("%s: expected %q; got %q", name, want, b.String())
+		}
+	}
+	set = Must(new(Set).Parse(`{{set $DEBUG := true}}{{define "x"}}{{if $DEBGU}}debug{{end}}{{end}}`))
+	if _, err := set.Compile(); err == nil || err.Error() != `template: x:1:41: undefined variable "$DEBGU"` {
+		t.Errorf("expected undefined variable error; got %v", err)
+	}
+}
+
+func TestKeywordFuncs(t *testing.T) {
+	// A function named like a contextual keyword is called instead.
+	set := Must(new(Set).Funcs(FuncMap{
+		"set":     func(k, v string) string { return k + "=" + v },
+		"capture": strings.ToUpper,
+	}).Parse(`{{define "x"}}{{set "a" "b"}} {{.}}{{end}}{{define "y"}}{{.X | capture}}{{end}}`))
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "x", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a=b c"; b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+	b.Reset()
+	if err := set.Execute(b, "y", map[string]string{"X": "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "D"; b.String() != want {
+		t.Errorf("expected %q; got %q", want, b.String())
+	}
+}
+
+func TestDiff(t *testing.T) {
+	const base = `
+{{define "base"}}[{{slot "body"}}{{end}}]{{end}}
+{{define "page" "base"}}{{fill "body"}}{{template "header"}}{{end}}{{end}}
+{{define "header"}}h{{template "logo"}}{{end}}
+{{define "logo"}}l{{end}}
+{{define "other"}}o{{end}}
+`
+	for _, test := range []struct {
+		old, new string
+		expect   []string
+	}{
+		{"", "", []string{}},
+		{`l{{end}}`, `L{{end}}`, []string{"header", "logo", "page"}},
+		{`[{{slot`, `<{{slot`, []string{"base", "page"}},
+		{"\n", `{{define "new"}}n{{end}}`, []string{"new"}},
+		{`o{{end}}`, `o{{template "logo"}}{{end}}`, []string{"other"}},
+		{"\n", `{{set $X := 1}}`, []string{"base", "header", "logo", "other", "page"}},
+	} {
+		old := Must(new(Set).Parse(base))
+		changed := Must(new(Set).Parse(strings.Replace(base, test.old, test.new, 1)))
+		names, err := Diff(old, changed)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.new, err)
+			continue
+		}
+		if !reflect.DeepEqual(names, test.expect) {
+			t.Errorf("%q: expected %v; got %v", test.new, test.expect, names)
+		}
+	}
+	if _, err := Diff(new(Set), Must(new(Set).Parse(`{{define "a" "missing"}}{{end}}`))); err == nil {
+		t.Errorf("expected error for a set that doesn't compile")
+	}
+}
+
+func TestHash(t *testing.T) {
+	const text = `
+{{define "page"}}p{{template "logo" .X}}{{end}}
+{{define "logo"}}l{{ .  }}{{end}}
+{{define "other"}}o{{end}}
+`
+	hash := func(text string) string {
+		h, err := Must(new(Set).Parse(text)).Hash("page")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	h := hash(text)
+	if len(h) != 64 {
+		t.Errorf("expected a hexadecimal SHA-256 hash; got %q", h)
+	}
+	for _, test := range []struct {
+		old, new string
+		same     bool
+	}{
+		{`{{ .  }}`, `{{.}}`, true},
+		{`o{{end}}`, `O{{end}}`, true},
+		{`{{template "logo" .X}}`, `{{template "logo" .Y}}`, false},
+		{`l{{`, `L{{`, false},
+		{"\n", "{{set $X := 1}}", false},
+	} {
+		if same := hash(strings.Replace(text, test.old, test.new, 1)) == h; same != test.same {
+			t.Errorf("%q: expected same hash to be %v", test.new, test.same)
+		}
+	}
+	if _, err := Must(new(Set).Parse(text)).Hash("missing"); err == nil {
+		t.Errorf("expected error for a missing template")
+	}
+}
+
+func TestAddPass(t *testing.T) {
+	var messages []string
+	extract := CompilePassFunc(func(tree parse.Tree) error {
+		for _, define := range tree {
+			for _, n := range define.List.Nodes {
+				if text, ok := n.(*parse.TextNode); ok {
+					messages = append(messages, string(text.Text))
+				}
+			}
+		}
+		return nil
+	})
+	upper := CompilePassFunc(func(tree parse.Tree) error {
+		for _, define := range tree {
+			for _, n := range define.List.Nodes {
+				if text, ok := n.(*parse.TextNode); ok {
+					text.Text = bytes.ToUpper(text.Text)
+				}
+			}
+		}
+		return nil
+	})
+	set := Must(new(Set).Parse(`{{define "a"}}<b>{{.}}</b>{{end}}`)).AddPass(extract).AddPass(upper).Escape()
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "a", "<x>"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "<B>&lt;x&gt;</B>"; got != want {
+		t.Errorf("expected %q; got %q", want, got)
+	}
+	if want := []string{"<b>", "</b>"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("expected messages %q; got %q", want, messages)
+	}
+	fail := CompilePassFunc(func(tree parse.Tree) error {
+		return fmt.Errorf("pass failed")
+	})
+	set = Must(new(Set).Parse(`{{define "a"}}a{{end}}`)).AddPass(fail)
+	if _, err := set.Compile(); err == nil || err.Error() != "pass failed" {
+		t.Errorf("expected error from the pass; got %v", err)
+	}
+}
+
+func TestCompileOnce(t *testing.T) {
+	var mutex sync.Mutex
+	runs := 0
+	count := CompilePassFunc(func(tree parse.Tree) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		runs++
+		return nil
+	})
+	set := Must(new(Set).Parse(`{{define "a"}}<b>{{.}}</b>{{end}}`)).AddPass(count).Escape()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := set.Execute(ioutil.Discard, "a", "x"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if runs != 1 {
+		t.Errorf("expected the set to be compiled once; got %d", runs)
+	}
+
+	// Failures are returned again without recompiling. The escaper
+	// fails after the pass ran.
+	runs = 0
+	set = Must(new(Set).Parse(`{{define "a"}}<a {{if .}}href{{end}}>{{end}}`)).AddPass(count).Escape()
+	_, err1 := set.Compile()
+	err2 := set.Execute(ioutil.Discard, "a", nil)
+	if err1 == nil || err2 == nil || err1.Error() != err2.Error() {
+		t.Errorf("expected the same error twice; got %v and %v", err1, err2)
+	}
+	if runs != 1 {
+		t.Errorf("expected the set to be compiled once; got %d", runs)
+	}
+	if _, err := set.Parse(`{{define "b"}}b{{end}}`); err == nil {
+		t.Errorf("expected error adding templates after a failed compilation")
+	}
+}
+
+func TestParseGlobErrors(t *testing.T) {
+	_, err := new(Set).ParseGlob("testdata/broken/*.tmpl")
+	errs, ok := err.(ParseErrors)
+	if !ok {
+		t.Fatalf("expected ParseErrors; got %v", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors; got %d: %v", len(errs), errs)
+	}
+	for i, name := range []string{"b.tmpl", "c.tmpl"} {
+		want, _ := filepath.Abs(filepath.Join("testdata", "broken", name))
+		if errs[i].Path != want {
+			t.Errorf("error %d: got path %q, want %q", i, errs[i].Path, want)
+		}
+		if !strings.HasPrefix(errs[i].Error(), want+": ") {
+			t.Errorf("error %d: got %q, want the path as prefix", i, errs[i])
+		}
+	}
+}
+
+func TestFilePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"page.html":        `{{define "page"}}page{{end}}`,
+		"notes.txt":        `{{define "notes"}}notes{{end}}`,
+		".draft.html":      `{{define "draft"}}draft{{end}}`,
+		".git/config.html": `{{define "git"}}git{{end}}`,
+	}
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, ".draft.html"), filepath.Join(dir, "link.html")); err != nil {
+		t.Skip("symbolic links not supported:", err)
+	}
+	tests := []struct {
+		policy  FilePolicy
+		pattern string
+		names   []string
+	}{
+		{FilePolicy{}, "*", []string{"notes", "page"}},
+		{FilePolicy{}, "*/*", nil},
+		{FilePolicy{}, ".git/*", []string{"git"}},
+		{FilePolicy{Hidden: true}, "*", []string{"draft", "notes", "page"}},
+		{FilePolicy{Hidden: true}, "*/*", []string{"git"}},
+		{FilePolicy{FollowSymlinks: true}, "*.html", []string{"draft", "page"}},
+		{FilePolicy{Extensions: []string{".txt"}}, "*", []string{"notes"}},
+	}
+	for _, test := range tests {
+		s, err := new(Set).Files(test.policy).ParseGlob(filepath.Join(dir, test.pattern))
+		var names []string
+		if err == nil {
+			for _, define := range s.tree {
+				names = append(names, define.Name)
+			}
+			sort.Strings(names)
+		}
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%+v %s: got templates %q, want %q (error: %v)", test.policy, test.pattern, names, test.names, err)
+		}
+	}
+}
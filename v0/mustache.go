@@ -0,0 +1,46 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gorilla/template/v0/escape"
+)
+
+// mustacheFuncs are the functions used by templates translated from the
+// Mustache syntax.
+var mustacheFuncs = FuncMap{
+	"mustache.raw":     mustacheRaw,
+	"mustache.section": mustacheSection,
+}
+
+// mustacheRaw returns v formatted as HTML that is not escaped, for the
+// {{{name}}} tag.
+func mustacheRaw(v interface{}) escape.HTML {
+	if v == nil {
+		return ""
+	}
+	return escape.HTML(fmt.Sprint(v))
+}
+
+// mustacheSection returns the values to execute a section with: the
+// elements of a list, the value itself if it is not empty, or nothing.
+func mustacheSection(v interface{}) []interface{} {
+	val := reflect.ValueOf(v)
+	if truth, _ := isTrue(val); !truth {
+		return nil
+	}
+	switch val.Kind() {
+	case reflect.Array, reflect.Slice:
+		values := make([]interface{}, val.Len())
+		for i := range values {
+			values[i] = val.Index(i).Interface()
+		}
+		return values
+	}
+	return []interface{}{v}
+}
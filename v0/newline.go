@@ -0,0 +1,100 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io"
+)
+
+// LineEnding defines how the line endings of the output are written. See
+// Set.LineEndings.
+type LineEnding int
+
+const (
+	// LineEndingKeep writes line endings as they are in the templates and
+	// in the printed values. This is the default.
+	LineEndingKeep LineEnding = iota
+	// LineEndingLF writes all line endings as "\n".
+	LineEndingLF
+	// LineEndingCRLF writes all line endings as "\r\n", as needed for
+	// example by SMTP message bodies and Windows batch files.
+	LineEndingCRLF
+)
+
+// LineEndings sets how line endings are written by executions. Both "\n"
+// and "\r\n" are line endings, in the text of the templates as well as in
+// printed values; a lone "\r" is written as is. By default line endings are
+// not changed, so the output depends on how the template files were saved.
+// The return value is the set, so calls can be chained.
+func (s *Set) LineEndings(e LineEnding) *Set {
+	s.lineEnding = e
+	return s
+}
+
+// lineWriter is a writer that replaces the line endings written to it.
+type lineWriter struct {
+	w       io.Writer
+	newline []byte
+	cr      bool   // a "\r" was held back by the last write
+	buf     []byte // reused by writes
+}
+
+// newLineWriter returns a writer that writes to w with line endings
+// replaced according to e, or w if e is LineEndingKeep.
+func newLineWriter(w io.Writer, e LineEnding) io.Writer {
+	switch e {
+	case LineEndingLF:
+		return &lineWriter{w: w, newline: []byte("\n")}
+	case LineEndingCRLF:
+		return &lineWriter{w: w, newline: []byte("\r\n")}
+	}
+	return w
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	out := w.buf[:0]
+	for _, c := range p {
+		if w.cr {
+			w.cr = false
+			if c == '\n' {
+				out = append(out, w.newline...)
+				continue
+			}
+			out = append(out, '\r')
+		}
+		switch c {
+		case '\r':
+			// Could be the start of "\r\n" split across writes.
+			w.cr = true
+		case '\n':
+			out = append(out, w.newline...)
+		default:
+			out = append(out, c)
+		}
+	}
+	w.buf = out
+	if _, err := w.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush writes the "\r" held back by the last write, if any.
+func (w *lineWriter) flush() error {
+	if !w.cr {
+		return nil
+	}
+	w.cr = false
+	_, err := w.w.Write([]byte{'\r'})
+	return err
+}
+
+// flushLines flushes w if it was returned by newLineWriter.
+func flushLines(w io.Writer) error {
+	if lw, ok := w.(*lineWriter); ok {
+		return lw.flush()
+	}
+	return nil
+}
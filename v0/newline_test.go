@@ -0,0 +1,49 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLineEndings(t *testing.T) {
+	const text = "{{define \"t\"}}a\r\nb\nc{{.}}\rd\n{{end}}"
+	tests := []struct {
+		ending LineEnding
+		data   string
+		output string
+	}{
+		{LineEndingKeep, "x\ny", "a\r\nb\ncx\ny\rd\n"},
+		{LineEndingLF, "x\r\ny", "a\nb\ncx\ny\rd\n"},
+		{LineEndingCRLF, "x\ny", "a\r\nb\r\ncx\r\ny\rd\r\n"},
+		// "\r\n" split between the value and the text.
+		{LineEndingLF, "x\r", "a\nb\ncx\r\rd\n"},
+		{LineEndingCRLF, "\n", "a\r\nb\r\nc\r\n\rd\r\n"},
+	}
+	for _, test := range tests {
+		s := Must(new(Set).LineEndings(test.ending).Parse(text))
+		var b bytes.Buffer
+		if err := s.Execute(&b, "t", test.data); err != nil {
+			t.Errorf("%d %q: %s", test.ending, test.data, err)
+			continue
+		}
+		if b.String() != test.output {
+			t.Errorf("%d %q: got %q, want %q", test.ending, test.data, b.String(), test.output)
+		}
+	}
+}
+
+func TestLineWriterSplit(t *testing.T) {
+	var b bytes.Buffer
+	w := newLineWriter(&b, LineEndingLF)
+	for _, s := range []string{"a\r", "\nb\r", "c\r"} {
+		w.Write([]byte(s))
+	}
+	flushLines(w)
+	if want := "a\nb\rc\r"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
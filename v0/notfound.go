@@ -0,0 +1,116 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// ErrTemplateNotFound is the error returned when a template is not in the
+// set. It suggests the names of the set closest to the requested one, to
+// help with typos.
+type ErrTemplateNotFound struct {
+	Name       string   // The requested name.
+	Candidates []string // The closest names in the set, best first.
+}
+
+func (e *ErrTemplateNotFound) Error() string {
+	msg := fmt.Sprintf("template: no template %q in the set", e.Name)
+	switch len(e.Candidates) {
+	case 0:
+		return msg
+	case 1:
+		return fmt.Sprintf("%s; did you mean %q?", msg, e.Candidates[0])
+	}
+	quoted := make([]string, len(e.Candidates))
+	for i, name := range e.Candidates {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	last := len(quoted) - 1
+	return fmt.Sprintf("%s; did you mean %s or %s?", msg, strings.Join(quoted[:last], ", "), quoted[last])
+}
+
+// maxCandidates is the maximum number of names suggested by
+// ErrTemplateNotFound.
+const maxCandidates = 3
+
+// notFound returns an ErrTemplateNotFound for the name, with the closest
+// names from the tree as candidates. Variants of templates derived by
+// contextual escaping are not suggested.
+func notFound(tree parse.Tree, name string) error {
+	var candidates byDistance
+	lower := strings.ToLower(name)
+	for other := range tree {
+		if strings.Contains(other, "$") {
+			continue
+		}
+		d := editDistance(lower, strings.ToLower(other))
+		// Allow about one edit every three characters.
+		if limit := (len(name) + 2) / 3; d <= limit {
+			candidates = append(candidates, candidate{other, d})
+		}
+	}
+	sort.Sort(candidates)
+	err := &ErrTemplateNotFound{Name: name}
+	for i := 0; i < len(candidates) && i < maxCandidates; i++ {
+		err.Candidates = append(err.Candidates, candidates[i].name)
+	}
+	return err
+}
+
+// candidate is a template name suggested by ErrTemplateNotFound.
+type candidate struct {
+	name     string
+	distance int // The edit distance to the requested name.
+}
+
+// byDistance sorts candidates by distance, then by name.
+type byDistance []candidate
+
+func (x byDistance) Len() int      { return len(x) }
+func (x byDistance) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x byDistance) Less(i, j int) bool {
+	if x[i].distance != x[j].distance {
+		return x[i].distance < x[j].distance
+	}
+	return x[i].name < x[j].name
+}
+
+// editDistance returns the Levenshtein distance between a and b, counted
+// in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// min3 returns the smallest of three integers.
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
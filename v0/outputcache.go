@@ -0,0 +1,60 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/gorilla/template/v0/cache"
+)
+
+// outputCache holds the cache of rendered output set by OutputCache.
+type outputCache struct {
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// OutputCache sets the cache where ExecuteCached stores rendered output,
+// and how long the output is kept: a ttl of zero or less keeps it until
+// the cache evicts it. The return value is the set, so calls can be
+// chained.
+func (s *Set) OutputCache(c cache.Cache, ttl time.Duration) *Set {
+	s.output = outputCache{c, ttl}
+	return s
+}
+
+// ExecuteCached applies the named template to data like Execute, and
+// stores the output in the cache set with OutputCache under the given key,
+// which must identify the data. Later calls with the same key write the
+// stored output without executing the template, until the template
+// changes, according to Hash. Without a cache, it is the same as Execute.
+func (s *Set) ExecuteCached(w io.Writer, name, key string, data interface{}) error {
+	s.mutex.Lock()
+	output := s.output
+	s.mutex.Unlock()
+	if output.cache == nil {
+		return s.Execute(w, name, data)
+	}
+	hash, err := s.Hash(name)
+	if err != nil {
+		return err
+	}
+	key = name + "\x00" + hash + "\x00" + key
+	if v, ok := output.cache.Get(key); ok {
+		if b, ok := v.([]byte); ok {
+			_, err := w.Write(b)
+			return err
+		}
+	}
+	var b bytes.Buffer
+	if err := s.Execute(&b, name, data); err != nil {
+		return err
+	}
+	output.cache.Set(key, b.Bytes(), output.ttl)
+	_, err = w.Write(b.Bytes())
+	return err
+}
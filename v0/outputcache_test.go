@@ -0,0 +1,44 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gorilla/template/v0/cache"
+)
+
+func TestOutputCache(t *testing.T) {
+	calls := 0
+	set := Must(new(Set).Funcs(FuncMap{
+		"count": func() int { calls++; return calls },
+	}).OutputCache(cache.NewLRU(10), 0).Parse(`{{define "t"}}{{.}} {{count}}{{end}}`))
+	execute := func(key string, data interface{}, want string) {
+		var b bytes.Buffer
+		if err := set.ExecuteCached(&b, "t", key, data); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != want {
+			t.Errorf("%s: expected %q, got %q", key, want, b.String())
+		}
+	}
+	execute("a", "A", "A 1")
+	execute("a", "A", "A 1")
+	execute("b", "B", "B 2")
+	// A new version of the template doesn't use the stored output.
+	snap, err := Must(new(Set).Funcs(FuncMap{
+		"count": func() int { calls++; return calls },
+	}).Parse(`{{define "t"}}[{{.}} {{count}}]{{end}}`)).Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Swap(snap)
+	execute("a", "A", "[A 3]")
+	execute("a", "A", "[A 3]")
+	if err := set.ExecuteCached(new(bytes.Buffer), "missing", "a", nil); err == nil {
+		t.Errorf("expected error for missing template")
+	}
+}
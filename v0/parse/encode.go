@@ -0,0 +1,138 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Encode returns a canonical encoding of the node and its children. Unlike
+// String, the encoding is unambiguous and doesn't depend on how the node
+// was written: positions, line numbers, quoting and the original text of
+// numbers are left out. Two nodes have the same encoding if and only if
+// they execute the same way, so the encoding can be hashed to detect
+// changes to templates.
+//
+// Each node is encoded as its type followed by its contents between
+// parentheses, for example:
+//
+//     (action (pipe () ((command ((field ("X")))))))
+func Encode(n Node) []byte {
+	var b bytes.Buffer
+	encode(&b, n)
+	return b.Bytes()
+}
+
+// encode writes the encoding of n to b.
+func encode(b *bytes.Buffer, n Node) {
+	switch n := n.(type) {
+	case *ActionNode:
+		encodeNode(b, "action", n.Pipe)
+	case *BoolNode:
+		encodeNode(b, "bool", n.True)
+	case *ChainNode:
+		encodeNode(b, "chain", n.Node, n.Field)
+	case *CommandNode:
+		encodeNode(b, "command", n.Args)
+	case *CaptureNode:
+		encodeNode(b, "capture", n.Variable, n.Content, n.List)
+	case *ConstNode:
+		encodeNode(b, "const", n.Name, n.List)
+	case *CustomNode:
+		encodeNode(b, "custom", n.Kind, fmt.Sprintf("%#v", n.Data), n.Pipe, n.List)
+	case *DefineNode:
+		encodeNode(b, "define", n.Name, n.Parent, n.konst, n.List)
+	case *DotNode:
+		encodeNode(b, "dot")
+	case *FieldNode:
+		encodeNode(b, "field", n.Ident)
+	case *FillNode:
+		encodeNode(b, "fill", n.Name, int(n.Mode), n.List)
+	case *IdentifierNode:
+		encodeNode(b, "identifier", n.Ident)
+	case *IfNode:
+		encodeNode(b, "if", n.Pipe, n.List, n.ElseList)
+	case *LetNode:
+		encodeNode(b, "let", n.Pipe, n.List)
+	case *ListNode:
+		if n == nil {
+			b.WriteString("nil")
+			return
+		}
+		encodeNode(b, "list", n.Nodes)
+	case *NilNode:
+		encodeNode(b, "nil")
+	case *NumberNode:
+		encodeNode(b, "number", n.IsInt, n.IsUint, n.IsFloat, n.IsComplex,
+			n.Int64, n.Uint64, n.Float64, n.Complex128)
+	case *PipeNode:
+		if n == nil {
+			b.WriteString("nil")
+			return
+		}
+		decl := make([]Node, len(n.Decl))
+		for i, v := range n.Decl {
+			decl[i] = v
+		}
+		cmds := make([]Node, len(n.Cmds))
+		for i, v := range n.Cmds {
+			cmds[i] = v
+		}
+		encodeNode(b, "pipe", decl, cmds)
+	case *RangeNode:
+		encodeNode(b, "range", n.Pipe, n.List, n.ElseList)
+	case *SlotNode:
+		encodeNode(b, "slot", n.Name, n.Default, n.List)
+	case *StringNode:
+		encodeNode(b, "string", n.Text)
+	case *TemplateNode:
+		encodeNode(b, "template", n.Name, n.Pipe)
+	case *TextNode:
+		encodeNode(b, "text", string(n.Text))
+	case *VariableNode:
+		encodeNode(b, "variable", n.Ident)
+	case *WithNode:
+		encodeNode(b, "with", n.Pipe, n.List, n.ElseList)
+	default:
+		panic(fmt.Sprintf("can't encode node of type %T", n))
+	}
+}
+
+// encodeNode writes a node with the given type name and contents to b.
+func encodeNode(b *bytes.Buffer, name string, contents ...interface{}) {
+	b.WriteString("(" + name)
+	for _, v := range contents {
+		b.WriteByte(' ')
+		switch v := v.(type) {
+		case Node:
+			encode(b, v)
+		case []Node:
+			b.WriteByte('(')
+			for i, n := range v {
+				if i > 0 {
+					b.WriteByte(' ')
+				}
+				encode(b, n)
+			}
+			b.WriteByte(')')
+		case string:
+			b.WriteString(strconv.Quote(v))
+		case []string:
+			b.WriteByte('(')
+			for i, s := range v {
+				if i > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(strconv.Quote(s))
+			}
+			b.WriteByte(')')
+		default:
+			fmt.Fprint(b, v)
+		}
+	}
+	b.WriteByte(')')
+}
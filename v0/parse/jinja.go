@@ -0,0 +1,645 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseJinja parses a template written in a subset of the syntax of Jinja2
+// and Django templates, and returns a tree containing a single template
+// with the given name. The template is translated to the native syntax and
+// parsed as usual, so the resulting tree is the same as for the equivalent
+// native template; error contexts refer to the translated text.
+//
+// The supported tags are:
+//
+//     {{ expression }}
+//     {# comment #}
+//     {% if expression %} ... {% elif expression %} ... {% else %} ... {% endif %}
+//     {% for x in expression %} ... {% else %} ... {% endfor %}
+//     {% for key, value in expression %} ... {% endfor %}
+//     {% block name %} ... {% endblock %}
+//     {% extends "name" %}
+//     {% include "name" %}
+//     {% set x = expression %}
+//     {% with x = expression, y = expression %} ... {% endwith %}
+//     {% raw %} ... {% endraw %}
+//
+// A block is a slot, or a fill when it is at the top level of a template
+// that extends another one. A leading or trailing dash in a tag, as in
+// {%- if x -%}, trims the adjacent white space. An included template
+// receives the data passed to the including one, but not its variables.
+//
+// Names declared by for, set and with are variables; other names are
+// fields of the data passed to the template, as in Jinja. Expressions
+// support literals, attribute access with dots, subscripts, function and
+// method calls, the operators ==, !=, <, <=, >, >=, and, or, not and ~
+// (concatenation), and filters. A filter is a function called with its
+// arguments followed by the filtered value, so x|truncate(30) calls
+// truncate(30, x).
+func ParseJinja(name, text string, funcs ...map[string]interface{}) (Tree, error) {
+	j := &jinja{name: name, text: text}
+	native, err := j.translate()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := new(parser).parseBody(name, native, "", "", funcs...)
+	if err != nil {
+		return nil, err
+	}
+	tree[name].Parent = j.parent
+	return tree, nil
+}
+
+// jinja translates a template from the Jinja syntax to the native one.
+type jinja struct {
+	name   string
+	text   string
+	pos    int // position of the tag being translated.
+	parent string
+	out    bytes.Buffer
+	open   []jinjaTag // tags waiting for their end tag.
+	locals []string   // names of the variables declared at the moment.
+}
+
+// jinjaTag is a tag waiting for its end tag.
+type jinjaTag struct {
+	name   string // "if", "for", "block" or "with".
+	ends   int    // number of native {{end}} actions that close it.
+	locals int    // number of variables declared before it.
+}
+
+// translateError is used to stop the translation of a template written in
+// another syntax with an error.
+type translateError struct {
+	err error
+}
+
+// translateErrorf stops a translation with an error at the given position
+// of the text.
+func translateErrorf(name, text string, pos int, format string, args ...interface{}) {
+	line := 1 + strings.Count(text[:pos], "\n")
+	format = fmt.Sprintf("template: %s:%d: %s", name, line, format)
+	panic(translateError{fmt.Errorf(format, args...)})
+}
+
+// recoverTranslate turns a translateError panic into an error return.
+func recoverTranslate(errp *error) {
+	if e := recover(); e != nil {
+		if e, ok := e.(translateError); ok {
+			*errp = e.err
+			return
+		}
+		panic(e)
+	}
+}
+
+func (j *jinja) errorf(format string, args ...interface{}) {
+	translateErrorf(j.name, j.text, j.pos, format, args...)
+}
+
+func (j *jinja) translate() (native string, err error) {
+	defer recoverTranslate(&err)
+	text := j.text
+	trim := false
+	for {
+		i := jinjaTagStart(text)
+		if i < 0 {
+			j.writeText(text, trim, false)
+			break
+		}
+		j.pos = len(j.text) - len(text) + i
+		open := text[i : i+2]
+		closing := map[string]string{"{{": "}}", "{%": "%}", "{#": "#}"}[open]
+		body := text[i+2:]
+		end := strings.Index(body, closing)
+		if end < 0 {
+			j.errorf("unclosed tag %s", open)
+		}
+		rest := body[end+2:]
+		body = body[:end]
+		j.writeText(text[:i], trim, strings.HasPrefix(body, "-"))
+		body = strings.TrimPrefix(body, "-")
+		trim = strings.HasSuffix(body, "-")
+		body = strings.TrimSuffix(body, "-")
+		switch open {
+		case "{{":
+			j.out.WriteString("{{" + j.expression(body) + "}}")
+		case "{%":
+			rest = j.statement(strings.TrimSpace(body), rest)
+		}
+		text = rest
+	}
+	if len(j.open) > 0 {
+		j.pos = len(j.text)
+		j.errorf("unclosed %s tag", j.open[len(j.open)-1].name)
+	}
+	return j.out.String(), nil
+}
+
+// jinjaTagStart returns the position of the first tag in text, or -1.
+func jinjaTagStart(text string) int {
+	for i := 0; i+1 < len(text); i++ {
+		if text[i] == '{' && strings.IndexByte("{%#", text[i+1]) >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeText writes text, trimming the white space at its start or end.
+func (j *jinja) writeText(text string, left, right bool) {
+	if left {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	}
+	if right {
+		text = strings.TrimRightFunc(text, unicode.IsSpace)
+	}
+	j.out.WriteString(text)
+}
+
+// statement translates a {% %} tag and returns the text after it.
+func (j *jinja) statement(body, rest string) string {
+	keyword, args := body, ""
+	if i := strings.IndexFunc(body, unicode.IsSpace); i >= 0 {
+		keyword, args = body[:i], strings.TrimSpace(body[i:])
+	}
+	switch keyword {
+	case "if":
+		j.push("if", 1)
+		j.out.WriteString("{{if " + j.expression(args) + "}}")
+	case "elif":
+		t := j.top("if")
+		t.ends++
+		j.out.WriteString("{{else}}{{if " + j.expression(args) + "}}")
+	case "else":
+		j.top("if", "for")
+		j.noArgs(keyword, args)
+		j.out.WriteString("{{else}}")
+	case "for":
+		j.forStatement(args)
+	case "block":
+		name := j.identifier(args)
+		action := "slot"
+		if j.parent != "" && len(j.open) == 0 {
+			action = "fill"
+		}
+		j.push("block", 1)
+		j.out.WriteString("{{" + action + " " + strconv.Quote(name) + "}}")
+	case "extends":
+		if j.parent != "" || j.out.Len() != 0 && strings.TrimSpace(j.out.String()) != "" {
+			j.errorf("extends must be the first tag of the template")
+		}
+		j.parent = j.stringLiteral(args)
+		j.out.Reset()
+	case "include":
+		j.out.WriteString("{{template " + strconv.Quote(j.stringLiteral(args)) + " $}}")
+	case "set":
+		name, value := j.assignment(args)
+		j.out.WriteString("{{$" + name + " := " + value + "}}")
+		j.locals = append(j.locals, name)
+	case "with":
+		t := j.push("with", 0)
+		for _, a := range j.split(args, ',') {
+			name, value := j.assignment(a)
+			j.out.WriteString("{{let $" + name + " := " + value + "}}")
+			j.locals = append(j.locals, name)
+			t.ends++
+		}
+	case "raw":
+		j.noArgs(keyword, args)
+		return j.raw(rest)
+	case "endif", "endfor", "endblock", "endwith":
+		t := j.top(keyword[3:])
+		if keyword != "endblock" {
+			j.noArgs(keyword, args)
+		}
+		j.out.WriteString(strings.Repeat("{{end}}", t.ends))
+		j.locals = j.locals[:t.locals]
+		j.open = j.open[:len(j.open)-1]
+	default:
+		j.errorf("unsupported tag %q", keyword)
+	}
+	return rest
+}
+
+// forStatement translates the arguments of a {% for %} tag.
+func (j *jinja) forStatement(args string) {
+	i := strings.Index(args, " in ")
+	if i < 0 {
+		j.errorf("missing in clause in for tag")
+	}
+	names := j.split(args[:i], ',')
+	if len(names) > 2 {
+		j.errorf("too many names in for tag")
+	}
+	value := j.expression(args[i+4:])
+	j.push("for", 1)
+	decl := ""
+	for k, name := range names {
+		name = j.identifier(name)
+		if k > 0 {
+			decl += ", "
+		}
+		decl += "$" + name
+		j.locals = append(j.locals, name)
+	}
+	j.out.WriteString("{{range " + decl + " := " + value + "}}")
+}
+
+// raw translates the contents of a {% raw %} tag and returns the text
+// after its end tag.
+func (j *jinja) raw(text string) string {
+	for i := 0; ; {
+		k := strings.Index(text[i:], "{%")
+		if k < 0 {
+			j.errorf("unclosed raw tag")
+		}
+		i += k
+		end := strings.Index(text[i:], "%}")
+		if end >= 0 && strings.Trim(text[i+2:i+end], " \t\r\n-") == "endraw" {
+			j.out.WriteString(strings.Replace(text[:i], "{{", `{{"{{"}}`, -1))
+			return text[i+end+2:]
+		}
+		i += 2
+	}
+}
+
+// push adds a tag waiting for its end tag.
+func (j *jinja) push(name string, ends int) *jinjaTag {
+	j.open = append(j.open, jinjaTag{name: name, ends: ends, locals: len(j.locals)})
+	return &j.open[len(j.open)-1]
+}
+
+// top returns the innermost tag waiting for its end tag, which must have
+// one of the given names.
+func (j *jinja) top(names ...string) *jinjaTag {
+	if len(j.open) > 0 {
+		t := &j.open[len(j.open)-1]
+		for _, name := range names {
+			if t.name == name {
+				return t
+			}
+		}
+	}
+	j.errorf("unexpected tag outside %s", strings.Join(names, " or "))
+	return nil
+}
+
+func (j *jinja) noArgs(keyword, args string) {
+	if args != "" {
+		j.errorf("unexpected %q in %s tag", args, keyword)
+	}
+}
+
+// identifier returns s, which must be a name.
+func (j *jinja) identifier(s string) string {
+	s = strings.TrimSpace(s)
+	if !isJinjaName(s) {
+		j.errorf("bad name %q", s)
+	}
+	return s
+}
+
+// stringLiteral returns the value of s, which must be a quoted string.
+func (j *jinja) stringLiteral(s string) string {
+	toks := j.tokens(s)
+	if len(toks) != 1 || toks[0][0] != '"' && toks[0][0] != '\'' {
+		j.errorf("expected string; found %q", s)
+	}
+	return jinjaUnquote(toks[0])
+}
+
+// assignment splits s in the name and the translated value of "x = e".
+func (j *jinja) assignment(s string) (name, value string) {
+	i := strings.Index(s, "=")
+	if i < 0 || strings.HasPrefix(s[i:], "==") {
+		j.errorf("expected assignment; found %q", s)
+	}
+	return j.identifier(s[:i]), j.expression(s[i+1:])
+}
+
+// split splits s at the separators that are not in parentheses, brackets
+// or strings.
+func (j *jinja) split(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// Expressions ----------------------------------------------------------------
+
+// jinjaExpr is a translated expression.
+type jinjaExpr struct {
+	text string // Native text of the expression.
+	cmd  bool   // Whether the text is a command, which needs parentheses as an argument.
+}
+
+// arg returns the expression as an argument of a command.
+func (e jinjaExpr) arg() string {
+	if e.cmd {
+		return "(" + e.text + ")"
+	}
+	return e.text
+}
+
+// jinjaParser translates the tokens of an expression.
+type jinjaParser struct {
+	j    *jinja
+	toks []string
+}
+
+// expression translates a Jinja expression to a native pipeline.
+func (j *jinja) expression(s string) string {
+	p := &jinjaParser{j: j, toks: j.tokens(s)}
+	if len(p.toks) == 0 {
+		j.errorf("missing expression")
+	}
+	e := p.or()
+	if len(p.toks) > 0 {
+		j.errorf("unexpected %q in expression", p.toks[0])
+	}
+	return e.text
+}
+
+func (p *jinjaParser) peek() string {
+	if len(p.toks) == 0 {
+		return ""
+	}
+	return p.toks[0]
+}
+
+func (p *jinjaParser) next() string {
+	if len(p.toks) == 0 {
+		p.j.errorf("unexpected end of expression")
+	}
+	tok := p.toks[0]
+	p.toks = p.toks[1:]
+	return tok
+}
+
+func (p *jinjaParser) expect(tok string) {
+	if next := p.next(); next != tok {
+		p.j.errorf("expected %q in expression; found %q", tok, next)
+	}
+}
+
+// call returns a command calling fn with the given expressions.
+func (p *jinjaParser) call(fn string, args ...jinjaExpr) jinjaExpr {
+	text := fn
+	for _, a := range args {
+		text += " " + a.arg()
+	}
+	return jinjaExpr{text, true}
+}
+
+// binary translates a sequence of operands separated by op.
+func (p *jinjaParser) binary(op, fn string, operand func() jinjaExpr) jinjaExpr {
+	e := operand()
+	if p.peek() != op {
+		return e
+	}
+	args := []jinjaExpr{e}
+	for p.peek() == op {
+		p.next()
+		args = append(args, operand())
+	}
+	return p.call(fn, args...)
+}
+
+func (p *jinjaParser) or() jinjaExpr {
+	return p.binary("or", "or", p.and)
+}
+
+func (p *jinjaParser) and() jinjaExpr {
+	return p.binary("and", "and", p.not)
+}
+
+func (p *jinjaParser) not() jinjaExpr {
+	if p.peek() == "not" {
+		p.next()
+		return p.call("not", p.not())
+	}
+	return p.comparison()
+}
+
+var jinjaComparisons = map[string]string{
+	"==": "eq", "!=": "ne", "<": "lt", "<=": "le", ">": "gt", ">=": "ge",
+}
+
+func (p *jinjaParser) comparison() jinjaExpr {
+	e := p.binary("~", "print", p.filtered)
+	if fn, ok := jinjaComparisons[p.peek()]; ok {
+		p.next()
+		return p.call(fn, e, p.binary("~", "print", p.filtered))
+	}
+	switch tok := p.peek(); tok {
+	case "+", "-", "*", "/", "%", "in", "is":
+		p.j.errorf("unsupported operator %q", tok)
+	}
+	return e
+}
+
+func (p *jinjaParser) filtered() jinjaExpr {
+	e := p.primary()
+	for p.peek() == "|" {
+		p.next()
+		fn := p.next()
+		if !isJinjaName(fn) {
+			p.j.errorf("bad filter %q", fn)
+		}
+		var args []jinjaExpr
+		if p.peek() == "(" {
+			args = p.args()
+		}
+		e = p.call(fn, append(args, e)...)
+	}
+	return e
+}
+
+// args translates the parenthesized arguments of a call.
+func (p *jinjaParser) args() []jinjaExpr {
+	p.expect("(")
+	var args []jinjaExpr
+	for p.peek() != ")" {
+		if len(args) > 0 {
+			p.expect(",")
+		}
+		args = append(args, p.or())
+	}
+	p.next()
+	return args
+}
+
+func (p *jinjaParser) primary() jinjaExpr {
+	tok := p.next()
+	var e jinjaExpr
+	switch {
+	case tok == "(":
+		e = p.or()
+		p.expect(")")
+	case tok[0] == '"' || tok[0] == '\'':
+		return jinjaExpr{strconv.Quote(jinjaUnquote(tok)), false}
+	case tok[0] >= '0' && tok[0] <= '9':
+		return jinjaExpr{tok, false}
+	case tok == "true" || tok == "True":
+		return jinjaExpr{"true", false}
+	case tok == "false" || tok == "False":
+		return jinjaExpr{"false", false}
+	case tok == "none" || tok == "None":
+		return jinjaExpr{"nil", false}
+	case isJinjaName(tok):
+		if p.peek() == "(" && !p.j.isLocal(tok) {
+			return p.postfix(p.call(tok, p.args()...))
+		}
+		e = jinjaExpr{"$." + tok, false}
+		if p.j.isLocal(tok) {
+			e.text = "$" + tok
+		}
+	default:
+		p.j.errorf("unexpected %q in expression", tok)
+	}
+	return p.postfix(e)
+}
+
+// postfix translates the attributes, subscripts and calls following e.
+func (p *jinjaParser) postfix(e jinjaExpr) jinjaExpr {
+	for {
+		switch p.peek() {
+		case ".":
+			p.next()
+			name := p.next()
+			if !isJinjaName(name) {
+				p.j.errorf("bad attribute %q", name)
+			}
+			e = jinjaExpr{e.arg() + "." + name, false}
+			if p.peek() == "(" {
+				if args := p.args(); len(args) > 0 {
+					e = p.call(e.text, args...)
+				}
+			}
+		case "[":
+			p.next()
+			i := p.or()
+			p.expect("]")
+			e = p.call("index", e, i)
+		default:
+			return e
+		}
+	}
+}
+
+func (j *jinja) isLocal(name string) bool {
+	for _, l := range j.locals {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tokens splits an expression in tokens.
+func (j *jinja) tokens(s string) []string {
+	var toks []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+			continue
+		case c == '"' || c == '\'':
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(s) {
+				j.errorf("unterminated string in expression")
+			}
+			i++
+		case c >= '0' && c <= '9':
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == '_') {
+				i++
+			}
+		case c == '_' || unicode.IsLetter(rune(c)):
+			for i < len(s) && (s[i] == '_' || unicode.IsLetter(rune(s[i])) || s[i] >= '0' && s[i] <= '9') {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!=") ||
+			strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">="):
+			i += 2
+		case strings.IndexByte("<>|()[].,~+-*/%=", c) >= 0:
+			i++
+		default:
+			j.errorf("unexpected %q in expression", c)
+		}
+		toks = append(toks, s[start:i])
+	}
+	return toks
+}
+
+func isJinjaName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	switch s {
+	case "and", "or", "not", "in", "is":
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// jinjaUnquote returns the value of a string literal quoted with single or
+// double quotes.
+func jinjaUnquote(s string) string {
+	var b bytes.Buffer
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+2 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+				continue
+			case 't':
+				b.WriteByte('\t')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type jinjaTest struct {
+	name   string
+	input  string
+	native string // Equivalent native template; empty for errors.
+}
+
+var jinjaTests = []jinjaTest{
+	{"text", "hello", "hello"},
+	{"comment", "a{# x #}b", "ab"},
+	{"field", "{{ user.name }}", "{{$.user.name}}"},
+	{"literals", `{{ 'a"b' }}{{ 3 }}{{ true }}{{ None }}`, `{{"a\"b"}}{{3}}{{true}}{{nil}}`},
+	{"filter", "{{ x|upper|truncate(3, 'x') }}", `{{truncate 3 "x" (upper $.x)}}`},
+	{"call", "{{ f(a, 1) }}{{ a.b() }}{{ a.b(1) }}", "{{f $.a 1}}{{$.a.b}}{{$.a.b 1}}"},
+	{"index", "{{ a[0].b }}", "{{(index $.a 0).b}}"},
+	{"operators", "{{ not a == 1 and b or c ~ 'x' }}", `{{or (and (not (eq $.a 1)) $.b) (print $.c "x")}}`},
+	{"if", "{% if a %}1{% elif b %}2{% else %}3{% endif %}",
+		"{{if $.a}}1{{else}}{{if $.b}}2{{else}}3{{end}}{{end}}"},
+	{"for", "{% for x in xs %}{{ x }}{% else %}none{% endfor %}{{ x }}",
+		"{{range $x := $.xs}}{{$x}}{{else}}none{{end}}{{$.x}}"},
+	{"for pair", "{% for k, v in m %}{{ k }}={{ v }}{% endfor %}",
+		"{{range $k, $v := $.m}}{{$k}}={{$v}}{{end}}"},
+	{"set", "{% set x = 1 %}{{ x }}", "{{$x := 1}}{{$x}}"},
+	{"with", "{% with a = 1, b = a %}{{ b }}{% endwith %}",
+		"{{let $a := 1}}{{let $b := $a}}{{$b}}{{end}}{{end}}"},
+	{"block", "{% block body %}x{% endblock body %}", `{{slot "body"}}x{{end}}`},
+	{"include", `{% include "x.html" %}`, `{{template "x.html" $}}`},
+	{"raw", "{% raw %}{{ a }}{% endraw %}", `{{"{{"}} a }}`},
+	{"trim", "a  {%- if x -%}  b  {%- endif %}  c", "a{{if $.x}}b{{end}}  c"},
+	// Errors.
+	{"unclosed tag", "{{ a", ""},
+	{"unclosed block", "{% if a %}", ""},
+	{"unexpected end", "{% endfor %}", ""},
+	{"unknown tag", "{% macro m() %}", ""},
+	{"bad operator", "{{ a + 1 }}", ""},
+	{"late extends", `x{% extends "base" %}`, ""},
+}
+
+var jinjaFuncs = map[string]interface{}{}
+
+func init() {
+	for _, name := range []string{"and", "eq", "f", "index", "not", "or", "print", "truncate", "upper"} {
+		jinjaFuncs[name] = fmt.Sprint
+	}
+}
+
+func TestParseJinja(t *testing.T) {
+	for _, test := range jinjaTests {
+		tree, err := ParseJinja(test.name, test.input, jinjaFuncs)
+		if test.native == "" {
+			if err == nil {
+				t.Errorf("%s: expected error; got none", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		want, err := ParseFile(test.name, test.native, "", "", jinjaFuncs)
+		if err != nil {
+			t.Fatalf("%s: bad native template: %v", test.name, err)
+		}
+		if got, want := tree[test.name].List.String(), want[test.name].List.String(); got != want {
+			t.Errorf("%s: got %q, want %q", test.name, got, want)
+		}
+	}
+}
+
+func TestParseJinjaExtends(t *testing.T) {
+	text := `{# child #}
+{%- extends 'base' %}
+{% block body %}{% block inner %}x{% endblock %}{% endblock %}`
+	tree, err := ParseJinja("child", text, jinjaFuncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := tree["child"]
+	if d.Parent != "base" {
+		t.Errorf("got parent %q, want %q", d.Parent, "base")
+	}
+	if got, want := d.List.String(), "\n"+`{{fill "body"}}{{slot "inner"}}x{{end}}{{end}}`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	_, err = ParseJinja("t", "a\n{% bad %}", jinjaFuncs)
+	if err == nil || !strings.HasPrefix(err.Error(), "template: t:2: ") {
+		t.Errorf("expected error at line 2; got %v", err)
+	}
+}
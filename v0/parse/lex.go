@@ -41,6 +41,7 @@ const (
 	itemChar                         // printable ASCII character; grab bag for comma etc.
 	itemCharConstant                 // character constant
 	itemComplex                      // complex constant (1+2i); imaginary is just a number
+	itemColon                        // colon (':') introducing a filter argument
 	itemColonEquals                  // colon-equals (':=') introducing a declaration
 	itemEOF
 	itemField      // alphanumeric identifier starting with '.'
@@ -69,6 +70,17 @@ const (
 	itemWith     // with keyword
 	itemSlot     // slot keyword
 	itemFill     // fill keyword
+	// Contextual keywords are keywords only where a statement may start,
+	// and only when no function of the same name is registered.
+	itemContextual // used only to delimit the contextual keywords
+	itemLet        // let keyword
+	itemConst      // const keyword
+	itemImport     // import keyword
+	itemSet        // set keyword
+	itemVariant    // variant keyword
+	itemCase       // case keyword
+	itemCapture    // capture keyword
+	itemExtends    // extends keyword
 )
 
 var key = map[string]itemType{
@@ -77,12 +89,20 @@ var key = map[string]itemType{
 	"else":     itemElse,
 	"end":      itemEnd,
 	"if":       itemIf,
+	"let":      itemLet,
 	"range":    itemRange,
 	"nil":      itemNil,
 	"template": itemTemplate,
 	"with":     itemWith,
 	"slot":     itemSlot,
 	"fill":     itemFill,
+	"const":    itemConst,
+	"import":   itemImport,
+	"set":      itemSet,
+	"variant":  itemVariant,
+	"case":     itemCase,
+	"capture":  itemCapture,
+	"extends":  itemExtends,
 }
 
 const eof = -1
@@ -280,9 +300,11 @@ func lexInsideAction(l *lexer) stateFn {
 	case isSpace(r):
 		return lexSpace
 	case r == ':':
-		if l.next() != '=' {
-			return l.errorf("expected :=")
+		if l.peek() != '=' {
+			l.emit(itemColon)
+			break
 		}
+		l.next()
 		l.emit(itemColonEquals)
 	case r == '|':
 		l.emit(itemPipe)
@@ -16,6 +16,7 @@ var itemName = map[itemType]string{
 	itemChar:         "char",
 	itemCharConstant: "charconst",
 	itemComplex:      "complex",
+	itemColon:        ":",
 	itemColonEquals:  ":=",
 	itemEOF:          "EOF",
 	itemField:        "field",
@@ -412,6 +413,56 @@ func TestDelims(t *testing.T) {
 	}
 }
 
+// Asymmetric delimiters of different lengths, with multi-byte runes.
+var lexDelimPosTests = []struct {
+	left, right string
+	lexTest
+}{
+	{"<%", "%>", lexTest{"erb", "a<%.X%>b<%/* c */%>", []item{
+		{itemText, 0, "a"},
+		{itemLeftDelim, 1, "<%"},
+		{itemField, 3, ".X"},
+		{itemRightDelim, 5, "%>"},
+		{itemText, 7, "b"},
+		{itemEOF, 19, ""},
+	}}},
+	{"«", "»»", lexTest{"multi-byte", "«x»»«»»", []item{
+		{itemLeftDelim, 0, "«"},
+		{itemIdentifier, 2, "x"},
+		{itemRightDelim, 3, "»»"},
+		{itemLeftDelim, 7, "«"},
+		{itemRightDelim, 9, "»»"},
+		{itemEOF, 13, ""},
+	}}},
+	{"{%", "}", lexTest{"prefix of the default", "{{%if .}}{%end}", []item{
+		{itemText, 0, "{"},
+		{itemLeftDelim, 1, "{%"},
+		{itemIf, 3, "if"},
+		{itemSpace, 5, " "},
+		{itemDot, 6, "."},
+		{itemRightDelim, 7, "}"},
+		{itemText, 8, "}"},
+		{itemLeftDelim, 9, "{%"},
+		{itemEnd, 11, "end"},
+		{itemRightDelim, 14, "}"},
+		{itemEOF, 15, ""},
+	}}},
+	{"<%", "%>", lexTest{"unclosed", "<%.X", []item{
+		{itemLeftDelim, 0, "<%"},
+		{itemField, 2, ".X"},
+		{itemError, 4, "unclosed action"},
+	}}},
+}
+
+func TestDelimPos(t *testing.T) {
+	for _, test := range lexDelimPosTests {
+		items := collect(&test.lexTest, test.left, test.right)
+		if !equal(items, test.items, true) {
+			t.Errorf("%s: got\n\t%v\nexpected\n\t%v", test.name, items, test.items)
+		}
+	}
+}
+
 var lexPosTests = []lexTest{
 	{"empty", "", []item{tEOF}},
 	{"punctuation", "{{,@%#}}", []item{
@@ -0,0 +1,81 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lineDirective is a {{/*line file:N*/}} comment. Like Go's //line
+// directives, it sets the position reported for the text following it to
+// line N of the given file, so that templates produced by a preprocessor
+// report errors against their original sources. Later lines are numbered
+// from there.
+type lineDirective struct {
+	pos      int    // Offset of the text following the directive.
+	line     int    // Line of pos in the input.
+	file     string // File reported for the text.
+	fileLine int    // Line reported for pos.
+}
+
+// lineDirectives holds the line directives of a text, in order.
+type lineDirectives []lineDirective
+
+// lineDirectivesOf returns the line directives found in text, for the
+// given action delimiters. Comments that don't have the form of a directive
+// are ignored.
+func lineDirectivesOf(text, left, right string) lineDirectives {
+	var dirs lineDirectives
+	start := left + leftComment + "line "
+	end := rightComment + right
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], start)
+		if i < 0 {
+			break
+		}
+		i += offset + len(start)
+		j := strings.Index(text[i:], end)
+		if j < 0 {
+			break
+		}
+		spec := text[i : i+j]
+		offset = i + j + len(end)
+		colon := strings.LastIndex(spec, ":")
+		if colon <= 0 || strings.ContainsAny(spec, "\r\n") {
+			continue
+		}
+		n, err := strconv.Atoi(spec[colon+1:])
+		if err != nil || n <= 0 {
+			continue
+		}
+		dirs = append(dirs, lineDirective{
+			pos:      offset,
+			line:     1 + strings.Count(text[:offset], "\n"),
+			file:     spec[:colon],
+			fileLine: n,
+		})
+	}
+	return dirs
+}
+
+// position returns the file and line to report for the given offset in the
+// input, which is at the given line of the template with the given name.
+func (dirs lineDirectives) position(name string, pos, line int) (string, int) {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if d := dirs[i]; d.pos <= pos {
+			return d.file, d.fileLine + line - d.line
+		}
+	}
+	return name, line
+}
+
+// location returns the location to report for the given offset in the
+// input, as file:line:column.
+func (dirs lineDirectives) location(name string, pos, line, col int) string {
+	file, line := dirs.position(name, pos, line)
+	return fmt.Sprintf("%s:%d:%d", file, line, col)
+}
@@ -0,0 +1,173 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ParseMustache parses a template written in the syntax of Mustache and
+// Handlebars, and returns a tree containing a single template with the
+// given name. Like ParseJinja, the template is translated to the native
+// syntax, mapping the tags to nodes as follows:
+//
+//     {{name}}                  {{.name}}
+//     {{{name}}}, {{&name}}     {{mustache.raw .name}}
+//     {{#name}}...{{/name}}     {{range mustache.section .name}}...{{end}}
+//     {{^name}}...{{/name}}     {{if .name}}{{else}}...{{end}}
+//     {{>name}}                 {{template "name" .}}
+//     {{!comment}}              removed
+//     {{=<% %>=}}               changes the delimiters
+//
+// A section is executed once for each element of a list, or once with the
+// value as dot if it is another non-empty value. Names are looked up in the
+// current context only, not in the enclosing ones. The functions
+// mustache.raw and mustache.section must be provided in funcs. Tags that
+// stand alone on a line remove the line, as in Mustache.
+func ParseMustache(name, text string, funcs ...map[string]interface{}) (Tree, error) {
+	m := &mustache{name: name, text: text, left: "{{", right: "}}"}
+	native, err := m.translate()
+	if err != nil {
+		return nil, err
+	}
+	return new(parser).parseBody(name, native, "", "", funcs...)
+}
+
+// mustache translates a template from the Mustache syntax to the native
+// one.
+type mustache struct {
+	name     string
+	text     string
+	pos      int // position of the tag being translated.
+	left     string
+	right    string
+	out      bytes.Buffer
+	sections []string // names of the sections waiting for their end tag.
+}
+
+func (m *mustache) errorf(format string, args ...interface{}) {
+	translateErrorf(m.name, m.text, m.pos, format, args...)
+}
+
+func (m *mustache) translate() (native string, err error) {
+	defer recoverTranslate(&err)
+	pos := 0
+	lineStart := true // whether pos is at the start of a line.
+	for {
+		i := strings.Index(m.text[pos:], m.left)
+		if i < 0 {
+			m.writeText(m.text[pos:])
+			break
+		}
+		m.pos = pos + i
+		body := m.text[m.pos+len(m.left):]
+		right := m.right
+		if strings.HasPrefix(body, "{") {
+			right = "}" + right
+		}
+		end := strings.Index(body, right)
+		if end < 0 {
+			m.errorf("unclosed tag")
+		}
+		next := m.pos + len(m.left) + end + len(right)
+		tag := body[:end]
+		var kind byte
+		if tag != "" && strings.IndexByte("{&#^/>!=", tag[0]) >= 0 {
+			kind, tag = tag[0], tag[1:]
+		}
+		if kind == '=' {
+			if !strings.HasSuffix(tag, "=") {
+				m.errorf("bad delimiter tag")
+			}
+			tag = tag[:len(tag)-1]
+		}
+		tag = strings.TrimSpace(tag)
+		text := m.text[pos:m.pos]
+		standalone := false
+		if kind != 0 && kind != '{' && kind != '&' {
+			// Remove the line of a tag that stands alone on it.
+			begin := strings.LastIndex(text, "\n") + 1
+			after := m.text[next:]
+			eol := strings.Index(after, "\n")
+			if eol >= 0 {
+				after = after[:eol]
+			}
+			if (begin > 0 || lineStart) && isBlank(text[begin:]) && isBlank(after) {
+				standalone = true
+				text = text[:begin]
+				next += len(after)
+				if eol >= 0 {
+					next++
+				}
+			}
+		}
+		m.writeText(text)
+		m.tag(kind, tag)
+		pos, lineStart = next, standalone
+	}
+	if len(m.sections) > 0 {
+		m.pos = len(m.text)
+		m.errorf("unclosed section %q", m.sections[len(m.sections)-1])
+	}
+	return m.out.String(), nil
+}
+
+// isBlank returns whether s contains only spaces and tabs.
+func isBlank(s string) bool {
+	return strings.Trim(s, " \t\r") == ""
+}
+
+// writeText writes text, protecting the native delimiters it may contain
+// after a change of delimiters.
+func (m *mustache) writeText(text string) {
+	m.out.WriteString(strings.Replace(text, "{{", `{{"{{"}}`, -1))
+}
+
+// tag translates a tag of the given kind.
+func (m *mustache) tag(kind byte, tag string) {
+	switch kind {
+	case 0:
+		m.out.WriteString("{{" + m.field(tag) + "}}")
+	case '{', '&':
+		m.out.WriteString("{{mustache.raw " + m.field(tag) + "}}")
+	case '#':
+		m.sections = append(m.sections, tag)
+		m.out.WriteString("{{range mustache.section " + m.field(tag) + "}}")
+	case '^':
+		m.sections = append(m.sections, tag)
+		m.out.WriteString("{{if " + m.field(tag) + "}}{{else}}")
+	case '/':
+		if len(m.sections) == 0 || m.sections[len(m.sections)-1] != tag {
+			m.errorf("unexpected end of section %q", tag)
+		}
+		m.sections = m.sections[:len(m.sections)-1]
+		m.out.WriteString("{{end}}")
+	case '>':
+		if tag == "" || strings.ContainsAny(tag, "\"\n") {
+			m.errorf("bad partial name %q", tag)
+		}
+		m.out.WriteString(`{{template "` + tag + `" .}}`)
+	case '=':
+		delims := strings.Fields(tag)
+		if len(delims) != 2 || strings.Contains(delims[0], "=") || strings.Contains(delims[1], "=") {
+			m.errorf("bad delimiters %q", tag)
+		}
+		m.left, m.right = delims[0], delims[1]
+	}
+}
+
+// field translates a dotted name to a native field.
+func (m *mustache) field(name string) string {
+	if name == "." {
+		return "."
+	}
+	for _, id := range strings.Split(name, ".") {
+		if !isJinjaName(id) {
+			m.errorf("bad name %q", name)
+		}
+	}
+	return "." + name
+}
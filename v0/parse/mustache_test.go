@@ -0,0 +1,57 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"fmt"
+	"testing"
+)
+
+var mustacheFuncs = map[string]interface{}{
+	"mustache.raw":     fmt.Sprint,
+	"mustache.section": fmt.Sprint,
+}
+
+var mustacheTests = []jinjaTest{
+	{"text", "hello", "hello"},
+	{"variable", "{{ name }}{{a.b}}{{.}}", "{{.name}}{{.a.b}}{{.}}"},
+	{"raw", "{{{ name }}}{{& name}}", "{{mustache.raw .name}}{{mustache.raw .name}}"},
+	{"section", "{{#items}}<{{.}}>{{/items}}", "{{range mustache.section .items}}<{{.}}>{{end}}"},
+	{"inverted", "{{^items}}none{{/items}}", "{{if .items}}{{else}}none{{end}}"},
+	{"partial", "{{> item}}", `{{template "item" .}}`},
+	{"comment", "a{{! b }}c", "ac"},
+	{"standalone", "a\n  {{#x}}  \nb\n{{/x}}\nc", "a\n{{range mustache.section .x}}b\n{{end}}c"},
+	{"inline", "a {{#x}}b{{/x}}\n", "a {{range mustache.section .x}}b{{end}}\n"},
+	{"delimiters", "{{=<% %>=}}<% a %>{{b}}", `{{.a}}{{"{{"}}b}}`},
+	// Errors.
+	{"unclosed tag", "{{ a", ""},
+	{"unclosed section", "{{#a}}", ""},
+	{"mismatched section", "{{#a}}{{/b}}", ""},
+	{"bad name", "{{a-b}}", ""},
+	{"bad delimiters", "{{=<%=}}", ""},
+}
+
+func TestParseMustache(t *testing.T) {
+	for _, test := range mustacheTests {
+		tree, err := ParseMustache(test.name, test.input, mustacheFuncs)
+		if test.native == "" {
+			if err == nil {
+				t.Errorf("%s: expected error; got none", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		want, err := ParseFile(test.name, test.native, "", "", mustacheFuncs)
+		if err != nil {
+			t.Fatalf("%s: bad native template: %v", test.name, err)
+		}
+		if got, want := tree[test.name].List.String(), want[test.name].List.String(); got != want {
+			t.Errorf("%s: got %q, want %q", test.name, got, want)
+		}
+	}
+}
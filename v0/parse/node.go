@@ -9,6 +9,8 @@ package parse
 import (
 	"bytes"
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -56,6 +58,7 @@ const (
 	NodeBool                       // A boolean constant.
 	NodeChain                      // A sequence of field accesses.
 	NodeCommand                    // An element of a pipeline.
+	NodeConst                      // A const action.
 	NodeDefine                     // A template definition.
 	NodeDot                        // The cursor, dot.
 	nodeElse                       // An else action. Not added to tree.
@@ -64,6 +67,7 @@ const (
 	NodeFill                       // A fill action.
 	NodeIdentifier                 // An identifier; always a function name.
 	NodeIf                         // An if action.
+	NodeLet                        // A let action.
 	NodeList                       // A list of Nodes.
 	NodeNil                        // An untyped nil constant.
 	NodeNumber                     // A numerical constant.
@@ -75,6 +79,9 @@ const (
 	NodeTree                       // A tree of define nodes.
 	NodeVariable                   // A $ variable.
 	NodeWith                       // A with action.
+	NodeCustom                     // A node of a kind defined outside this package.
+	NodeCapture                    // A capture action.
+	nodeExtends                    // An extends action. Not added to tree.
 )
 
 // Nodes.
@@ -632,7 +639,30 @@ func (e *elseNode) Copy() Node {
 	return newElse(e.Pos, e.Line)
 }
 
-// BranchNode is the common representation of if, range, and with.
+// extendsNode represents an {{extends}} action, which sets the parent of
+// the template. It does not appear in the final tree.
+type extendsNode struct {
+	Pos
+	Parent string // The name of the parent template (unquoted).
+}
+
+func newExtends(pos Pos, parent string) *extendsNode {
+	return &extendsNode{Pos: pos, Parent: parent}
+}
+
+func (e *extendsNode) Type() NodeType {
+	return nodeExtends
+}
+
+func (e *extendsNode) String() string {
+	return fmt.Sprintf("{{extends %q}}", e.Parent)
+}
+
+func (e *extendsNode) Copy() Node {
+	return newExtends(e.Pos, e.Parent)
+}
+
+// BranchNode is the common representation of if, let, range, and with.
 type BranchNode struct {
 	NodeType
 	Pos
@@ -647,6 +677,8 @@ func (b *BranchNode) String() string {
 	switch b.NodeType {
 	case NodeIf:
 		name = "if"
+	case NodeLet:
+		name = "let"
 	case NodeRange:
 		name = "range"
 	case NodeWith:
@@ -673,6 +705,21 @@ func (i *IfNode) Copy() Node {
 	return newIf(i.Pos, i.Line, i.Pipe.CopyPipe(), i.List.CopyList(), i.ElseList.CopyList())
 }
 
+// LetNode represents a {{let}} action and its commands. The variable
+// declared by its pipeline is only visible in List, and ElseList is
+// always nil.
+type LetNode struct {
+	BranchNode
+}
+
+func newLet(pos Pos, line int, pipe *PipeNode, list *ListNode) *LetNode {
+	return &LetNode{BranchNode{NodeType: NodeLet, Pos: pos, Line: line, Pipe: pipe, List: list}}
+}
+
+func (l *LetNode) Copy() Node {
+	return newLet(l.Pos, l.Line, l.Pipe.CopyPipe(), l.List.CopyList())
+}
+
 // RangeNode represents a {{range}} action and its commands.
 type RangeNode struct {
 	BranchNode
@@ -727,11 +774,15 @@ func (t *TemplateNode) Copy() Node {
 type DefineNode struct {
 	NodeType
 	Pos
-	Line   int       // The line number in the input.
-	Name   string    // The name of the template (unquoted).
-	Parent string    // The name of the parent template (unquoted).
-	List   *ListNode // Contents of the template.
-	text   string    // TODO: how could we avoid this field?
+	Line     int             // The line number in the input.
+	Name     string          // The name of the template (unquoted).
+	Parent   string          // The name of the parent template (unquoted).
+	List     *ListNode       // Contents of the template.
+	text     string          // Input text, for error context; see DropText.
+	lines    []int           // Offsets of the lines of the text; see Tree.KeepLines.
+	lineDirs lineDirectives  // Line directives of the text, for error locations.
+	konst    bool            // Whether it holds a constant defined by {{set}}.
+	consts   []*VariableNode // Variables used without declaration; see Constants.
 }
 
 func newDefine(pos Pos, line int, name, parent string, list *ListNode, text string) *DefineNode {
@@ -739,21 +790,70 @@ func newDefine(pos Pos, line int, name, parent string, list *ListNode, text stri
 }
 
 func (d *DefineNode) String() string {
+	if d.konst {
+		return fmt.Sprintf("{{set %s := %s}}", d.Name, d.List.Nodes[0].(*ActionNode).Pipe)
+	}
 	return fmt.Sprintf("{{define %q}}%s{{end}}", d.Name, d.List)
 }
 
+// IsConstant returns whether the node holds a constant defined at the top
+// level of a text by {{set $NAME := pipeline}}, rather than a template. The
+// node is named after the variable, and its list contains a single action
+// with the pipeline.
+func (d *DefineNode) IsConstant() bool {
+	return d.konst
+}
+
+// Constants returns the variables named like constants, as in $SITE_NAME,
+// that the template uses without declaring them. They must be constants
+// defined by {{set}}, maybe in another text, which is checked when the
+// set is compiled.
+func (d *DefineNode) Constants() []*VariableNode {
+	return d.consts
+}
+
 func (d *DefineNode) CopyDefine() *DefineNode {
-	return newDefine(d.Pos, d.Line, d.Name, d.Parent, d.List.CopyList(), d.text)
+	c := newDefine(d.Pos, d.Line, d.Name, d.Parent, d.List.CopyList(), d.text)
+	c.lines = d.lines
+	c.lineDirs = d.lineDirs
+	c.konst = d.konst
+	c.consts = d.consts
+	return c
 }
 
 func (d *DefineNode) Copy() Node {
 	return d.CopyDefine()
 }
 
+// DropText discards the input text the template was parsed from, which is
+// kept only to report error locations. Afterwards ErrorContext reports the
+// template name as location, without line and column.
+func (d *DefineNode) DropText() {
+	d.text = ""
+	d.lines = nil
+	d.lineDirs = nil
+}
+
 // ErrorContext returns a textual representation of the location of the node
 // in the input text.
 func (d *DefineNode) ErrorContext(n Node) (location, context string) {
 	pos := int(n.Position())
+	context = n.String()
+	if len(context) > 20 {
+		context = fmt.Sprintf("%.20s...", context)
+	}
+	if d.lines != nil {
+		// The last offset is past the end of the text.
+		if pos >= d.lines[len(d.lines)-1] {
+			return d.Name, context
+		}
+		lineNum := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > pos })
+		return d.lineDirs.location(d.Name, pos, lineNum, pos-d.lines[lineNum-1]), context
+	}
+	if pos > len(d.text) {
+		// The text was dropped, or the node comes from another template.
+		return d.Name, context
+	}
 	text := d.text[:pos]
 	byteNum := strings.LastIndex(text, "\n")
 	if byteNum == -1 {
@@ -763,11 +863,7 @@ func (d *DefineNode) ErrorContext(n Node) (location, context string) {
 		byteNum = pos - byteNum
 	}
 	lineNum := 1 + strings.Count(text, "\n")
-	context = n.String()
-	if len(context) > 20 {
-		context = fmt.Sprintf("%.20s...", context)
-	}
-	return fmt.Sprintf("%s:%d:%d", d.Name, lineNum, byteNum), context
+	return d.lineDirs.location(d.Name, pos, lineNum, byteNum), context
 }
 
 // SlotNode represents a {{slot}} action.
@@ -777,20 +873,86 @@ type SlotNode struct {
 	Line int       // The line number in the input.
 	Name string    // The name of the slot (unquoted).
 	List *ListNode // Contents of the fill.
+	// The name of the template that provides the contents, if any. The
+	// list then holds a single {{template}} action that calls it.
+	Default string
 }
 
-func newSlot(pos Pos, line int, name string, list *ListNode) *SlotNode {
-	return &SlotNode{NodeType: NodeSlot, Pos: pos, Line: line, Name: name, List: list}
+func newSlot(pos Pos, line int, name string, list *ListNode, def string) *SlotNode {
+	return &SlotNode{NodeType: NodeSlot, Pos: pos, Line: line, Name: name, List: list, Default: def}
 }
 
 func (s *SlotNode) String() string {
+	if s.Default != "" {
+		return fmt.Sprintf("{{slot %q default %q}}{{end}}", s.Name, s.Default)
+	}
 	return fmt.Sprintf("{{slot %q}}%s{{end}}", s.Name, s.List)
 }
 
 func (s *SlotNode) Copy() Node {
-	return newSlot(s.Pos, s.Line, s.Name, s.List.CopyList())
+	return newSlot(s.Pos, s.Line, s.Name, s.List.CopyList(), s.Default)
 }
 
+// ConstNode represents a {{const}} action: a block of a parent template
+// that, unlike a slot, can't be filled by children.
+type ConstNode struct {
+	NodeType
+	Pos
+	Line int       // The line number in the input.
+	Name string    // The name of the block (unquoted).
+	List *ListNode // Contents of the block.
+}
+
+func newConst(pos Pos, line int, name string, list *ListNode) *ConstNode {
+	return &ConstNode{NodeType: NodeConst, Pos: pos, Line: line, Name: name, List: list}
+}
+
+func (c *ConstNode) String() string {
+	return fmt.Sprintf("{{const %q}}%s{{end}}", c.Name, c.List)
+}
+
+func (c *ConstNode) Copy() Node {
+	return newConst(c.Pos, c.Line, c.Name, c.List.CopyList())
+}
+
+// CaptureNode represents a {{capture}} action, which executes its contents
+// and assigns the output to a variable instead of writing it. The variable
+// is visible after the action, until the end of the enclosing control
+// structure, like a variable declared by an action.
+type CaptureNode struct {
+	NodeType
+	Pos
+	Line     int           // The line number in the input.
+	Variable *VariableNode // The variable assigned.
+	List     *ListNode     // Contents of the action.
+	// Content is the type of content of the output, set by contextual
+	// escaping: "html", "css" or "js", or empty for plain text.
+	Content string
+}
+
+func newCapture(pos Pos, line int, variable *VariableNode, list *ListNode) *CaptureNode {
+	return &CaptureNode{NodeType: NodeCapture, Pos: pos, Line: line, Variable: variable, List: list}
+}
+
+func (c *CaptureNode) String() string {
+	return fmt.Sprintf("{{capture %s}}%s{{end}}", c.Variable, c.List)
+}
+
+func (c *CaptureNode) Copy() Node {
+	n := newCapture(c.Pos, c.Line, c.Variable.Copy().(*VariableNode), c.List.CopyList())
+	n.Content = c.Content
+	return n
+}
+
+// FillMode defines how a fill combines with the contents of a slot.
+type FillMode int
+
+const (
+	FillReplace FillMode = iota // The fill replaces the contents.
+	FillAppend                  // The fill is added after the contents.
+	FillPrepend                 // The fill is added before the contents.
+)
+
 // FillNode represents a {{fill}} action.
 type FillNode struct {
 	NodeType
@@ -798,24 +960,70 @@ type FillNode struct {
 	Line int       // The line number in the input.
 	Name string    // The name of the fill (unquoted).
 	List *ListNode // Contents of the fill.
+	Mode FillMode  // How the fill combines with the slot contents.
 }
 
-func newFill(pos Pos, line int, name string, list *ListNode) *FillNode {
-	return &FillNode{NodeType: NodeFill, Line: line, Name: name, List: list}
+func newFill(pos Pos, line int, name string, list *ListNode, mode FillMode) *FillNode {
+	return &FillNode{NodeType: NodeFill, Pos: pos, Line: line, Name: name, List: list, Mode: mode}
 }
 
 func (f *FillNode) String() string {
+	switch f.Mode {
+	case FillAppend:
+		return fmt.Sprintf("{{fill %q append}}%s{{end}}", f.Name, f.List)
+	case FillPrepend:
+		return fmt.Sprintf("{{fill %q prepend}}%s{{end}}", f.Name, f.List)
+	}
 	return fmt.Sprintf("{{fill %q}}%s{{end}}", f.Name, f.List)
 }
 
 func (f *FillNode) CopyFill() *FillNode {
-	return newFill(f.Pos, f.Line, f.Name, f.List.CopyList())
+	return newFill(f.Pos, f.Line, f.Name, f.List.CopyList(), f.Mode)
 }
 
 func (f *FillNode) Copy() Node {
 	return f.CopyFill()
 }
 
+// CustomNode holds a node of a kind defined outside this package. The
+// parser never produces custom nodes: compilation passes insert them, and
+// they are executed by the code registered for their kind.
+type CustomNode struct {
+	NodeType
+	Pos
+	Line int         // The line number in the input.
+	Kind string      // The kind of the node, which selects how it executes.
+	Data interface{} // Data for the execution; shared by copies.
+	Pipe *PipeNode   // Pipeline to evaluate before execution (nil if absent).
+	List *ListNode   // Contents of the node (nil if absent).
+}
+
+// NewCustom returns a new CustomNode with the given kind, data, pipeline
+// and contents. The pipeline and the contents can be nil.
+func NewCustom(kind string, data interface{}, pipe *PipeNode, list *ListNode) *CustomNode {
+	return &CustomNode{NodeType: NodeCustom, Kind: kind, Data: data, Pipe: pipe, List: list}
+}
+
+func (c *CustomNode) String() string {
+	s := fmt.Sprintf("{{custom %q", c.Kind)
+	if c.Pipe != nil {
+		s += " " + c.Pipe.String()
+	}
+	if c.List == nil {
+		return s + "}}"
+	}
+	return fmt.Sprintf("%s}}%s{{end}}", s, c.List)
+}
+
+func (c *CustomNode) CopyCustom() *CustomNode {
+	return &CustomNode{NodeType: NodeCustom, Pos: c.Pos, Line: c.Line, Kind: c.Kind,
+		Data: c.Data, Pipe: c.Pipe.CopyPipe(), List: c.List.CopyList()}
+}
+
+func (c *CustomNode) Copy() Node {
+	return c.CopyCustom()
+}
+
 // Tree stores a collection of DefineNode's.
 type Tree map[string]*DefineNode
 
@@ -847,6 +1055,94 @@ func (t Tree) String() string {
 	return b.String()
 }
 
+// Size returns the approximate number of bytes retained by the tree: the
+// nodes and the input texts. A text shared by templates parsed together is
+// counted once.
+func (t Tree) Size() int {
+	size := 0
+	texts := make(map[string]bool)
+	lines := make(map[*int]bool)
+	for name, d := range t {
+		size += len(name) + int(reflect.TypeOf(*d).Size())
+		size += len(d.Name) + len(d.Parent) + sizeOf(reflect.ValueOf(d.List))
+		if !texts[d.text] {
+			texts[d.text] = true
+			size += len(d.text)
+		}
+		if d.lines != nil && !lines[&d.lines[0]] {
+			lines[&d.lines[0]] = true
+			size += sizeOf(reflect.ValueOf(d.lines))
+		}
+	}
+	return size
+}
+
+// KeepLines replaces the input text of the templates by the offsets of its
+// lines, which use less memory and are enough for ErrorContext to report
+// line and column numbers. Templates parsed from the same text share the
+// offsets.
+func (t Tree) KeepLines() {
+	lines := make(map[string][]int)
+	for _, d := range t {
+		if d.text == "" {
+			continue
+		}
+		l, ok := lines[d.text]
+		if !ok {
+			l = lineOffsets(d.text)
+			lines[d.text] = l
+		}
+		d.text = ""
+		d.lines = l
+	}
+}
+
+// lineOffsets returns the offsets where the lines of text start, followed
+// by an offset past the end of the text.
+func lineOffsets(text string) []int {
+	l := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			l = append(l, i+1)
+		}
+	}
+	return append(l, len(text)+1)
+}
+
+// sizeOf returns the approximate number of bytes referenced by v, not
+// including v itself. Nodes don't contain cycles.
+func sizeOf(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		if v.Kind() == reflect.Ptr || e.Kind() != reflect.Ptr {
+			return int(e.Type().Size()) + sizeOf(e)
+		}
+		return sizeOf(e)
+	case reflect.Slice:
+		size := v.Cap() * int(v.Type().Elem().Size())
+		switch v.Type().Elem().Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.String, reflect.Struct:
+			for i := 0; i < v.Len(); i++ {
+				size += sizeOf(v.Index(i))
+			}
+		}
+		return size
+	case reflect.String:
+		return v.Len()
+	case reflect.Struct:
+		size := 0
+		for i := 0; i < v.NumField(); i++ {
+			size += sizeOf(v.Field(i))
+		}
+		return size
+	}
+	return 0
+}
+
 // Copy returns a deep copy of the tree.
 func (t Tree) Copy() Tree {
 	nt := Tree{}
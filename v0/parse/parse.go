@@ -24,16 +24,44 @@ func Parse(name, text, leftDelim, rightDelim string, funcs ...map[string]interfa
 	return new(parser).parse(name, text, leftDelim, rightDelim, funcs...)
 }
 
+// ParseFile is like Parse, but if the text contains no {{define}} actions
+// it is parsed as the contents of a single template with the given name.
+func ParseFile(name, text, leftDelim, rightDelim string, funcs ...map[string]interface{}) (Tree, error) {
+	if hasDefine(text, leftDelim, rightDelim) {
+		return Parse(name, text, leftDelim, rightDelim, funcs...)
+	}
+	return new(parser).parseBody(name, text, leftDelim, rightDelim, funcs...)
+}
+
+// hasDefine returns whether the text contains a {{define}} action.
+func hasDefine(text, leftDelim, rightDelim string) bool {
+	l := lex("", text, leftDelim, rightDelim)
+	found := false
+	// Drain the lexer so that it finishes.
+	for {
+		switch l.nextItem().typ {
+		case itemDefine:
+			found = true
+		case itemEOF, itemError:
+			return found
+		}
+	}
+}
+
 // parser parses a single template into a tree.
 type parser struct {
 	name      string // template being parsed, for error messages.
 	text      string
+	lineDirs  lineDirectives // line directives of the text.
 	lex       *lexer
 	tree      Tree // tree being built.
 	funcs     []map[string]interface{}
-	vars      []string // variables defined at the moment.
-	token     [3]item  // three-token lookahead for parser.
+	vars      []string          // variables defined at the moment.
+	consts    []*VariableNode   // undeclared variables used, see DefineNode.Constants.
+	imports   map[string]string // paths of the imported files by alias.
+	token     [3]item           // three-token lookahead for parser.
 	peekCount int
+	extendsOK bool // whether an {{extends}} action can come next.
 }
 
 // next returns the next token.
@@ -117,12 +145,13 @@ func (p *parser) ErrorContext(n Node) (location, context string) {
 	if len(context) > 20 {
 		context = fmt.Sprintf("%.20s...", context)
 	}
-	return fmt.Sprintf("%s:%d:%d", p.name, lineNum, byteNum), context
+	return p.lineDirs.location(p.name, pos, lineNum, byteNum), context
 }
 
 // errorf formats the error and terminates processing.
 func (p *parser) errorf(format string, args ...interface{}) {
-	format = fmt.Sprintf("template: %s:%d: %s", p.name, p.lex.lineNumber(), format)
+	name, line := p.lineDirs.position(p.name, int(p.lex.lastPos), p.lex.lineNumber())
+	format = fmt.Sprintf("template: %s:%d: %s", name, line, format)
 	panic(fmt.Errorf(format, args...))
 }
 
@@ -194,6 +223,7 @@ func (p *parser) parse(name, text, leftDelim, rightDelim string, funcs ...map[st
 	p.name = name
 	p.text = text
 	p.lex = lex(name, text, leftDelim, rightDelim)
+	p.lineDirs = lineDirectivesOf(text, p.lex.leftDelim, p.lex.rightDelim)
 	p.tree = make(Tree)
 	p.funcs = funcs
 	p.vars = []string{"$"}
@@ -202,6 +232,9 @@ func (p *parser) parse(name, text, leftDelim, rightDelim string, funcs ...map[st
 		case itemEOF:
 			return p.tree, nil
 		case itemLeftDelim:
+			if p.rootControl() {
+				continue
+			}
 			token := p.expect(itemDefine, "template root")
 			if err = p.tree.Add(p.parseDefinition(token.pos)); err != nil {
 				p.error(err)
@@ -211,14 +244,81 @@ func (p *parser) parse(name, text, leftDelim, rightDelim string, funcs ...map[st
 	return p.tree, nil
 }
 
+// parseBody parses the whole text as the contents of a template with the
+// given name, and returns a tree containing only that template.
+func (p *parser) parseBody(name, text, leftDelim, rightDelim string, funcs ...map[string]interface{}) (tree Tree, err error) {
+	defer p.recover(&err)
+	p.name = name
+	p.text = text
+	p.lex = lex(name, text, leftDelim, rightDelim)
+	p.lineDirs = lineDirectivesOf(text, p.lex.leftDelim, p.lex.rightDelim)
+	p.tree = make(Tree)
+	p.funcs = funcs
+	p.vars = []string{"$"}
+	p.extendsOK = true
+	list := newList(p.peek().pos)
+	for p.peek().typ != itemEOF {
+		if delim := p.next(); delim.typ == itemLeftDelim {
+			if p.rootControl() {
+				continue
+			}
+			p.backup2(delim)
+		} else {
+			p.backup()
+		}
+		n := p.textOrAction()
+		if n.Type() == nodeEnd || n.Type() == nodeElse {
+			p.errorf("unexpected %s", n)
+		}
+		list.append(n)
+	}
+	define := newDefine(0, 1, name, p.takeExtends(list), list, text)
+	define.lineDirs = p.lineDirs
+	define.consts = p.consts
+	p.tree.Add(define)
+	return p.tree, nil
+}
+
+// ParseExpr parses text as the pipeline of a single action, without the
+// delimiters, as in `.User.Name | printf "%q"`, and returns a template with
+// the given name holding only that action. The template is not part of a
+// tree; the text can't declare variables.
+func ParseExpr(name, text string, funcs ...map[string]interface{}) (define *DefineNode, err error) {
+	p := new(parser)
+	defer p.recover(&err)
+	// The delimiters make the lexer start inside an action.
+	text = "{{" + text + "}}"
+	p.name = name
+	p.text = text
+	p.lex = lex(name, text, "", "")
+	p.funcs = funcs
+	p.vars = []string{"$"}
+	const context = "expression"
+	p.expect(itemLeftDelim, context)
+	action := newAction(p.peekNonSpace().pos, p.lex.lineNumber(), p.pipeline(context))
+	if len(action.Pipe.Decl) > 0 {
+		p.errorf("unexpected variable declaration in %s", context)
+	}
+	if token := p.next(); token.typ != itemEOF {
+		p.unexpected(token, context)
+	}
+	list := newList(action.Pos)
+	list.append(action)
+	return newDefine(0, 1, name, "", list, text), nil
+}
+
 // parseDefinition parses a {{define}} ... {{end}} template definition and
 // returns a defineNode. The "define" keyword has already been scanned.
 //
 //	{{define stringValue}} itemList {{end}}
 //	{{define stringValue stringValue}} itemList {{end}}
+//
+// The parent can also be set by an {{extends}} action at the start of the
+// item list.
 func (p *parser) parseDefinition(pos Pos) *DefineNode {
 	const context = "define clause"
 	defer p.popVars(1)
+	p.consts = nil
 	line := p.lex.lineNumber()
 	var name, parent string
 	token := p.nextNonSpace()
@@ -245,11 +345,21 @@ func (p *parser) parseDefinition(pos Pos) *DefineNode {
 	default:
 		p.unexpected(token, context)
 	}
+	p.extendsOK = true
 	list, end := p.itemList()
 	if end.Type() != nodeEnd {
 		p.errorf("unexpected %s in %s", end, context)
 	}
-	return newDefine(pos, line, name, parent, list, p.text)
+	if extends := p.takeExtends(list); extends != "" {
+		if parent != "" {
+			p.errorf("template %q extends both %q and %q", name, parent, extends)
+		}
+		parent = extends
+	}
+	define := newDefine(pos, line, name, parent, list, p.text)
+	define.lineDirs = p.lineDirs
+	define.consts = p.consts
+	return define
 }
 
 // itemList:
@@ -274,6 +384,9 @@ func (p *parser) itemList() (list *ListNode, next Node) {
 func (p *parser) textOrAction() Node {
 	switch token := p.nextNonSpace(); token.typ {
 	case itemText:
+		if strings.TrimSpace(token.val) != "" {
+			p.extendsOK = false
+		}
 		return newText(token.pos, token.val)
 	case itemLeftDelim:
 		return p.action()
@@ -289,13 +402,17 @@ func (p *parser) textOrAction() Node {
 // Left delim is past. Now get actions.
 // First word could be a keyword such as range.
 func (p *parser) action() (n Node) {
-	switch token := p.nextNonSpace(); token.typ {
+	extendsOK := p.extendsOK
+	p.extendsOK = false
+	switch token := p.keyword(p.nextNonSpace()); token.typ {
 	case itemElse:
 		return p.elseControl()
 	case itemEnd:
 		return p.endControl()
 	case itemIf:
 		return p.ifControl()
+	case itemLet:
+		return p.letControl()
 	case itemRange:
 		return p.rangeControl()
 	case itemTemplate:
@@ -306,8 +423,19 @@ func (p *parser) action() (n Node) {
 		return p.slotControl()
 	case itemFill:
 		return p.fillControl()
+	case itemConst:
+		return p.constControl()
+	case itemVariant:
+		return p.variantControl()
+	case itemCapture:
+		return p.captureControl()
+	case itemExtends:
+		return p.extendsControl(extendsOK)
 	}
 	p.backup()
+	if token := p.peekNonSpace(); token.typ == itemIdentifier && p.imports[token.val] != "" {
+		return p.importedTemplate()
+	}
 	// Do not pop variables; they persist until "end".
 	return newAction(p.peek().pos, p.lex.lineNumber(), p.pipeline("command"))
 }
@@ -347,7 +475,7 @@ func (p *parser) pipeline(context string) (pipe *PipeNode) {
 	}
 	pipe = newPipeline(pos, p.lex.lineNumber(), decl)
 	for {
-		switch token := p.nextNonSpace(); token.typ {
+		switch token := p.keyword(p.nextNonSpace()); token.typ {
 		case itemRightDelim, itemRightParen:
 			if len(pipe.Cmds) == 0 {
 				p.errorf("missing value for %s", context)
@@ -393,6 +521,23 @@ func (p *parser) ifControl() Node {
 	return newIf(p.parseControl("if"))
 }
 
+// Let:
+//	{{let $x := pipeline}} itemList {{end}}
+// Let keyword is past.
+func (p *parser) letControl() Node {
+	defer p.popVars(len(p.vars))
+	line := p.lex.lineNumber()
+	pipe := p.pipeline("let")
+	if len(pipe.Decl) == 0 {
+		p.errorf("missing variable declaration in let")
+	}
+	list, next := p.itemList()
+	if next.Type() != nodeEnd {
+		p.errorf("expected end; found %s", next)
+	}
+	return newLet(pipe.Position(), line, pipe, list)
+}
+
 // Range:
 //	{{range pipeline}} itemList {{end}}
 //	{{range pipeline}} itemList {{else}} itemList {{end}}
@@ -423,6 +568,48 @@ func (p *parser) elseControl() Node {
 	return newElse(p.expect(itemRightDelim, "else").pos, p.lex.lineNumber())
 }
 
+// Extends:
+//	{{extends stringValue}}
+// Extends keyword is past. The action sets the parent of the template, like
+// the second argument of {{define}}, so it must come before any other text
+// or action in the template; ok reports whether it does.
+func (p *parser) extendsControl(ok bool) Node {
+	const context = "extends clause"
+	token := p.nextNonSpace()
+	var parent string
+	switch token.typ {
+	case itemString, itemRawString:
+		s, err := strconv.Unquote(token.val)
+		if err != nil {
+			p.error(err)
+		}
+		parent = s
+	default:
+		p.unexpected(token, context)
+	}
+	p.expect(itemRightDelim, context)
+	if !ok {
+		p.errorf("{{extends}} must be at the start of the template")
+	}
+	return newExtends(token.pos, parent)
+}
+
+// takeExtends removes the {{extends}} action at the start of list, and the
+// spaces before it, and returns the parent it sets. It returns an empty
+// string if there is no such action.
+func (p *parser) takeExtends(list *ListNode) string {
+	for i, n := range list.Nodes {
+		if e, ok := n.(*extendsNode); ok {
+			list.Nodes = list.Nodes[i+1:]
+			return e.Parent
+		}
+		if n.Type() != NodeText {
+			break
+		}
+	}
+	return ""
+}
+
 // Template:
 //	{{template stringValue pipeline}}
 // Template keyword is past.  The name must be something that can evaluate
@@ -449,11 +636,190 @@ func (p *parser) templateControl() Node {
 	return newTemplate(token.pos, p.lex.lineNumber(), name, pipe)
 }
 
+// rootControl parses an action allowed only at the top level, if the next
+// token starts one, and returns whether it did. The left delimiter is past.
+func (p *parser) rootControl() bool {
+	switch token := p.keyword(p.peekNonSpace()); token.typ {
+	case itemImport:
+		p.next()
+		p.importControl()
+	case itemSet:
+		p.next()
+		if err := p.tree.Add(p.setControl(token.pos)); err != nil {
+			p.error(err)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// Set:
+//	{{set $NAME := pipeline}}
+// Set keyword is past. See DefineNode.IsConstant.
+func (p *parser) setControl(pos Pos) *DefineNode {
+	defer p.popVars(len(p.vars))
+	defer func(consts []*VariableNode) { p.consts = consts }(p.consts)
+	p.consts = nil
+	line := p.lex.lineNumber()
+	pipe := p.pipeline("set")
+	if len(pipe.Decl) != 1 {
+		p.errorf("expected one variable declaration in set")
+	}
+	name := pipe.Decl[0].Ident
+	if len(name) != 1 || !isConstantName(name[0]) {
+		p.errorf("set constant %s must be named in upper case, as in $SITE_NAME", pipe.Decl[0])
+	}
+	pipe.Decl = nil
+	list := newList(pipe.Position())
+	list.append(newAction(pipe.Position(), line, pipe))
+	define := newDefine(pos, line, name[0], "", list, p.text)
+	define.lineDirs = p.lineDirs
+	define.consts = p.consts
+	define.konst = true
+	return define
+}
+
+// isConstantName returns whether name is the name of a constant defined by
+// {{set}}: a dollar sign followed by upper case letters, digits and
+// underscores, starting with a letter.
+func isConstantName(name string) bool {
+	if len(name) < 2 || name[0] != '$' || name[1] < 'A' || name[1] > 'Z' {
+		return false
+	}
+	for _, r := range name[2:] {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// Import:
+//	{{import stringValue as alias}}
+// Import keyword is past. Imports are allowed only at the top level; the
+// alias is valid until the end of the text.
+func (p *parser) importControl() {
+	const context = "import"
+	var path string
+	token := p.nextNonSpace()
+	switch token.typ {
+	case itemString, itemRawString:
+		s, err := strconv.Unquote(token.val)
+		if err != nil {
+			p.error(err)
+		}
+		path = s
+	default:
+		p.unexpected(token, context)
+	}
+	if token = p.nextNonSpace(); token.typ != itemIdentifier || token.val != "as" {
+		p.errorf("expected as in %s; got %s", context, token)
+	}
+	alias := p.expect(itemIdentifier, context).val
+	p.expect(itemRightDelim, context)
+	if p.imports == nil {
+		p.imports = make(map[string]string)
+	}
+	p.imports[alias] = path
+}
+
+// importedTemplate parses the invocation of a template defined in an
+// imported file, as in {{h.button "OK"}}, which is equivalent to
+// {{template "button" "OK"}} with the template name qualified by the path
+// of the file.
+func (p *parser) importedTemplate() Node {
+	token := p.nextNonSpace()
+	field := p.next()
+	if field.typ != itemField {
+		p.errorf("expected template name after import alias %q; got %s", token.val, field)
+	}
+	var pipe *PipeNode
+	if p.nextNonSpace().typ != itemRightDelim {
+		p.backup()
+		// Do not pop variables; they persist until "end".
+		pipe = p.pipeline("template")
+	}
+	name := ImportName(p.imports[token.val], field.val[1:])
+	return newTemplate(token.pos, p.lex.lineNumber(), name, pipe)
+}
+
+// ImportName returns the name under which a template defined in an
+// imported file is added to a set: the template name qualified by the
+// path of the file.
+func ImportName(path, name string) string {
+	return path + "#" + name
+}
+
+// SplitImportName splits a name returned by ImportName in the path of the
+// file and the template name. It returns ok == false if the name is not
+// qualified.
+func SplitImportName(qualified string) (path, name string, ok bool) {
+	i := strings.LastIndex(qualified, "#")
+	if i < 0 {
+		return "", qualified, false
+	}
+	return qualified[:i], qualified[i+1:], true
+}
+
 // Slot:
-//	{{slot stringValue}}
+//	{{slot stringValue}} itemList {{end}}
+//	{{slot stringValue default stringValue}}{{end}}
 // Slot keyword is past.
 func (p *parser) slotControl() Node {
 	const context = "slot definition"
+	var name, def string
+	token := p.nextNonSpace()
+	switch token.typ {
+	case itemString, itemRawString:
+		s, err := strconv.Unquote(token.val)
+		if err != nil {
+			p.error(err)
+		}
+		name = s
+	default:
+		p.unexpected(token, context)
+	}
+	if next := p.nextNonSpace(); next.typ == itemIdentifier && next.val == "default" {
+		next = p.nextNonSpace()
+		switch next.typ {
+		case itemString, itemRawString:
+			s, err := strconv.Unquote(next.val)
+			if err != nil {
+				p.error(err)
+			}
+			def = s
+		default:
+			p.unexpected(next, context)
+		}
+	} else {
+		p.backup()
+	}
+	p.expect(itemRightDelim, context)
+	line := p.lex.lineNumber()
+	list, end := p.itemList()
+	if end.Type() != nodeEnd {
+		p.errorf("unexpected %s in %s", end, context)
+	}
+	if def != "" {
+		if len(list.Nodes) != 0 {
+			p.errorf("slot %q with a default template can't have contents", name)
+		}
+		// The contents call the default template, passing dot.
+		pipe := newPipeline(token.pos, line, nil)
+		cmd := newCommand(token.pos)
+		cmd.append(newDot(token.pos))
+		pipe.append(cmd)
+		list.append(newTemplate(token.pos, line, def, pipe))
+	}
+	return newSlot(token.pos, p.lex.lineNumber(), name, list, def)
+}
+
+// Const:
+//	{{const stringValue}} itemList {{end}}
+// Const keyword is past.
+func (p *parser) constControl() Node {
+	const context = "const definition"
 	var name string
 	token := p.nextNonSpace()
 	switch token.typ {
@@ -471,15 +837,18 @@ func (p *parser) slotControl() Node {
 	if end.Type() != nodeEnd {
 		p.errorf("unexpected %s in %s", end, context)
 	}
-	return newSlot(token.pos, p.lex.lineNumber(), name, list)
+	return newConst(token.pos, p.lex.lineNumber(), name, list)
 }
 
 // Fill:
 //	{{fill stringValue}} itemList {{end}}
+//	{{fill stringValue append}} itemList {{end}}
+//	{{fill stringValue prepend}} itemList {{end}}
 // Fill keyword is past.
 func (p *parser) fillControl() Node {
 	const context = "fill definition"
 	var name string
+	var mode FillMode
 	token := p.nextNonSpace()
 	switch token.typ {
 	case itemString, itemRawString:
@@ -491,12 +860,170 @@ func (p *parser) fillControl() Node {
 	default:
 		p.unexpected(token, context)
 	}
+	switch next := p.nextNonSpace(); {
+	case next.typ == itemIdentifier && next.val == "append":
+		mode = FillAppend
+	case next.typ == itemIdentifier && next.val == "prepend":
+		mode = FillPrepend
+	default:
+		p.backup()
+	}
 	p.expect(itemRightDelim, context)
 	list, end := p.itemList()
 	if end.Type() != nodeEnd {
 		p.errorf("unexpected %s in %s", end, context)
 	}
-	return newFill(token.pos, p.lex.lineNumber(), name, list)
+	return newFill(token.pos, p.lex.lineNumber(), name, list, mode)
+}
+
+// Capture:
+//	{{capture $x}} itemList {{end}}
+// Capture keyword is past. The variable is declared after the contents,
+// and persists until "end" like the variables declared by actions.
+func (p *parser) captureControl() Node {
+	const context = "capture"
+	token := p.nextNonSpace()
+	if token.typ != itemVariable {
+		p.unexpected(token, context)
+	}
+	p.expect(itemRightDelim, context)
+	line := p.lex.lineNumber()
+	list, end := p.captureList()
+	if end.Type() != nodeEnd {
+		p.errorf("unexpected %s in %s", end, context)
+	}
+	p.vars = append(p.vars, token.val)
+	return newCapture(token.pos, line, newVariable(token.pos, token.val), list)
+}
+
+// captureList parses the contents of a capture, whose variables are only
+// visible inside.
+func (p *parser) captureList() (list *ListNode, next Node) {
+	defer p.popVars(len(p.vars))
+	return p.itemList()
+}
+
+// VariantFunc is the name of the function called by {{variant}} actions to
+// get the variant of an experiment to render. It is called with the name
+// of the experiment, the data of the execution ($) and the names of the
+// cases, and returns the name of a case, or "" to render the else branch.
+const VariantFunc = "html_template_variant"
+
+// VariantEqFunc is the name of the function called by {{variant}} actions
+// to compare the chosen variant with the name of a case. Unlike eq, it
+// can't be overridden.
+const VariantEqFunc = "html_template_varianteq"
+
+// variantVar is the variable holding the variant chosen by a {{variant}}
+// action. It can't be written in templates.
+const variantVar = "$#variant"
+
+// Variant:
+//	{{variant stringValue}} ({{case stringValue}} itemList {{end}})* {{end}}
+//	{{variant stringValue}} ({{case stringValue}} itemList {{end}})* {{else}} itemList {{end}}
+// Variant keyword is past. Only spaces can separate the cases. The action
+// is rewritten as
+//	{{let $#variant := html_template_variant "exp" $ "a" "b"}}
+//	{{if html_template_varianteq $#variant "a"}}...{{else}}...{{end}}
+//	{{end}}
+func (p *parser) variantControl() Node {
+	const context = "variant"
+	var experiment string
+	token := p.nextNonSpace()
+	switch token.typ {
+	case itemString, itemRawString:
+		s, err := strconv.Unquote(token.val)
+		if err != nil {
+			p.error(err)
+		}
+		experiment = s
+	default:
+		p.unexpected(token, context)
+	}
+	p.expect(itemRightDelim, context)
+	line := p.lex.lineNumber()
+	var names []string
+	var lists []*ListNode
+	var elseList *ListNode
+Cases:
+	for {
+		switch next := p.nextNonSpace(); next.typ {
+		case itemText:
+			if strings.TrimSpace(next.val) != "" {
+				p.errorf("unexpected text in %s; expected case", context)
+			}
+			continue
+		case itemLeftDelim:
+		default:
+			p.unexpected(next, context)
+		}
+		switch next := p.nextNonSpace(); next.typ {
+		case itemCase:
+			name := p.nextNonSpace()
+			if name.typ != itemString && name.typ != itemRawString {
+				p.unexpected(name, "case")
+			}
+			s, err := strconv.Unquote(name.val)
+			if err != nil {
+				p.error(err)
+			}
+			for _, n := range names {
+				if n == s {
+					p.errorf("duplicate case %q in variant %q", s, experiment)
+				}
+			}
+			p.expect(itemRightDelim, "case")
+			list, end := p.itemList()
+			if end.Type() != nodeEnd {
+				p.errorf("unexpected %s in case", end)
+			}
+			names = append(names, s)
+			lists = append(lists, list)
+		case itemElse:
+			p.expect(itemRightDelim, "else")
+			var end Node
+			elseList, end = p.itemList()
+			if end.Type() != nodeEnd {
+				p.errorf("expected end; found %s", end)
+			}
+			break Cases
+		case itemEnd:
+			p.expect(itemRightDelim, "end")
+			break Cases
+		default:
+			p.unexpected(next, context)
+		}
+	}
+	if len(names) == 0 {
+		p.errorf("variant %q has no cases", experiment)
+	}
+	pos := token.pos
+	str := func(s string) *StringNode {
+		return newString(pos, strconv.Quote(s), s)
+	}
+	// The variable holding the chosen variant.
+	pipe := newPipeline(pos, line, []*VariableNode{newVariable(pos, variantVar)})
+	cmd := newCommand(pos)
+	cmd.append(NewIdentifier(VariantFunc).SetPos(pos))
+	cmd.append(str(experiment))
+	cmd.append(newVariable(pos, "$"))
+	for _, name := range names {
+		cmd.append(str(name))
+	}
+	pipe.append(cmd)
+	// The chain of cases, from the last one.
+	for i := len(names) - 1; i >= 0; i-- {
+		cond := newPipeline(pos, line, nil)
+		cmd := newCommand(pos)
+		cmd.append(NewIdentifier(VariantEqFunc).SetPos(pos))
+		cmd.append(newVariable(pos, variantVar))
+		cmd.append(str(names[i]))
+		cond.append(cmd)
+		list := newList(pos)
+		list.append(newIf(pos, line, cond, lists[i], elseList))
+		elseList = list
+	}
+	return newLet(pos, line, pipe, elseList)
 }
 
 // command:
@@ -510,6 +1037,9 @@ func (p *parser) command() *CommandNode {
 		operand := p.operand()
 		if operand != nil {
 			cmd.append(operand)
+			if operand.Type() == NodeIdentifier {
+				p.filterArgs(cmd)
+			}
 		}
 		switch token := p.next(); token.typ {
 		case itemSpace:
@@ -530,6 +1060,20 @@ func (p *parser) command() *CommandNode {
 	return cmd
 }
 
+// filterArgs parses the arguments following a function name in the filter
+// form, as in {{.Name | truncate:30}}, and appends them to the command.
+//	(':' operand)*
+func (p *parser) filterArgs(cmd *CommandNode) {
+	for p.peek().typ == itemColon {
+		p.next()
+		arg := p.operand()
+		if arg == nil {
+			p.errorf("missing argument after colon in command")
+		}
+		cmd.append(arg)
+	}
+}
+
 // operand:
 //	term .Field*
 // An operand is a space-separated component of a command,
@@ -571,14 +1115,20 @@ func (p *parser) operand() Node {
 // A term is a simple "expression".
 // A nil return means the next item is not a term.
 func (p *parser) term() Node {
-	switch token := p.nextNonSpace(); token.typ {
+	switch token := p.keyword(p.nextNonSpace()); token.typ {
 	case itemError:
 		p.errorf("%s", token.val)
 	case itemIdentifier:
-		if !p.hasFunction(token.val) {
-			p.errorf("function %q not defined", token.val)
+		// Functions in a namespace are spelled as an identifier followed
+		// by fields, as in strings.upper.
+		name := token.val
+		for p.peek().typ == itemField && p.hasFunction(name+p.peek().val) {
+			name += p.next().val
 		}
-		return NewIdentifier(token.val).SetPos(token.pos)
+		if !p.hasFunction(name) {
+			p.errorf("function %q not defined", name)
+		}
+		return NewIdentifier(name).SetPos(token.pos)
 	case itemDot:
 		return newDot(token.pos)
 	case itemNil:
@@ -612,6 +1162,15 @@ func (p *parser) term() Node {
 	return nil
 }
 
+// keyword returns the token, turned into an identifier if it is a
+// contextual keyword naming a registered function.
+func (p *parser) keyword(token item) item {
+	if token.typ > itemContextual && p.hasFunction(token.val) {
+		token.typ = itemIdentifier
+	}
+	return token
+}
+
 // hasFunction reports if a function name exists in the Tree's maps.
 func (p *parser) hasFunction(name string) bool {
 	for _, funcMap := range p.funcs {
@@ -631,7 +1190,7 @@ func (p *parser) popVars(n int) {
 }
 
 // useVar returns a node for a variable reference. It errors if the
-// variable is not defined.
+// variable is not defined, unless it is named like a constant.
 func (p *parser) useVar(pos Pos, name string) Node {
 	v := newVariable(pos, name)
 	for _, varName := range p.vars {
@@ -639,6 +1198,12 @@ func (p *parser) useVar(pos Pos, name string) Node {
 			return v
 		}
 	}
+	if isConstantName(v.Ident[0]) {
+		// A constant defined by {{set}}, maybe in another text; it is
+		// checked when the set is compiled.
+		p.consts = append(p.consts, v)
+		return v
+	}
 	p.errorf("undefined variable %q", v.Ident[0])
 	return nil
 }
@@ -220,12 +220,29 @@ var parseTests = []parseTest{
 		`{{with .X}}hello{{end}}`},
 	{"with with else", "{{with .X}}hello{{else}}goodbye{{end}}", noError,
 		`{{with .X}}hello{{else}}goodbye{{end}}`},
+	{"let", "{{let $x := .X}}{{$x}}{{end}}", noError,
+		`{{let $x := .X}}{{$x}}{{end}}`},
+	{"capture", "{{capture $x}}{{.X}}{{end}}{{$x}}", noError,
+		`{{capture $x}}{{.X}}{{end}}{{$x}}`},
+	{"upper case variable", "{{$X := 1}}{{capture $Y}}{{end}}{{$X}}{{$Y}}", noError,
+		`{{$X := 1}}{{capture $Y}}{{end}}{{$X}}{{$Y}}`},
+	{"constant", "{{$SITE_NAME}}", noError,
+		`{{$SITE_NAME}}`},
+	{"namespaced function", "{{strings.upper .X}}", noError,
+		`{{strings.upper .X}}`},
+	{"namespaced function in pipeline", "{{.X | strings.upper | printf `%s`}}", noError,
+		"{{.X | strings.upper | printf `%s`}}"},
+	{"filter arguments", "{{.X | printf:`%s`:3 | strings.upper}}", noError,
+		"{{.X | printf `%s` 3 | strings.upper}}"},
+	{"filter argument field", "{{printf:.Y.Z}}", noError,
+		`{{printf .Y.Z}}`},
 	// Errors.
 	{"unclosed action", "hello{{range", hasError, ""},
 	{"unmatched end", "{{end}}", hasError, ""},
 	{"missing end", "hello{{range .x}}", hasError, ""},
 	{"missing end after else", "hello{{range .x}}{{else}}", hasError, ""},
 	{"undefined function", "hello{{undefined}}", hasError, ""},
+	{"undefined namespaced function", "hello{{strings.undefined}}", hasError, ""},
 	{"undefined variable", "{{$x}}", hasError, ""},
 	{"variable undefined after end", "{{with $x := 4}}{{end}}{{$x}}", hasError, ""},
 	{"variable undefined in template", "{{template $v}}", hasError, ""},
@@ -234,10 +251,22 @@ var parseTests = []parseTest{
 	{"template with var", "{{template $v}}", hasError, ""},
 	{"invalid punctuation", "{{printf 3, 4}}", hasError, ""},
 	{"multidecl outside range", "{{with $v, $u := 3}}{{end}}", hasError, ""},
+	{"let without declaration", "{{let .X}}{{end}}", hasError, ""},
+	{"let with else", "{{let $x := .X}}{{else}}{{end}}", hasError, ""},
+	{"variable undefined after let", "{{let $x := 4}}{{end}}{{$x}}", hasError, ""},
+	{"capture without variable", "{{capture .X}}{{end}}", hasError, ""},
+	{"capture with else", "{{capture $x}}{{else}}{{end}}", hasError, ""},
+	{"extends after text", "x{{extends `p`}}", hasError, ""},
+	{"extends after action", "{{.X}}{{extends `p`}}", hasError, ""},
+	{"extends in if", "{{if .X}}{{extends `p`}}{{end}}", hasError, ""},
+	{"extends twice", "{{extends `p`}}{{extends `q`}}", hasError, ""},
+	{"extends without name", "{{extends .X}}", hasError, ""},
 	{"too many decls in range", "{{range $u, $v, $w := 3}}{{end}}", hasError, ""},
 	{"dot applied to parentheses", "{{printf (printf .).}}", hasError, ""},
 	{"adjacent args", "{{printf 3`x`}}", hasError, ""},
 	{"adjacent args with .", "{{printf `x`.}}", hasError, ""},
+	{"missing filter argument", "{{.X | printf:}}", hasError, ""},
+	{"filter argument after field", "{{.X:3}}", hasError, ""},
 	// Equals (and other chars) do not assignments make (yet).
 	{"bug0a", "{{$x := 0}}{{$x}}", noError, "{{$x := 0}}{{$x}}"},
 	{"bug0b", "{{$x = 1}}{{$x}}", hasError, ""},
@@ -252,7 +281,8 @@ var parseTests = []parseTest{
 }
 
 var builtins = map[string]interface{}{
-	"printf": fmt.Sprintf,
+	"printf":        fmt.Sprintf,
+	"strings.upper": strings.ToUpper,
 }
 
 func testParse(doCopy bool, t *testing.T) {
@@ -285,6 +315,97 @@ func testParse(doCopy bool, t *testing.T) {
 	}
 }
 
+func TestParseImport(t *testing.T) {
+	tree, err := Parse("import", `{{import "h.tmpl" as h}}{{define "x"}}{{h.button}}{{h.icon .X}}{{end}}`, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{{define "x"}}{{template "h.tmpl#button"}}{{template "h.tmpl#icon" .X}}{{end}}`
+	if got := tree["x"].String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	path, name, ok := SplitImportName("h.tmpl#button")
+	if path != "h.tmpl" || name != "button" || !ok {
+		t.Errorf("got %q, %q, %v", path, name, ok)
+	}
+	tree, err = ParseFile("import", "{{import `h.tmpl` as h}}\n{{h.button}}", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tree["import"].List.String(), "\n"+`{{template "h.tmpl#button"}}`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, text := range []string{
+		`{{import "h.tmpl"}}`,
+		`{{import "h.tmpl" h}}`,
+		`{{define "x"}}{{import "h.tmpl" as h}}{{end}}`,
+		`{{import "h.tmpl" as h}}{{define "x"}}{{h}}{{end}}`,
+	} {
+		if _, err := Parse("import", text, "", ""); err == nil {
+			t.Errorf("%s: expected error", text)
+		}
+	}
+}
+
+func TestParseExtends(t *testing.T) {
+	tree, err := Parse("extends", "{{define `a`}}\n  {{extends `b`}}\n{{fill `x`}}{{end}}{{end}}", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tree["a"].Parent, "b"; got != want {
+		t.Errorf("got parent %q, want %q", got, want)
+	}
+	if got, want := tree["a"].List.String(), "\n"+`{{fill "x"}}{{end}}`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	tree, err = ParseFile("page", "{{import `h.tmpl` as h}}{{extends `base`}}{{fill `x`}}{{end}}", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tree["page"].Parent, "base"; got != want {
+		t.Errorf("got parent %q, want %q", got, want)
+	}
+	if _, err = Parse("extends", "{{define `a` `b`}}{{extends `c`}}{{end}}", "", ""); err == nil {
+		t.Errorf("expected error for a template extending two parents")
+	}
+}
+
+func TestParseExpr(t *testing.T) {
+	define, err := ParseExpr("expr", `.X | printf "%s}}"`, builtins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := define.List.String(), `{{.X | printf "%s}}"}}`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, text := range []string{"", ".X}}text", ".X}}{{.Y", "end", "$x := .X", "undefined"} {
+		if _, err := ParseExpr("expr", text, builtins); err == nil {
+			t.Errorf("%q: expected error", text)
+		}
+	}
+}
+
+func TestContextualKeywords(t *testing.T) {
+	funcs := map[string]interface{}{}
+	for _, name := range []string{"let", "const", "import", "set", "variant", "case", "capture", "extends"} {
+		funcs[name] = fmt.Sprint
+		text := fmt.Sprintf(`{{define "t"}}{{%s 1}}{{.X | %s}}{{end}}`, name, name)
+		tree, err := Parse("t", text, "", "", funcs)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got, want := tree["t"].List.String(), fmt.Sprintf("{{%s 1}}{{.X | %s}}", name, name); got != want {
+			t.Errorf("%s: expected %q; got %q", name, want, got)
+		}
+		// Without the function, the keyword can't be used as one.
+		text = fmt.Sprintf(`{{define "t"}}{{.X | %s}}{{end}}`, name)
+		if _, err := Parse("t", text, "", "", builtins); err == nil || !strings.Contains(err.Error(), "unexpected <"+name+">") {
+			t.Errorf("%s: expected unexpected keyword error; got %v", name, err)
+		}
+	}
+}
+
 func TestParse(t *testing.T) {
 	testParse(false, t)
 }
@@ -294,6 +415,33 @@ func TestParseCopy(t *testing.T) {
 	testParse(true, t)
 }
 
+func TestEncode(t *testing.T) {
+	encode := func(text string) string {
+		tree, err := Parse("t", `{{define "t"}}`+text+`{{end}}`, "", "", builtins)
+		if err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+		return string(Encode(tree["t"].List))
+	}
+	if got, want := encode("{{.X}}"), `(list ((action (pipe () ((command ((field ("X")))))))))`; got != want {
+		t.Errorf("expected %s; got %s", want, got)
+	}
+	for _, test := range []struct {
+		a, b  string
+		equal bool
+	}{
+		{"{{.X}}", "{{ .X }}", true},
+		{"{{printf `%d` 1}}", `{{printf "%d" 0x1}}`, true},
+		{"{{if .X}}a{{end}}", "{{if .X}}a{{else}}{{end}}", false},
+		{"{{.X}}", "{{`{{.X}}`}}", false},
+		{`{{"a" | printf "%s%s" "b"}}`, `{{printf "%s%s" "b" "a"}}`, false},
+	} {
+		if equal := encode(test.a) == encode(test.b); equal != test.equal {
+			t.Errorf("%q and %q: expected equal encodings to be %v", test.a, test.b, test.equal)
+		}
+	}
+}
+
 type isEmptyTest struct {
 	name  string
 	input string
@@ -372,3 +520,35 @@ func TestErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestLineDirective(t *testing.T) {
+	// Parsing errors.
+	_, err := Parse("a", "{{define `a`}}\n{{/*line src/page.tmpl:10*/}}\nx\n{{.X 1 2 3)}}{{end}}", "", "")
+	if err == nil || !strings.HasPrefix(err.Error(), "template: src/page.tmpl:12: ") {
+		t.Errorf("expected error at src/page.tmpl:12, got %v", err)
+	}
+	// Locations of nodes, with other delimiters.
+	text := "<%define `a`%>x<%/*line notadirective*/%>\n<%/*line gen.tmpl:5*/%>\n  <%.Y%><%end%>"
+	tree, err := Parse("a", text, "<%", "%>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	define := tree["a"]
+	var action Node
+	for _, n := range define.List.Nodes {
+		if n.Type() == NodeAction {
+			action = n
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if location, _ := define.ErrorContext(action); location != "gen.tmpl:6:4" {
+			t.Errorf("expected location gen.tmpl:6:4, got %s", location)
+		}
+		// The directives are kept with the offsets of the lines.
+		tree.KeepLines()
+	}
+	define.DropText()
+	if location, _ := define.ErrorContext(action); location != "a" {
+		t.Errorf("expected location a, got %s", location)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// PreparedTemplate is a compiled template ready to be executed repeatedly,
+// for example to render a batch of emails. It can be executed concurrently.
+type PreparedTemplate struct {
+	snap *Snapshot
+	tmpl *parse.DefineNode
+}
+
+// Prepare compiles the set and returns the named template prepared for
+// execution. The template, the functions and the execution options are
+// looked up once, so executing it skips the per-call work of Execute.
+// The prepared template keeps using the templates current when Prepare
+// was called, even if they are later replaced with Swap.
+func (s *Set) Prepare(name string) (*PreparedTemplate, error) {
+	if _, err := s.Compile(); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	snap := s.current()
+	tmpl := snap.tree[name]
+	if tmpl == nil {
+		return nil, notFound(snap.tree, name)
+	}
+	return &PreparedTemplate{snap: snap, tmpl: tmpl}, nil
+}
+
+// Name returns the name of the prepared template.
+func (p *PreparedTemplate) Name() string {
+	return p.tmpl.Name
+}
+
+// Execute applies the prepared template to the specified data object and
+// writes the output to wr.
+func (p *PreparedTemplate) Execute(wr io.Writer, data interface{}) error {
+	return p.snap.executeTemplate(wr, p.tmpl, data, false)
+}
@@ -0,0 +1,49 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrepare(t *testing.T) {
+	set := Must(new(Set).Parse(`{{define "mail"}}Hi {{.}}, {{template "sig"}}{{end}}{{define "sig"}}bye{{end}}`)).Escape()
+	p, err := set.Prepare("mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "mail" {
+		t.Errorf("expected name %q; got %q", "mail", p.Name())
+	}
+	tests := []struct {
+		data, output string
+	}{
+		{"Ann", "Hi Ann, bye"},
+		{"<Bob>", "Hi &lt;Bob&gt;, bye"},
+	}
+	for _, test := range tests {
+		b := new(bytes.Buffer)
+		if err := p.Execute(b, test.data); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.output {
+			t.Errorf("expected %q; got %q", test.output, b.String())
+		}
+	}
+	// The prepared template is not affected by Swap.
+	snap, err := Must(new(Set).Parse(`{{define "mail"}}other{{end}}`)).Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Swap(snap)
+	b := new(bytes.Buffer)
+	if err := p.Execute(b, "Ann"); err != nil || b.String() != "Hi Ann, bye" {
+		t.Errorf("expected the prepared template; got %q, %v", b.String(), err)
+	}
+	if _, err := set.Prepare("missing"); err == nil {
+		t.Errorf("expected error preparing a missing template")
+	}
+}
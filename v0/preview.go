@@ -0,0 +1,252 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// FakeData returns placeholder data for the named template, fabricated
+// from the fields the template and the templates it calls use: maps for
+// values with fields, three elements for values used in {{range}},
+// booleans for flags used in {{if}}, and numbers, URLs or lorem ipsum
+// strings for other values, depending on their names. The set is compiled
+// first.
+//
+// The analysis is static, so values accessed through functions, indexes
+// or methods with arguments are not fabricated.
+func (s *Set) FakeData(name string) (interface{}, error) {
+	if _, err := s.Compile(); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	define := s.tree[name]
+	if define == nil {
+		return nil, notFound(s.tree, name)
+	}
+	root := new(fakeShape)
+	w := &fakeWalker{tree: s.tree, calls: make(map[string]int)}
+	w.walk(define.List, root, map[string]*fakeShape{"$": root})
+	return root.value("", new(int)), nil
+}
+
+// Preview executes the named template with data fabricated by FakeData,
+// so that templates can be previewed without the application that
+// provides their data. The execution is lenient, as described in
+// Set.Lenient: failed actions are replaced by a placeholder, "[error]"
+// unless the set is already lenient, and the errors are returned.
+func (s *Set) Preview(wr io.Writer, name string) error {
+	data, err := s.FakeData(name)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	snap := *s.current()
+	s.mutex.Unlock()
+	if !snap.lenient {
+		snap.lenient, snap.placeholder = true, "[error]"
+	}
+	return snap.execute(wr, name, data, false)
+}
+
+// fakeShape describes how a template uses a value.
+type fakeShape struct {
+	fields map[string]*fakeShape // Fields accessed on the value.
+	elem   *fakeShape            // Elements, if ranged over.
+	cond   bool                  // Whether it is used as a condition.
+}
+
+// field returns the shape of the named field of the value.
+func (f *fakeShape) field(name string) *fakeShape {
+	if f.fields == nil {
+		f.fields = make(map[string]*fakeShape)
+	}
+	if f.fields[name] == nil {
+		f.fields[name] = new(fakeShape)
+	}
+	return f.fields[name]
+}
+
+// path returns the shape at the end of the given field names.
+func (f *fakeShape) path(names []string) *fakeShape {
+	for _, name := range names {
+		f = f.field(name)
+	}
+	return f
+}
+
+// element returns the shape of the elements of the value.
+func (f *fakeShape) element() *fakeShape {
+	if f.elem == nil {
+		f.elem = new(fakeShape)
+	}
+	return f.elem
+}
+
+// fakeWords are used to fabricate strings.
+var fakeWords = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor")
+
+// value fabricates a value with the shape, for a field with the given name.
+// The counter n varies the fabricated values.
+func (f *fakeShape) value(name string, n *int) interface{} {
+	*n++
+	switch {
+	case f.elem != nil:
+		values := make([]interface{}, 3)
+		for i := range values {
+			values[i] = f.elem.value(name, n)
+		}
+		return values
+	case f.fields != nil:
+		m := make(map[string]interface{}, len(f.fields))
+		for k, v := range f.fields {
+			m[k] = v.value(k, n)
+		}
+		return m
+	}
+	lower := strings.ToLower(name)
+	for _, prefix := range []string{"is", "has", "can", "show", "enable"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	switch {
+	case f.cond:
+		return true
+	case strings.HasSuffix(name, "ID") || strings.HasSuffix(name, "Id") || lower == "id" ||
+		strings.Contains(lower, "count") || strings.Contains(lower, "num") ||
+		strings.Contains(lower, "total") || strings.Contains(lower, "price") ||
+		strings.Contains(lower, "age") || strings.Contains(lower, "year"):
+		return 10 + *n
+	case strings.Contains(lower, "url") || strings.Contains(lower, "link") || strings.Contains(lower, "href"):
+		return fmt.Sprintf("https://example.com/%d", *n)
+	case strings.Contains(lower, "email"):
+		return fmt.Sprintf("user%d@example.com", *n)
+	}
+	words := make([]string, 2+*n%3)
+	for i := range words {
+		words[i] = fakeWords[(*n+i)%len(fakeWords)]
+	}
+	s := strings.Join(words, " ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// fakeWalker collects the shapes of the values used by templates.
+type fakeWalker struct {
+	tree  parse.Tree
+	calls map[string]int // Templates being walked, to stop recursion.
+}
+
+// walk collects the shapes used by the nodes, executed with the given dot
+// and variables.
+func (w *fakeWalker) walk(n parse.Node, dot *fakeShape, vars map[string]*fakeShape) {
+	switch n := n.(type) {
+	case *parse.ActionNode:
+		w.pipe(n.Pipe, dot, vars)
+	case *parse.CaptureNode:
+		w.walk(n.List, dot, vars)
+		// The variable holds the output, not data.
+		delete(vars, n.Variable.Ident[0])
+	case *parse.CustomNode:
+		if n.Pipe != nil {
+			w.pipe(n.Pipe, dot, vars)
+		}
+		w.walk(n.List, dot, vars)
+	case *parse.IfNode:
+		if v := w.pipe(n.Pipe, dot, vars); v != nil {
+			v.cond = true
+		}
+		w.walk(n.List, dot, vars)
+		w.walk(n.ElseList, dot, vars)
+	case *parse.LetNode:
+		w.pipe(n.Pipe, dot, vars)
+		w.walk(n.List, dot, vars)
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, node := range n.Nodes {
+			w.walk(node, dot, vars)
+		}
+	case *parse.RangeNode:
+		elem := new(fakeShape)
+		if v := w.pipe(n.Pipe, dot, vars); v != nil {
+			elem = v.element()
+		}
+		if len(n.Pipe.Decl) > 0 {
+			vars[n.Pipe.Decl[len(n.Pipe.Decl)-1].Ident[0]] = elem
+		}
+		w.walk(n.List, elem, vars)
+		w.walk(n.ElseList, dot, vars)
+	case *parse.TemplateNode:
+		var v *fakeShape
+		if n.Pipe != nil {
+			v = w.pipe(n.Pipe, dot, vars)
+		}
+		define := w.tree[n.Name]
+		if define == nil || w.calls[n.Name] > 0 {
+			return
+		}
+		if v == nil {
+			v = new(fakeShape)
+		}
+		w.calls[n.Name]++
+		w.walk(define.List, v, map[string]*fakeShape{"$": v})
+		w.calls[n.Name]--
+	case *parse.WithNode:
+		v := w.pipe(n.Pipe, dot, vars)
+		if v == nil {
+			v = new(fakeShape)
+		}
+		w.walk(n.List, v, vars)
+		w.walk(n.ElseList, dot, vars)
+	}
+}
+
+// pipe collects the shapes used by a pipeline, and returns the shape of
+// its value if it is a field or a variable, or nil.
+func (w *fakeWalker) pipe(pipe *parse.PipeNode, dot *fakeShape, vars map[string]*fakeShape) *fakeShape {
+	var v *fakeShape
+	for _, cmd := range pipe.Cmds {
+		v = nil
+		for _, arg := range cmd.Args {
+			v = w.arg(arg, dot, vars)
+		}
+		if len(cmd.Args) != 1 {
+			v = nil
+		}
+	}
+	for _, decl := range pipe.Decl {
+		if v != nil {
+			vars[decl.Ident[0]] = v
+		}
+	}
+	return v
+}
+
+// arg collects the shapes used by an argument, and returns its shape if it
+// is a field or a variable, or nil.
+func (w *fakeWalker) arg(n parse.Node, dot *fakeShape, vars map[string]*fakeShape) *fakeShape {
+	switch n := n.(type) {
+	case *parse.ChainNode:
+		w.arg(n.Node, dot, vars)
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return dot.path(n.Ident)
+	case *parse.PipeNode:
+		return w.pipe(n, dot, vars)
+	case *parse.VariableNode:
+		if v := vars[n.Ident[0]]; v != nil {
+			return v.path(n.Ident[1:])
+		}
+	}
+	return nil
+}
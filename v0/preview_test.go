@@ -0,0 +1,59 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFakeData(t *testing.T) {
+	set := Must(new(Set).Parse(`
+{{define "page"}}<h1>{{.Title}}</h1>{{if .IsAdmin}}admin{{end}}{{if .Draft}}draft{{end}}
+{{range $i, $p := .Posts}}{{template "post" $p}}{{end}}{{with .Author}}{{.Email}}{{end}}{{end}}
+{{define "post"}}<a href="{{.URL}}">{{.Title}}</a> {{.Count}} {{range .Tags}}{{.}}{{end}}{{end}}`))
+	data, err := set.FakeData("page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a map; got %T", data)
+	}
+	for key, kind := range map[string]reflect.Kind{
+		"Title":   reflect.String,
+		"IsAdmin": reflect.Bool,
+		"Draft":   reflect.Bool,
+		"Posts":   reflect.Slice,
+		"Author":  reflect.Map,
+	} {
+		if got := reflect.ValueOf(m[key]).Kind(); got != kind {
+			t.Errorf("%s: expected %s; got %s", key, kind, got)
+		}
+	}
+	post := m["Posts"].([]interface{})[0].(map[string]interface{})
+	if _, ok := post["Count"].(int); !ok {
+		t.Errorf("expected an int count; got %T", post["Count"])
+	}
+	if url, _ := post["URL"].(string); !strings.HasPrefix(url, "https://") {
+		t.Errorf("expected an URL; got %v", post["URL"])
+	}
+	if len(post["Tags"].([]interface{})) != 3 {
+		t.Errorf("expected three tags; got %v", post["Tags"])
+	}
+
+	b := new(bytes.Buffer)
+	if err = set.Preview(b, "page"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(b.String(), "<a href=") != 3 || !strings.Contains(b.String(), "admin") {
+		t.Errorf("unexpected preview %q", b.String())
+	}
+	if _, err = set.FakeData("missing"); err == nil {
+		t.Errorf("expected error for a missing template")
+	}
+}
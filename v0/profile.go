@@ -0,0 +1,201 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Profile accumulates evaluation counts and times of template nodes across
+// executions. Install it in a set using Set.Profile:
+//
+//     prof := new(template.Profile)
+//     set.Profile(prof)
+//     // execute templates...
+//     prof.WriteReport(os.Stderr)
+//
+// Actions, control structures and template invocations are measured. A
+// node is identified by the template name, its line and a short form of its
+// source, as in `page:12 {{range .Items}}`.
+//
+// A profile can be used by concurrent executions.
+type Profile struct {
+	mutex  sync.Mutex
+	stacks map[string]*stackStats
+}
+
+// stackStats holds the measurements of a node for one stack of callers.
+type stackStats struct {
+	count int64
+	total time.Duration // cumulative time, including children.
+	child time.Duration // time spent in profiled children.
+}
+
+// NodeStats holds the measurements of a template node.
+type NodeStats struct {
+	Node  string        // Template name, line and short source of the node.
+	Count int64         // Number of evaluations.
+	Time  time.Duration // Cumulative time, including nested nodes.
+	Self  time.Duration // Time excluding nested nodes.
+}
+
+// Reset discards all measurements.
+func (p *Profile) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.stacks = nil
+}
+
+// record adds an evaluation of the last node in stack, which took d.
+func (p *Profile) record(stack []string, d time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.stacks == nil {
+		p.stacks = make(map[string]*stackStats)
+	}
+	e := p.entry(stack)
+	e.count++
+	e.total += d
+	if len(stack) > 1 {
+		p.entry(stack[:len(stack)-1]).child += d
+	}
+}
+
+// entry returns the measurements for the given stack, creating them if
+// needed. The caller must hold the mutex.
+func (p *Profile) entry(stack []string) *stackStats {
+	key := strings.Join(stack, ";")
+	e := p.stacks[key]
+	if e == nil {
+		e = new(stackStats)
+		p.stacks[key] = e
+	}
+	return e
+}
+
+// Nodes returns the measurements of each node, the most expensive first.
+// The cumulative time of recursive templates counts nested calls more than
+// once.
+func (p *Profile) Nodes() []NodeStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	nodes := make(map[string]*NodeStats)
+	for key, e := range p.stacks {
+		name := key[strings.LastIndex(key, ";")+1:]
+		n := nodes[name]
+		if n == nil {
+			n = &NodeStats{Node: name}
+			nodes[name] = n
+		}
+		n.Count += e.count
+		n.Time += e.total
+		n.Self += e.total - e.child
+	}
+	stats := make([]NodeStats, 0, len(nodes))
+	for _, n := range nodes {
+		stats = append(stats, *n)
+	}
+	sort.Sort(byTime(stats))
+	return stats
+}
+
+// byTime sorts node measurements by decreasing cumulative time.
+type byTime []NodeStats
+
+func (s byTime) Len() int      { return len(s) }
+func (s byTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byTime) Less(i, j int) bool {
+	if s[i].Time != s[j].Time {
+		return s[i].Time > s[j].Time
+	}
+	return s[i].Node < s[j].Node
+}
+
+// WriteReport writes a table of the measured nodes to w, the most expensive
+// first.
+func (p *Profile) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "count\tcumulative\tself\t %s\n", "node")
+	for _, n := range p.Nodes() {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t %s\n", n.Count, n.Time, n.Self, n.Node)
+	}
+	return tw.Flush()
+}
+
+// WriteFolded writes the measurements to w in the folded stacks format
+// used by flame graph tools: one line per stack of nodes, with the frames
+// separated by semicolons and followed by the self time in microseconds.
+func (p *Profile) WriteFolded(w io.Writer) error {
+	p.mutex.Lock()
+	keys := make([]string, 0, len(p.stacks))
+	self := make(map[string]time.Duration, len(p.stacks))
+	for key, e := range p.stacks {
+		keys = append(keys, key)
+		self[key] = e.total - e.child
+	}
+	p.mutex.Unlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(w, "%s %d\n", key, self[key]/time.Microsecond); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Profile sets a profile to accumulate measurements of the executions of
+// the set. A nil profile turns profiling off. Profiling adds overhead to
+// every node evaluated, so it is meant for development. The return value
+// is the set, so calls can be chained.
+func (s *Set) Profile(p *Profile) *Set {
+	s.profile = p
+	return s
+}
+
+// profileNode starts measuring the evaluation of node, if it is profiled.
+// It returns a function that ends the measurement, or nil.
+func (s *state) profileNode(node parse.Node) func() {
+	var short string
+	var line int
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		short, line = n.String(), n.Line
+	case *parse.CaptureNode:
+		short, line = fmt.Sprintf("{{capture %s}}", n.Variable), n.Line
+	case *parse.CustomNode:
+		short, line = fmt.Sprintf("{{custom %q}}", n.Kind), n.Line
+	case *parse.IfNode:
+		short, line = fmt.Sprintf("{{if %s}}", n.Pipe), n.Line
+	case *parse.LetNode:
+		short, line = fmt.Sprintf("{{let %s}}", n.Pipe), n.Line
+	case *parse.RangeNode:
+		short, line = fmt.Sprintf("{{range %s}}", n.Pipe), n.Line
+	case *parse.TemplateNode:
+		short, line = n.String(), n.Line
+	case *parse.WithNode:
+		short, line = fmt.Sprintf("{{with %s}}", n.Pipe), n.Line
+	default:
+		return nil
+	}
+	if utf8.RuneCountInString(short) > 40 {
+		short = fmt.Sprintf("%.37s...", short)
+	}
+	frame := strings.NewReplacer(";", ",", "\n", " ").Replace(fmt.Sprintf("%s:%d %s", s.tmpl.Name, line, short))
+	s.stack = append(s.stack, frame)
+	start := time.Now()
+	return func() {
+		s.snap.profile.record(s.stack, time.Since(start))
+		s.stack = s.stack[:len(s.stack)-1]
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	prof := new(Profile)
+	set := Must(new(Set).Profile(prof).Parse(`{{define "page"}}
+{{range .}}{{template "item" .}}{{end}}
+{{end}}
+{{define "item"}}{{.}};{{end}}`))
+	for i := 0; i < 2; i++ {
+		if err := set.Execute(ioutil.Discard, "page", []int{1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts := map[string]int64{}
+	for _, n := range prof.Nodes() {
+		counts[n.Node] = n.Count
+		if n.Self > n.Time {
+			t.Errorf("%s: self time %s greater than cumulative time %s", n.Node, n.Self, n.Time)
+		}
+	}
+	expect := map[string]int64{
+		`page:2 {{range .}}`:           2,
+		`page:2 {{template "item" .}}`: 6,
+		`item:4 {{.}}`:                 6,
+	}
+	for node, count := range expect {
+		if counts[node] != count {
+			t.Errorf("%s: expected count %d; got %d", node, count, counts[node])
+		}
+	}
+	if len(counts) != len(expect) {
+		t.Errorf("expected %d nodes; got %v", len(expect), counts)
+	}
+
+	b := new(bytes.Buffer)
+	if err := prof.WriteFolded(b); err != nil {
+		t.Fatal(err)
+	}
+	stack := `page:2 {{range .}};page:2 {{template "item" .}};item:4 {{.}} `
+	if !strings.Contains(b.String(), stack) {
+		t.Errorf("folded stacks don't contain %q:\n%s", stack, b)
+	}
+	b.Reset()
+	if err := prof.WriteReport(b); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(b.String(), "\n"); lines != 4 {
+		t.Errorf("expected 4 report lines; got:\n%s", b)
+	}
+
+	prof.Reset()
+	if n := len(prof.Nodes()); n != 0 {
+		t.Errorf("expected no nodes after reset; got %d", n)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Provenance describes where a template came from.
+type Provenance int
+
+const (
+	// ProvenanceCode is for templates parsed from strings by Parse and
+	// ParseTemplate, usually written in the program.
+	ProvenanceCode Provenance = iota
+	// ProvenanceDisk is for templates parsed from files by ParseFiles,
+	// ParseGlob, ParseFS and {{import}}.
+	ProvenanceDisk
+	// ProvenanceUntrusted is for templates parsed by ParseUntrusted, for
+	// example templates authored by the users of an application.
+	ProvenanceUntrusted
+)
+
+var provenanceNames = map[Provenance]string{
+	ProvenanceCode:      "code",
+	ProvenanceDisk:      "disk",
+	ProvenanceUntrusted: "untrusted",
+}
+
+func (p Provenance) String() string {
+	if name, ok := provenanceNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Provenance(%d)", int(p))
+}
+
+// ParseUntrusted is like ParseTemplate, but the templates are marked as
+// untrusted and are restricted accordingly, so that users can author them
+// safely:
+//
+//   - they can't read files with {{import}} or {{include}};
+//   - they can't call noescape, or the set fails to compile.
+//
+// Untrusted templates can call trusted ones, and extend them, unless the set
+// has Restrictions.
+func (s *Set) ParseUntrusted(name, text string) (*Set, error) {
+	return s.record(func(s *Set) (*Set, error) {
+		return s.parse(text, name, "", true, ProvenanceUntrusted)
+	})
+}
+
+// Provenance returns where the named template came from.
+func (s *Set) Provenance(name string) (Provenance, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.tree[name] == nil {
+		return 0, notFound(s.tree, name)
+	}
+	return s.provenance[name], nil
+}
+
+// setProvenance records the provenance of the templates in tree, which was
+// just added to the set. Templates loaded by {{import}} come from disk. The
+// caller must hold the mutex.
+func (s *Set) setProvenance(tree parse.Tree, prov Provenance) {
+	for name := range tree {
+		p := prov
+		if _, _, ok := parse.SplitImportName(name); ok {
+			p = ProvenanceDisk
+		}
+		if p == ProvenanceCode {
+			continue
+		}
+		if s.provenance == nil {
+			s.provenance = make(map[string]Provenance)
+		}
+		s.provenance[name] = p
+	}
+}
+
+// checkUntrustedText returns an error if the untrusted templates in tree,
+// parsed from the named text, read files. Includes are not expanded for
+// untrusted text, so they fail to parse as calls to an undefined function.
+func checkUntrustedText(name string, tree parse.Tree) error {
+	if paths := importPaths(tree); len(paths) > 0 {
+		return fmt.Errorf("template: %s: untrusted templates can't import %q", name, paths[0])
+	}
+	return nil
+}
+
+// untrustedFuncs are the functions untrusted templates can't call: those
+// that type any string as safe content, so that it is not escaped. The
+// {{{name}}} tag of untrusted Mustache templates calls mustache.raw, so
+// these templates can't use it either.
+var untrustedFuncs = map[string]bool{
+	"mustache.raw": true,
+	"noescape":     true,
+}
+
+// checkUntrusted returns an error if an untrusted template in tree calls a
+// function reserved to trusted ones, or doesn't follow the restrictions, if
+// not nil. It runs before inlining, so the fills of untrusted templates that
+// extend trusted ones are checked too.
+func checkUntrusted(tree parse.Tree, provenance map[string]Provenance, r *Restrictions) error {
+	for _, name := range untrustedNames(tree, provenance) {
+		define := tree[name]
+		var err error
+		funcCalls(define.List, func(n *parse.IdentifierNode) {
+			if err == nil && untrustedFuncs[n.Ident] {
+				location, _ := define.ErrorContext(n)
+				err = fmt.Errorf("template: %s: untrusted template %q can't call %s",
+					location, name, n.Ident)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			if err = r.check(tree, provenance, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// untrustedNames returns the sorted names of the untrusted templates in
+// tree.
+func untrustedNames(tree parse.Tree, provenance map[string]Provenance) []string {
+	var names []string
+	for name := range tree {
+		if provenance[name] == ProvenanceUntrusted {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// funcCalls calls fn for each function called in n.
+func funcCalls(n parse.Node, fn func(*parse.IdentifierNode)) {
+	switch n := n.(type) {
+	case *parse.ActionNode:
+		funcCalls(n.Pipe, fn)
+	case *parse.ChainNode:
+		funcCalls(n.Node, fn)
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			funcCalls(arg, fn)
+		}
+	case *parse.CaptureNode:
+		funcCalls(n.List, fn)
+	case *parse.ConstNode:
+		funcCalls(n.List, fn)
+	case *parse.CustomNode:
+		funcCalls(n.Pipe, fn)
+		funcCalls(n.List, fn)
+	case *parse.FillNode:
+		funcCalls(n.List, fn)
+	case *parse.IdentifierNode:
+		fn(n)
+	case *parse.IfNode:
+		funcCalls(n.Pipe, fn)
+		funcCalls(n.List, fn)
+		funcCalls(n.ElseList, fn)
+	case *parse.LetNode:
+		funcCalls(n.Pipe, fn)
+		funcCalls(n.List, fn)
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Nodes {
+			funcCalls(v, fn)
+		}
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			funcCalls(cmd, fn)
+		}
+	case *parse.RangeNode:
+		funcCalls(n.Pipe, fn)
+		funcCalls(n.List, fn)
+		funcCalls(n.ElseList, fn)
+	case *parse.SlotNode:
+		funcCalls(n.List, fn)
+	case *parse.TemplateNode:
+		funcCalls(n.Pipe, fn)
+	case *parse.WithNode:
+		funcCalls(n.Pipe, fn)
+		funcCalls(n.List, fn)
+		funcCalls(n.ElseList, fn)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	set := Must(new(Set).Parse(`{{define "layout"}}<main>{{slot "body"}}{{end}}</main>{{end}}`))
+	Must(set.ParseFiles("testdata/file1.tmpl"))
+	Must(set.ParseUntrusted("user", `{{define "user" "layout"}}{{fill "body"}}<b>{{.}}</b>{{end}}{{end}}`))
+	tests := []struct {
+		name string
+		prov Provenance
+	}{
+		{"layout", ProvenanceCode},
+		{"x", ProvenanceDisk},
+		{"user", ProvenanceUntrusted},
+	}
+	for _, test := range tests {
+		if prov, err := set.Provenance(test.name); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else if prov != test.prov {
+			t.Errorf("%s: expected %s, got %s", test.name, test.prov, prov)
+		}
+	}
+	if _, err := set.Provenance("missing"); err == nil {
+		t.Errorf("expected error for missing template")
+	}
+	var b bytes.Buffer
+	if err := set.Execute(&b, "user", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "<main><b>hi</b></main>"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestUntrustedRestrictions(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{`{{import "testdata/import/helpers.tmpl" as h}}{{define "u"}}{{h.a}}{{end}}`,
+			`can't import "testdata/import/helpers.tmpl"`},
+		{`{{include "testdata/include/nav.part"}}`, `function "include" not defined`},
+		{`{{define "u"}}{{if true}}{{noescape .}}{{end}}{{end}}`,
+			`template: u:1:27: untrusted template "u" can't call noescape`},
+		{`{{define "u" "layout"}}{{fill "body"}}{{. | noescape}}{{end}}{{end}}`,
+			`untrusted template "u" can't call noescape`},
+	}
+	for _, test := range tests {
+		noescape := func(v interface{}) string { return fmt.Sprint(v) }
+		set := Must(new(Set).Funcs(FuncMap{"noescape": noescape}).Parse(`{{define "layout"}}{{slot "body"}}{{end}}{{noescape "<br>"}}{{end}}`))
+		_, err := set.ParseUntrusted("u", test.text)
+		if err == nil {
+			_, err = set.Compile()
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q, got %v", test.text, test.err, err)
+		}
+	}
+}
+
+func TestUntrustedRawHTML(t *testing.T) {
+	tests := []struct {
+		syntax Syntax
+		text   string
+	}{
+		{SyntaxMustache, `<p>{{{x}}}</p>`},
+		{SyntaxNative, `{{define "u"}}<p>{{mustache.raw .x}}</p>{{end}}`},
+	}
+	for _, test := range tests {
+		set := new(Set).Syntax(SyntaxMustache).Escape()
+		set.Syntax(test.syntax)
+		_, err := set.ParseUntrusted("u", test.text)
+		if err == nil {
+			_, err = set.Compile()
+		}
+		if err == nil || !strings.Contains(err.Error(), `untrusted template "u" can't call mustache.raw`) {
+			t.Errorf("%s: expected error for mustache.raw, got %v", test.text, err)
+		}
+	}
+}
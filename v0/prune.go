@@ -0,0 +1,113 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"strings"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Prune removes from the set all templates that are not reachable from the
+// given roots through {{template}} actions. The set is compiled first, so
+// parent templates that are only used through inheritance are removed as
+// well. The versions of the roots, as in "page@v2", are kept for
+// ExecuteVersion. It is useful to save memory when a large directory of
+// shared templates is parsed but only a few pages are executed.
+//
+// Templates called only by the executors of custom nodes must be given as
+// roots, since Prune can't see which templates an executor calls. The
+// templates are replaced as with Swap: executions in progress, snapshots
+// and prepared templates keep using the previous ones.
+//
+// It returns an error if the set can't be compiled or a root is not
+// defined. Otherwise the returned set is s.
+func (s *Set) Prune(roots ...string) (*Set, error) {
+	if _, err := s.Compile(); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	reachable := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		define := s.tree[name]
+		if define == nil || reachable[name] {
+			return
+		}
+		reachable[name] = true
+		templateCalls(define.List, func(n *parse.TemplateNode) {
+			visit(n.Name)
+		})
+	}
+	for _, name := range roots {
+		if s.tree[name] == nil {
+			return nil, notFound(s.tree, name)
+		}
+		visit(name)
+		prefix := name + versionSeparator
+		for version := range s.tree {
+			if strings.HasPrefix(version, prefix) {
+				visit(version)
+			}
+		}
+	}
+	// The tree is shared with snapshots, so it is replaced rather than
+	// modified.
+	tree := make(parse.Tree, len(reachable))
+	for name := range reachable {
+		tree[name] = s.tree[name]
+	}
+	s.tree = tree
+	return s, nil
+}
+
+// templateCalls calls fn for each {{template}} action in n.
+//
+// May contain child actions:
+// CaptureNode: n.List
+// ConstNode:  n.List
+// CustomNode: n.List
+// SlotNode:  n.List
+// FillNode:   n.List
+// IfNode:     n.List, n.ElseList
+// LetNode:    n.List
+// ListNode:   n.Nodes
+// RangeNode:  n.List, n.ElseList
+// WithNode:   n.List, n.ElseList
+func templateCalls(n parse.Node, fn func(*parse.TemplateNode)) {
+	switch n := n.(type) {
+	case *parse.CaptureNode:
+		templateCalls(n.List, fn)
+	case *parse.ConstNode:
+		templateCalls(n.List, fn)
+	case *parse.CustomNode:
+		templateCalls(n.List, fn)
+	case *parse.FillNode:
+		templateCalls(n.List, fn)
+	case *parse.IfNode:
+		templateCalls(n.List, fn)
+		templateCalls(n.ElseList, fn)
+	case *parse.LetNode:
+		templateCalls(n.List, fn)
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Nodes {
+			templateCalls(v, fn)
+		}
+	case *parse.RangeNode:
+		templateCalls(n.List, fn)
+		templateCalls(n.ElseList, fn)
+	case *parse.SlotNode:
+		templateCalls(n.List, fn)
+	case *parse.TemplateNode:
+		fn(n)
+	case *parse.WithNode:
+		templateCalls(n.List, fn)
+		templateCalls(n.ElseList, fn)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo
+// +build !tinygo
+
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gorilla/template/v0/escape"
+)
+
+// Reloader reloads a set when its files change and makes the pages open in
+// a browser reload when the templates they were rendered with are affected,
+// using server-sent events. It is meant for development:
+//
+//	r, err := template.NewReloader("/_reload", load, "templates/*.html")
+//	...
+//	http.Handle("/_reload", r)
+//	go r.Watch(time.Second, nil)
+//
+// and in handlers, r.Execute(w, "page", data) instead of set.Execute.
+type Reloader struct {
+	path     string
+	load     func() (*Set, error)
+	patterns []string
+	mutex    sync.Mutex
+	set      *Set
+	modTimes map[string]time.Time
+	clients  map[chan []byte]bool
+}
+
+// NewReloader loads a set with load and returns a Reloader that loads it
+// again when the files matching the patterns, as in filepath.Glob, change.
+// path is the URL path where the Reloader is served.
+func NewReloader(path string, load func() (*Set, error), patterns ...string) (*Reloader, error) {
+	r := &Reloader{
+		path:     path,
+		load:     load,
+		patterns: patterns,
+		clients:  make(map[chan []byte]bool),
+	}
+	modTimes, err := r.scan()
+	if err != nil {
+		return nil, err
+	}
+	set, err := load()
+	if err != nil {
+		return nil, err
+	}
+	r.set, r.modTimes = set, modTimes
+	return r, nil
+}
+
+// Set returns the current set.
+func (r *Reloader) Set() *Set {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.set
+}
+
+// Execute executes the named template of the current set like Set.Execute,
+// and adds to the output a script that reloads the page when the template
+// is affected by a change. The script is inserted before the closing body
+// tag, or at the end if there is none.
+func (r *Reloader) Execute(w io.Writer, name string, data interface{}) error {
+	var b bytes.Buffer
+	if err := r.Set().Execute(&b, name, data); err != nil {
+		return err
+	}
+	out := b.Bytes()
+	i := lastIndexASCIIFold(out, "</body>")
+	if i < 0 {
+		i = len(out)
+	}
+	if _, err := w.Write(out[:i]); err != nil {
+		return err
+	}
+	script, err := r.script(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, string(script)); err != nil {
+		return err
+	}
+	_, err = w.Write(out[i:])
+	return err
+}
+
+// lastIndexASCIIFold returns the index of the last instance of the lower
+// case ASCII string sub in b, ignoring the case of ASCII letters, or -1.
+// Unlike bytes.ToLower, it leaves other bytes alone, so the index is valid
+// in b.
+func lastIndexASCIIFold(b []byte, sub string) int {
+	for i := len(b) - len(sub); i >= 0; i-- {
+		j := 0
+		for ; j < len(sub); j++ {
+			c := b[i+j]
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			if c != sub[j] {
+				break
+			}
+		}
+		if j == len(sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// script returns the script that reloads a page rendered by the named
+// template.
+func (r *Reloader) script(name string) (escape.HTML, error) {
+	page, err := escape.JSON(name)
+	if err != nil {
+		return "", err
+	}
+	path, err := escape.JSON(r.path)
+	if err != nil {
+		return "", err
+	}
+	return escape.HTML(fmt.Sprintf(`<script>(function() {
+  var page = %s, events = new EventSource(%s);
+  events.addEventListener("change", function(e) {
+    if (JSON.parse(e.data).indexOf(page) >= 0) location.reload();
+  });
+  events.addEventListener("failure", function(e) { console.error(JSON.parse(e.data)); });
+})();</script>`, page, path)), nil
+}
+
+// ServeHTTP streams the changes to the browser as server-sent events: a
+// "change" event with the names of the affected templates, or a "failure"
+// event with the error that prevented the set from loading.
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	events := make(chan []byte, 8)
+	r.mutex.Lock()
+	r.clients[events] = true
+	r.mutex.Unlock()
+	defer func() {
+		r.mutex.Lock()
+		delete(r.clients, events)
+		r.mutex.Unlock()
+	}()
+	flusher.Flush()
+	for {
+		select {
+		case event := <-events:
+			if _, err := w.Write(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// Check loads the set again if its files changed since the last check, and
+// notifies the browsers of the affected templates, as reported by Diff. It
+// returns whether the files changed. Errors are also sent to the browsers.
+func (r *Reloader) Check() (bool, error) {
+	modTimes, err := r.scan()
+	if err != nil {
+		return false, err
+	}
+	r.mutex.Lock()
+	changed := !equalModTimes(r.modTimes, modTimes)
+	r.modTimes = modTimes
+	old := r.set
+	r.mutex.Unlock()
+	if !changed {
+		return false, nil
+	}
+	set, err := r.load()
+	var names []string
+	if err == nil {
+		names, err = Diff(old, set)
+	}
+	if err != nil {
+		r.broadcast("failure", err.Error())
+		return true, err
+	}
+	r.mutex.Lock()
+	r.set = set
+	r.mutex.Unlock()
+	if len(names) > 0 {
+		r.broadcast("change", names)
+	}
+	return true, nil
+}
+
+// Watch calls Check at the given interval until stop is closed. Errors are
+// reported to the browsers by Check.
+func (r *Reloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Check()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// broadcast sends an event to the connected browsers. Browsers that are
+// too slow to receive it miss it.
+func (r *Reloader) broadcast(event string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	msg := []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, b))
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for c := range r.clients {
+		select {
+		case c <- msg:
+		default:
+		}
+	}
+}
+
+// scan returns the modification times of the files matching the patterns.
+func (r *Reloader) scan() (map[string]time.Time, error) {
+	var paths []string
+	for _, pattern := range r.patterns {
+		filenames, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, filenames...)
+	}
+	return scanModTimes(paths), nil
+}
+
+// equalModTimes returns whether two scans found the same files with the
+// same modification times.
+func equalModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, t := range a {
+		if u, ok := b[name]; !ok || !u.Equal(t) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo
+// +build !tinygo
+
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, text string, mod time.Time) {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("a.html", `{{define "a"}}<body>A</body>{{end}}`, start)
+	write("b.html", `{{define "b"}}B{{end}}`, start)
+	pattern := filepath.Join(dir, "*.html")
+	r, err := NewReloader("/_reload", func() (*Set, error) {
+		return new(Set).ParseGlob(pattern)
+	}, pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := r.Execute(&b, "a", nil); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.HasPrefix(out, "<body>A<script>") || !strings.HasSuffix(out, "</script></body>") ||
+		!strings.Contains(out, `var page = "a", events = new EventSource("/_reload")`) {
+		t.Errorf("unexpected output %q", out)
+	}
+	if changed, err := r.Check(); changed || err != nil {
+		t.Fatalf("expected no change, got %v, %v", changed, err)
+	}
+	// The index of the closing body tag is found in the output itself,
+	// whose non-ASCII text may change length when lowered.
+	set := Must(new(Set).Parse(`{{define "c"}}<p>` + strings.Repeat("\u023a", 20) + `</p></BODY>{{end}}`))
+	r.mutex.Lock()
+	old := r.set
+	r.set = set
+	r.mutex.Unlock()
+	b.Reset()
+	if err := r.Execute(&b, "c", nil); err != nil {
+		t.Fatal(err)
+	}
+	if out := b.String(); !strings.HasPrefix(out, "<p>"+strings.Repeat("\u023a", 20)+"</p><script>") || !strings.HasSuffix(out, "</script></BODY>") {
+		t.Errorf("unexpected output %q", out)
+	}
+	r.mutex.Lock()
+	r.set = old
+	r.mutex.Unlock()
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	// Wait for the client to be registered.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		r.mutex.Lock()
+		n := len(r.clients)
+		r.mutex.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	write("b.html", `{{define "b"}}B2{{end}}`, start.Add(time.Minute))
+	if changed, err := r.Check(); !changed || err != nil {
+		t.Fatalf("expected change, got %v, %v", changed, err)
+	}
+	if got, want := readEvent(), "event: change\ndata: [\"b\"]\n"; got != want {
+		t.Errorf("expected event %q, got %q", want, got)
+	}
+	b.Reset()
+	if err := r.Set().Execute(&b, "b", nil); err != nil || b.String() != "B2" {
+		t.Errorf("expected reloaded template, got %q, %v", b.String(), err)
+	}
+
+	write("b.html", `{{define "b"}}{{end}`, start.Add(2*time.Minute))
+	if _, err := r.Check(); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := readEvent(); !strings.HasPrefix(got, "event: failure\n") {
+		t.Errorf("expected failure event, got %q", got)
+	}
+}
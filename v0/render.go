@@ -0,0 +1,27 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package template
+
+import (
+	"io"
+	"reflect"
+)
+
+// Render applies the template that has the given name to data and writes
+// the output to w, like s.Execute. If a type was bound to the template with
+// Set.Bind, T must be assignable to it, or an error is returned without
+// executing the template:
+//
+//	set.Bind("page", reflect.TypeOf((*Page)(nil)))
+//	err := template.Render[*Page](set, w, "page", page)
+func Render[T any](s *Set, w io.Writer, name string, data T) error {
+	if err := s.checkBinding(name, reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return err
+	}
+	return s.Execute(w, name, data)
+}
@@ -0,0 +1,45 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package template
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	type page struct{ Title string }
+	set := Must(new(Set).Parse(`{{define "page"}}<h1>{{.Title}}</h1>{{end}}`))
+	var b bytes.Buffer
+	if err := Render[page](set, &b, "page", page{"Hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "<h1>Hello</h1>"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if err := Render(set, &b, "missing", page{}); err == nil {
+		t.Errorf("expected error for missing template")
+	}
+	set.Bind("page", reflect.TypeOf(page{}))
+	b.Reset()
+	if err := Render(set, &b, "page", page{"Bound"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "<h1>Bound</h1>"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	b.Reset()
+	err := Render(set, &b, "page", &page{"Pointer"})
+	if want := `template: "page" is bound to template.page, can't render it with *template.page`; err == nil || err.Error() != want {
+		t.Errorf("expected error %q, got %v", want, err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("template executed despite the type mismatch")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Restrictions limit what untrusted templates, parsed by ParseUntrusted,
+// can do, so that they can be edited by the customers of an application.
+// They are enforced when the set is compiled, in addition to the rules
+// described in ParseUntrusted.
+type Restrictions struct {
+	// Funcs are the functions untrusted templates can call, including
+	// builtins such as printf or eq. Other functions can't be called.
+	Funcs []string
+	// Templates are the trusted templates untrusted ones can call with
+	// {{template}} or extend. They can always call and extend other
+	// untrusted templates.
+	Templates []string
+	// MaxRangeDepth is the maximum nesting of {{range}} actions, which
+	// bounds the number of iterations relative to the size of the data.
+	// It counts the actions of the templates an untrusted template extends
+	// and calls, which can't call themselves inside {{range}}. Zero means
+	// that {{range}} can't be used.
+	MaxRangeDepth int
+}
+
+// Restrict applies a restricted dialect to the untrusted templates in the
+// set: see Restrictions. Without it, untrusted templates are only
+// restricted as described in ParseUntrusted. The return value is the set,
+// so calls can be chained.
+func (s *Set) Restrict(r Restrictions) *Set {
+	s.restrict = &r
+	return s
+}
+
+// check returns an error if the untrusted template with the given name
+// doesn't follow the restrictions.
+func (r *Restrictions) check(tree parse.Tree, provenance map[string]Provenance, name string) error {
+	define := tree[name]
+	funcs := make(map[string]bool, len(r.Funcs))
+	for _, fn := range r.Funcs {
+		funcs[fn] = true
+	}
+	templates := make(map[string]bool, len(r.Templates))
+	for _, t := range r.Templates {
+		templates[t] = true
+	}
+	allowed := func(name string) bool {
+		return templates[name] || provenance[name] == ProvenanceUntrusted
+	}
+	if define.Parent != "" && !allowed(define.Parent) {
+		return fmt.Errorf("template: untrusted template %q can't extend %q", name, define.Parent)
+	}
+	var err error
+	fail := func(n parse.Node, format string, args ...interface{}) {
+		if err == nil {
+			location, _ := define.ErrorContext(n)
+			err = fmt.Errorf("template: %s: untrusted template %q %s", location, name,
+				fmt.Sprintf(format, args...))
+		}
+	}
+	funcCalls(define.List, func(n *parse.IdentifierNode) {
+		if !funcs[n.Ident] {
+			fail(n, "can't call %s", n.Ident)
+		}
+	})
+	templateCalls(define.List, func(n *parse.TemplateNode) {
+		if !allowed(n.Name) {
+			fail(n, "can't call template %q", n.Name)
+		}
+	})
+	return err
+}
+
+// checkRanges returns an error if an untrusted template in tree nests
+// {{range}} actions more than allowed. It runs after inlining, so that the
+// actions of the templates it extends count, and follows {{template}}
+// actions, so that the actions of the templates it calls count too.
+func (r *Restrictions) checkRanges(tree parse.Tree, provenance map[string]Provenance) error {
+	for _, name := range untrustedNames(tree, provenance) {
+		define := tree[name]
+		var err error
+		// The depth at which the templates being walked were called.
+		calls := map[string]int{name: 0}
+		var walk func(l *parse.ListNode, depth int, call parse.Node)
+		walk = func(l *parse.ListNode, depth int, call parse.Node) {
+			rangeNodes(l, depth, func(n parse.Node, depth int) {
+				if err != nil {
+					return
+				}
+				// Errors are reported at the action of the untrusted
+				// template that leads to n.
+				at := call
+				if at == nil {
+					at = n
+				}
+				fail := func(format string, args ...interface{}) {
+					location, _ := define.ErrorContext(at)
+					err = fmt.Errorf("template: %s: untrusted template %q %s", location, name,
+						fmt.Sprintf(format, args...))
+				}
+				switch n := n.(type) {
+				case *parse.RangeNode:
+					if depth > r.MaxRangeDepth {
+						fail("can't nest {{range}} more than %d levels", r.MaxRangeDepth)
+					}
+				case *parse.TemplateNode:
+					called := tree[n.Name]
+					if called == nil {
+						return
+					}
+					if d, ok := calls[n.Name]; ok {
+						if depth > d {
+							fail("can't call template %q recursively inside {{range}}", n.Name)
+						}
+						return
+					}
+					calls[n.Name] = depth
+					walk(called.List, depth, at)
+					delete(calls, n.Name)
+				}
+			})
+		}
+		walk(define.List, 0, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rangeNodes calls fn for each {{range}} action in n, with its nesting
+// depth, counting from depth+1, and for each {{template}} action, with the
+// depth of the {{range}} actions around it.
+//
+// May contain child actions:
+// CaptureNode: n.List
+// ConstNode:  n.List
+// CustomNode: n.List
+// FillNode:   n.List
+// IfNode:     n.List, n.ElseList
+// LetNode:    n.List
+// ListNode:   n.Nodes
+// RangeNode:  n.List, n.ElseList
+// SlotNode:   n.List
+// WithNode:   n.List, n.ElseList
+func rangeNodes(n parse.Node, depth int, fn func(parse.Node, int)) {
+	switch n := n.(type) {
+	case *parse.CaptureNode:
+		rangeNodes(n.List, depth, fn)
+	case *parse.ConstNode:
+		rangeNodes(n.List, depth, fn)
+	case *parse.CustomNode:
+		rangeNodes(n.List, depth, fn)
+	case *parse.FillNode:
+		rangeNodes(n.List, depth, fn)
+	case *parse.IfNode:
+		rangeNodes(n.List, depth, fn)
+		rangeNodes(n.ElseList, depth, fn)
+	case *parse.LetNode:
+		rangeNodes(n.List, depth, fn)
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Nodes {
+			rangeNodes(v, depth, fn)
+		}
+	case *parse.RangeNode:
+		fn(n, depth+1)
+		rangeNodes(n.List, depth+1, fn)
+		rangeNodes(n.ElseList, depth, fn)
+	case *parse.SlotNode:
+		rangeNodes(n.List, depth, fn)
+	case *parse.TemplateNode:
+		fn(n, depth)
+	case *parse.WithNode:
+		rangeNodes(n.List, depth, fn)
+		rangeNodes(n.ElseList, depth, fn)
+	}
+}
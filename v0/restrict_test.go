@@ -0,0 +1,62 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRestrict(t *testing.T) {
+	const trusted = `{{define "layout"}}<main>{{slot "body"}}{{end}}</main>{{end}}
+{{define "secret"}}{{printf "%s" .}}{{end}}
+{{define "ranges"}}{{range .}}{{slot "item"}}{{end}}{{end}}{{end}}`
+	restrictions := Restrictions{
+		Funcs:         []string{"upper", "eq"},
+		Templates:     []string{"layout", "ranges"},
+		MaxRangeDepth: 1,
+	}
+	tests := []struct {
+		text string
+		err  string // Empty if no error.
+	}{
+		{`{{define "u" "layout"}}{{fill "body"}}{{range .}}{{if eq . "a"}}{{upper .}}{{end}}{{end}}{{template "v" .}}{{end}}{{end}}
+{{define "v"}}!{{end}}`, ``},
+		{`{{define "u"}}{{printf "%v" .}}{{end}}`, `template: u:1:16: untrusted template "u" can't call printf`},
+		{`{{define "u"}}{{with .}}{{. | len}}{{end}}{{end}}`, `untrusted template "u" can't call len`},
+		{`{{define "u"}}{{template "secret" .}}{{end}}`, `untrusted template "u" can't call template "secret"`},
+		{`{{define "u" "secret"}}{{end}}`, `untrusted template "u" can't extend "secret"`},
+		{`{{define "u"}}{{range .}}{{range .}}{{.}}{{end}}{{end}}{{end}}`, `can't nest {{range}} more than 1 levels`},
+		{`{{define "u"}}{{range .}}{{template "v" .}}{{end}}{{end}}{{define "v"}}{{if .}}{{range .}}y{{end}}{{end}}{{end}}`,
+			`template: u:1:36: untrusted template "u" can't nest {{range}} more than 1 levels`},
+		{`{{define "u"}}{{range .}}{{template "ranges" .}}{{end}}{{end}}`, `can't nest {{range}} more than 1 levels`},
+		{`{{define "u" "ranges"}}{{fill "item"}}{{range .}}{{.}}{{end}}{{end}}{{end}}`, `can't nest {{range}} more than 1 levels`},
+		{`{{define "u"}}{{template "v" .}}{{end}}{{define "v"}}{{range .}}{{if .}}{{template "v" .}}{{end}}{{end}}{{end}}`,
+			`can't call template "v" recursively inside {{range}}`},
+		{`{{define "u"}}{{template "v" .}}{{template "v" .}}{{end}}{{define "v"}}{{range .}}{{.}}{{end}}{{end}}`, ``},
+	}
+	for _, test := range tests {
+		set := Must(new(Set).FlatBuiltins().Parse(trusted)).Restrict(restrictions)
+		_, err := set.ParseUntrusted("u", test.text)
+		if err == nil {
+			_, err = set.Compile()
+		}
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %s", test.text, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: expected error containing %q, got %v", test.text, test.err, err)
+		}
+	}
+	// Trusted templates are not restricted.
+	set := Must(new(Set).Parse(trusted)).Restrict(Restrictions{})
+	var b bytes.Buffer
+	if err := set.Execute(&b, "secret", "s"); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "s" {
+		t.Errorf("expected %q, got %q", "s", b.String())
+	}
+}
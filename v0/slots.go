@@ -0,0 +1,179 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Slots returns the names of the slots that templates extending the named
+// template can fill. They are the slots declared by the template and by its
+// parents, in order of declaration starting from the topmost parent.
+//
+// Slots and fills are resolved by compilation, so Slots returns an error
+// after the set was compiled or executed.
+func (s *Set) Slots(name string) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.compiled {
+		return nil, fmt.Errorf("template: slots are not available after compilation")
+	}
+	chain, err := parentList(s.tree, name)
+	if err != nil {
+		return nil, err
+	}
+	return chainSlots(s.tree, chain), nil
+}
+
+// chainSlots returns the names of the slots declared by the templates in
+// chain, as returned by parentList, in order of declaration starting from
+// the topmost parent.
+func chainSlots(tree parse.Tree, chain []string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(n *parse.SlotNode) {
+		if !seen[n.Name] {
+			seen[n.Name] = true
+			names = append(names, n.Name)
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		define := tree[chain[i]]
+		if define.Parent == "" {
+			slotNodes(define.List, add)
+			continue
+		}
+		// Only the fills of an extending template are used.
+		for _, n := range define.List.Nodes {
+			if f, ok := n.(*parse.FillNode); ok {
+				slotNodes(f.List, add)
+			}
+		}
+	}
+	return names
+}
+
+// Fills returns the names of the slots filled by the named template, in
+// order of declaration.
+//
+// Slots and fills are resolved by compilation, so Fills returns an error
+// after the set was compiled or executed.
+func (s *Set) Fills(name string) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.compiled {
+		return nil, fmt.Errorf("template: fills are not available after compilation")
+	}
+	define := s.tree[name]
+	if define == nil {
+		return nil, notFound(s.tree, name)
+	}
+	var names []string
+	for _, n := range define.List.Nodes {
+		if f, ok := n.(*parse.FillNode); ok {
+			names = append(names, f.Name)
+		}
+	}
+	return names, nil
+}
+
+// slotNodes calls fn for each slot in n that can be filled. The contents of
+// slots, constant blocks and fills can't be filled.
+//
+// May contain child actions:
+// CaptureNode: n.List
+// IfNode:     n.List, n.ElseList
+// LetNode:    n.List
+// ListNode:   n.Nodes
+// RangeNode:  n.List, n.ElseList
+// WithNode:   n.List, n.ElseList
+func slotNodes(n parse.Node, fn func(*parse.SlotNode)) {
+	switch n := n.(type) {
+	case *parse.CaptureNode:
+		slotNodes(n.List, fn)
+	case *parse.IfNode:
+		slotNodes(n.List, fn)
+		slotNodes(n.ElseList, fn)
+	case *parse.LetNode:
+		slotNodes(n.List, fn)
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Nodes {
+			slotNodes(v, fn)
+		}
+	case *parse.RangeNode:
+		slotNodes(n.List, fn)
+		slotNodes(n.ElseList, fn)
+	case *parse.SlotNode:
+		fn(n)
+	case *parse.WithNode:
+		slotNodes(n.List, fn)
+		slotNodes(n.ElseList, fn)
+	}
+}
+
+// checkSlots returns an error if two slots with the same name are declared
+// in the chain of a template, because fills for that name would target both.
+// A slot declared by a fill replaces the filled slot, so it doesn't count as
+// a duplicate of it.
+func checkSlots(tree parse.Tree) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		chain, err := parentList(tree, name)
+		if err != nil {
+			// Reported by inlining.
+			continue
+		}
+		type decl struct {
+			define *parse.DefineNode
+			slot   *parse.SlotNode
+		}
+		declared := make(map[string]decl)
+		var dup error
+		for i := len(chain) - 1; i >= 0 && dup == nil; i-- {
+			define := tree[chain[i]]
+			add := func(n *parse.SlotNode) {
+				if dup != nil {
+					return
+				}
+				if d, ok := declared[n.Name]; ok {
+					first, _ := d.define.ErrorContext(d.slot)
+					second, _ := define.ErrorContext(n)
+					dup = fmt.Errorf("template: %q: slot %q is declared twice, at %s and at %s",
+						name, n.Name, first, second)
+					return
+				}
+				declared[n.Name] = decl{define, n}
+			}
+			if define.Parent == "" {
+				slotNodes(define.List, add)
+				continue
+			}
+			var fills []*parse.FillNode
+			for _, n := range define.List.Nodes {
+				if f, ok := n.(*parse.FillNode); ok {
+					fills = append(fills, f)
+					delete(declared, f.Name)
+				}
+			}
+			for _, f := range fills {
+				slotNodes(f.List, add)
+			}
+		}
+		if dup != nil {
+			return dup
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Snapshot is an immutable copy of the compiled templates of a set.
+//
+// A snapshot can be executed concurrently, and it can be installed in a
+// set using Set.Swap. Together they allow to replace the templates of a
+// set that is in use, for example to reload templates in production:
+//
+//     next, err := new(Set).ParseGlob("templates/*.html")
+//     if err != nil {
+//         // keep serving the current templates...
+//     }
+//     snap, err := next.Escape().Snapshot()
+//     if err != nil {
+//         // keep serving the current templates...
+//     }
+//     set.Swap(snap)
+type Snapshot struct {
+	tree        parse.Tree
+	funcs       map[string]reflect.Value
+	nilPolicy   NilPolicy
+	profile     *Profile
+	consts      map[string]reflect.Value
+	lenient     bool
+	placeholder string
+	executors   map[string]NodeExecutor
+	audit       func(Bypass)
+	truths      truthFuncs
+	recorder    *AccessRecorder
+	log         logConfig
+	debug       bool
+	deprecated  map[string]string
+	lineEnding  LineEnding
+	indent      bool
+}
+
+// Snapshot compiles the set and returns an immutable copy of its templates.
+func (s *Set) Snapshot() (*Snapshot, error) {
+	if _, err := s.Compile(); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	snap := s.current()
+	snap.tree = copyTree(snap.tree)
+	snap.funcs = copyFuncs(snap.funcs)
+	return snap, nil
+}
+
+// current returns the templates and options currently in use by the set,
+// without copying. The caller must hold the set mutex.
+func (s *Set) current() *Snapshot {
+	return &Snapshot{
+		tree:        s.tree,
+		funcs:       s.execFuncs,
+		nilPolicy:   s.nilPolicy,
+		profile:     s.profile,
+		consts:      s.consts,
+		lenient:     s.lenient,
+		placeholder: s.placeholder,
+		executors:   s.executors,
+		audit:       s.audit,
+		truths:      s.truths,
+		recorder:    s.recorder,
+		log:         s.log,
+		debug:       s.debug,
+		deprecated:  s.deprecated,
+		lineEnding:  s.lineEnding,
+		indent:      s.indent,
+	}
+}
+
+// Swap atomically replaces all templates, functions and execution options
+// of the set by the ones from the snapshot. Executions in progress finish using the previous
+// templates; later ones use the new templates. The return value is the set,
+// so calls can be chained.
+func (s *Set) Swap(snap *Snapshot) *Set {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tree = copyTree(snap.tree)
+	s.execFuncs = copyFuncs(snap.funcs)
+	s.nilPolicy = snap.nilPolicy
+	s.profile = snap.profile
+	s.consts = snap.consts
+	s.lenient = snap.lenient
+	s.placeholder = snap.placeholder
+	s.executors = snap.executors
+	s.audit = snap.audit
+	s.truths = snap.truths
+	s.recorder = snap.recorder
+	s.log = snap.log
+	s.debug = snap.debug
+	s.deprecated = snap.deprecated
+	s.lineEnding = snap.lineEnding
+	s.indent = snap.indent
+	s.compiled = true
+	s.compileErr = nil
+	return s
+}
+
+// Execute applies the template from the snapshot that has the given name
+// to the specified data object and writes the output to wr.
+func (s *Snapshot) Execute(wr io.Writer, name string, data interface{}) error {
+	return s.execute(wr, name, data, false)
+}
+
+// copyTree returns a copy of the tree map. Compiled templates are never
+// modified, so they can be shared.
+func copyTree(tree parse.Tree) parse.Tree {
+	t := make(parse.Tree, len(tree))
+	for k, v := range tree {
+		t[k] = v
+	}
+	return t
+}
+
+// copyFuncs returns a copy of the given function map.
+func copyFuncs(funcs map[string]reflect.Value) map[string]reflect.Value {
+	m := make(map[string]reflect.Value, len(funcs))
+	for k, v := range funcs {
+		m[k] = v
+	}
+	return m
+}
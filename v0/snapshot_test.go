@@ -0,0 +1,47 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotSwap(t *testing.T) {
+	set := Must(new(Set).Parse(`{{define "t"}}one{{end}}`))
+	snap1, err := set.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := Must(new(Set).Parse(`{{define "t"}}two{{end}}`))
+	snap2, err := next.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := new(bytes.Buffer)
+			if err := set.Execute(b, "t", nil); err != nil {
+				t.Error(err)
+			} else if s := b.String(); s != "one" && s != "two" {
+				t.Errorf("unexpected output %q", s)
+			}
+		}()
+	}
+	set.Swap(snap2)
+	wg.Wait()
+	b := new(bytes.Buffer)
+	if err := set.Execute(b, "t", nil); err != nil || b.String() != "two" {
+		t.Errorf("after swap: expected %q, got %q (%v)", "two", b.String(), err)
+	}
+	b.Reset()
+	if err := snap1.Execute(b, "t", nil); err != nil || b.String() != "one" {
+		t.Errorf("snapshot: expected %q, got %q (%v)", "one", b.String(), err)
+	}
+}
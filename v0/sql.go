@@ -0,0 +1,22 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"github.com/gorilla/template/v0/escape"
+)
+
+// SQLDialect sets the dialect of the sql.literal and sql.ident builtins,
+// which quote values as SQL literals and names as SQL identifiers, to
+// generate SQL files such as migrations. By default they follow the SQL
+// standard. See escape.SQLDialect, and its warning: these functions are
+// not a substitute for parameterized queries. The return value is the set,
+// so calls can be chained.
+func (s *Set) SQLDialect(d escape.SQLDialect) *Set {
+	return s.addFuncs(FuncMap{
+		"sql.literal": d.Literal,
+		"sql.ident":   d.Identifier,
+	})
+}
@@ -0,0 +1,42 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gorilla/template/v0/escape"
+)
+
+func TestSQLDialect(t *testing.T) {
+	const text = `INSERT INTO {{sql.ident "app" .Table}} (name) VALUES ({{sql.literal .Name}});`
+	data := map[string]string{"Table": "users", "Name": "O'Brien"}
+	tests := []struct {
+		set    *Set
+		output string
+	}{
+		{new(Set), `INSERT INTO "app"."users" (name) VALUES ('O''Brien');`},
+		{new(Set).SQLDialect(escape.SQLMySQL), "INSERT INTO `app`.`users` (name) VALUES ('O\\'Brien');"},
+		{new(Set).SQLDialect(escape.SQLServer), `INSERT INTO [app].[users] (name) VALUES (N'O''Brien');`},
+	}
+	for _, test := range tests {
+		s := Must(test.set.ParseTemplate("t", text))
+		var b bytes.Buffer
+		if err := s.Execute(&b, "t", data); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		} else if b.String() != test.output {
+			t.Errorf("got %s, want %s", b.String(), test.output)
+		}
+	}	// A negative number after a minus sign doesn't start a comment.
+	s := Must(new(Set).ParseTemplate("t", `UPDATE stock SET n = n-{{sql.literal .}};`))
+	var b bytes.Buffer
+	if err := s.Execute(&b, "t", -5); err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE stock SET n = n-(-5);`; b.String() != want {
+		t.Errorf("got %s, want %s", b.String(), want)
+	}
+}
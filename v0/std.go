@@ -0,0 +1,430 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	stdparse "text/template/parse"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// FromStdTemplate returns a set with the templates associated with t, which
+// can then be extended with templates parsed by the set. Contextual escaping
+// is enabled in the returned set, as it is in html/template, so t must not
+// have been executed: the trees of a template already escaped by
+// html/template can't be converted.
+//
+// The functions added to t can't be retrieved from it: add them to the
+// returned set using Set.Funcs before executing it. The {{break}} and
+// {{continue}} actions and assignments to variables declared outside a
+// pipeline are not supported.
+func FromStdTemplate(t *htmltemplate.Template) (*Set, error) {
+	s := new(Set).Escape()
+	s.init()
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		c := &stdConverter{name: tmpl.Name()}
+		list, err := c.list(tmpl.Tree.Root)
+		if err != nil {
+			return nil, err
+		}
+		err = s.tree.Add(&parse.DefineNode{
+			NodeType: parse.NodeDefine,
+			Pos:      parse.Pos(tmpl.Tree.Root.Pos),
+			Line:     1,
+			Name:     tmpl.Name(),
+			List:     list,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// stdConverter converts the nodes of a text/template parse tree.
+type stdConverter struct {
+	name string // Name of the template being converted.
+}
+
+// errorf returns an error about the template being converted.
+func (c *stdConverter) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("template: %s: %s", c.name, fmt.Sprintf(format, args...))
+}
+
+func (c *stdConverter) list(l *stdparse.ListNode) (*parse.ListNode, error) {
+	if l == nil {
+		return nil, nil
+	}
+	list := &parse.ListNode{NodeType: parse.NodeList, Pos: parse.Pos(l.Pos)}
+	for _, n := range l.Nodes {
+		var node parse.Node
+		switch n := n.(type) {
+		case *stdparse.CommentNode:
+			continue
+		case *stdparse.TextNode:
+			node = &parse.TextNode{
+				NodeType: parse.NodeText,
+				Pos:      parse.Pos(n.Pos),
+				Text:     append([]byte(nil), n.Text...),
+			}
+		case *stdparse.ActionNode:
+			pipe, err := c.pipe(n.Pipe)
+			if err != nil {
+				return nil, err
+			}
+			node = &parse.ActionNode{
+				NodeType: parse.NodeAction,
+				Pos:      parse.Pos(n.Pos),
+				Line:     n.Line,
+				Pipe:     pipe,
+			}
+		case *stdparse.IfNode:
+			b, err := c.branch(parse.NodeIf, &n.BranchNode)
+			if err != nil {
+				return nil, err
+			}
+			node = &parse.IfNode{BranchNode: *b}
+		case *stdparse.RangeNode:
+			b, err := c.branch(parse.NodeRange, &n.BranchNode)
+			if err != nil {
+				return nil, err
+			}
+			node = &parse.RangeNode{BranchNode: *b}
+		case *stdparse.WithNode:
+			b, err := c.branch(parse.NodeWith, &n.BranchNode)
+			if err != nil {
+				return nil, err
+			}
+			node = &parse.WithNode{BranchNode: *b}
+		case *stdparse.TemplateNode:
+			var pipe *parse.PipeNode
+			if n.Pipe != nil {
+				var err error
+				if pipe, err = c.pipe(n.Pipe); err != nil {
+					return nil, err
+				}
+			}
+			node = &parse.TemplateNode{
+				NodeType: parse.NodeTemplate,
+				Pos:      parse.Pos(n.Pos),
+				Line:     n.Line,
+				Name:     n.Name,
+				Pipe:     pipe,
+			}
+		default:
+			return nil, c.errorf("unsupported node %s", n)
+		}
+		list.Nodes = append(list.Nodes, node)
+	}
+	return list, nil
+}
+
+func (c *stdConverter) branch(typ parse.NodeType, b *stdparse.BranchNode) (*parse.BranchNode, error) {
+	pipe, err := c.pipe(b.Pipe)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.list(b.List)
+	if err != nil {
+		return nil, err
+	}
+	elseList, err := c.list(b.ElseList)
+	if err != nil {
+		return nil, err
+	}
+	return &parse.BranchNode{
+		NodeType: typ,
+		Pos:      parse.Pos(b.Pos),
+		Line:     b.Line,
+		Pipe:     pipe,
+		List:     list,
+		ElseList: elseList,
+	}, nil
+}
+
+func (c *stdConverter) pipe(p *stdparse.PipeNode) (*parse.PipeNode, error) {
+	if p.IsAssign {
+		return nil, c.errorf("unsupported assignment %s", p)
+	}
+	pipe := &parse.PipeNode{
+		NodeType: parse.NodePipe,
+		Pos:      parse.Pos(p.Pos),
+		Line:     p.Line,
+	}
+	for _, v := range p.Decl {
+		pipe.Decl = append(pipe.Decl, c.variable(v))
+	}
+	for _, cmd := range p.Cmds {
+		command := &parse.CommandNode{NodeType: parse.NodeCommand, Pos: parse.Pos(cmd.Pos)}
+		for _, arg := range cmd.Args {
+			a, err := c.arg(arg)
+			if err != nil {
+				return nil, err
+			}
+			command.Args = append(command.Args, a)
+		}
+		pipe.Cmds = append(pipe.Cmds, command)
+	}
+	return pipe, nil
+}
+
+func (c *stdConverter) variable(v *stdparse.VariableNode) *parse.VariableNode {
+	return &parse.VariableNode{
+		NodeType: parse.NodeVariable,
+		Pos:      parse.Pos(v.Pos),
+		Ident:    append([]string(nil), v.Ident...),
+	}
+}
+
+func (c *stdConverter) arg(n stdparse.Node) (parse.Node, error) {
+	switch n := n.(type) {
+	case *stdparse.BoolNode:
+		return &parse.BoolNode{NodeType: parse.NodeBool, Pos: parse.Pos(n.Pos), True: n.True}, nil
+	case *stdparse.ChainNode:
+		node, err := c.arg(n.Node)
+		if err != nil {
+			return nil, err
+		}
+		return &parse.ChainNode{
+			NodeType: parse.NodeChain,
+			Pos:      parse.Pos(n.Pos),
+			Node:     node,
+			Field:    append([]string(nil), n.Field...),
+		}, nil
+	case *stdparse.DotNode:
+		return &parse.DotNode{Pos: parse.Pos(n.Pos)}, nil
+	case *stdparse.FieldNode:
+		return &parse.FieldNode{
+			NodeType: parse.NodeField,
+			Pos:      parse.Pos(n.Pos),
+			Ident:    append([]string(nil), n.Ident...),
+		}, nil
+	case *stdparse.IdentifierNode:
+		if strings.HasPrefix(n.Ident, "_html_template_") {
+			return nil, c.errorf("already escaped by html/template")
+		}
+		return parse.NewIdentifier(n.Ident).SetPos(parse.Pos(n.Pos)), nil
+	case *stdparse.NilNode:
+		return &parse.NilNode{Pos: parse.Pos(n.Pos)}, nil
+	case *stdparse.NumberNode:
+		return &parse.NumberNode{
+			NodeType:   parse.NodeNumber,
+			Pos:        parse.Pos(n.Pos),
+			IsInt:      n.IsInt,
+			IsUint:     n.IsUint,
+			IsFloat:    n.IsFloat,
+			IsComplex:  n.IsComplex,
+			Int64:      n.Int64,
+			Uint64:     n.Uint64,
+			Float64:    n.Float64,
+			Complex128: n.Complex128,
+			Text:       n.Text,
+		}, nil
+	case *stdparse.PipeNode:
+		return c.pipe(n)
+	case *stdparse.StringNode:
+		return &parse.StringNode{
+			NodeType: parse.NodeString,
+			Pos:      parse.Pos(n.Pos),
+			Quoted:   n.Quoted,
+			Text:     n.Text,
+		}, nil
+	case *stdparse.VariableNode:
+		return c.variable(n), nil
+	}
+	return nil, c.errorf("unsupported argument %s", n)
+}
+
+// ToStdTrees compiles the set and returns its templates as text/template
+// parse trees, indexed by name, so that tools written for text/template can
+// analyze them. The trees reflect the compiled set: slots are replaced by
+// their contents and, if the set is escaped, escaping functions are added to
+// the pipelines. A {{let}} block is represented as an {{if}} action that
+// declares the variable and executes the block in both branches. Custom
+// nodes are left out.
+//
+// Node positions refer to the input text of the template that defined each
+// node, which the trees don't retain, so their ErrorContext method can't be
+// used.
+func (s *Set) ToStdTrees() (map[string]*stdparse.Tree, error) {
+	if _, err := s.Compile(); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	trees := make(map[string]*stdparse.Tree, len(s.tree))
+	for name, define := range s.tree {
+		t := &stdparse.Tree{Name: name, ParseName: name, Mode: stdparse.ParseComments}
+		t.Root = toStdList(define.List, t)
+		trees[name] = t
+	}
+	return trees, nil
+}
+
+// toStdList converts a list of nodes to text/template, flattening the
+// nested lists left by inlining.
+func toStdList(l *parse.ListNode, t *stdparse.Tree) *stdparse.ListNode {
+	if l == nil {
+		return nil
+	}
+	list := &stdparse.ListNode{NodeType: stdparse.NodeList, Pos: stdparse.Pos(l.Pos)}
+	var add func(l *parse.ListNode)
+	add = func(l *parse.ListNode) {
+		for _, n := range l.Nodes {
+			if n, ok := n.(*parse.ListNode); ok {
+				add(n)
+				continue
+			}
+			if node := toStdNode(n, t); node != nil {
+				list.Nodes = append(list.Nodes, node)
+			}
+		}
+	}
+	add(l)
+	return list
+}
+
+func toStdNode(n parse.Node, t *stdparse.Tree) stdparse.Node {
+	switch n := n.(type) {
+	case *parse.ActionNode:
+		return &stdparse.ActionNode{
+			NodeType: stdparse.NodeAction,
+			Pos:      stdparse.Pos(n.Pos),
+			Line:     n.Line,
+			Pipe:     toStdPipe(n.Pipe, t),
+		}
+	case *parse.ConstNode:
+		return toStdList(n.List, t)
+	case *parse.IfNode:
+		return &stdparse.IfNode{BranchNode: toStdBranch(stdparse.NodeIf, &n.BranchNode, t)}
+	case *parse.LetNode:
+		b := toStdBranch(stdparse.NodeIf, &n.BranchNode, t)
+		b.ElseList = toStdList(n.List, t)
+		return &stdparse.IfNode{BranchNode: b}
+	case *parse.RangeNode:
+		return &stdparse.RangeNode{BranchNode: toStdBranch(stdparse.NodeRange, &n.BranchNode, t)}
+	case *parse.SlotNode:
+		return toStdList(n.List, t)
+	case *parse.TemplateNode:
+		var pipe *stdparse.PipeNode
+		if n.Pipe != nil {
+			pipe = toStdPipe(n.Pipe, t)
+		}
+		return &stdparse.TemplateNode{
+			NodeType: stdparse.NodeTemplate,
+			Pos:      stdparse.Pos(n.Pos),
+			Line:     n.Line,
+			Name:     n.Name,
+			Pipe:     pipe,
+		}
+	case *parse.TextNode:
+		return &stdparse.TextNode{
+			NodeType: stdparse.NodeText,
+			Pos:      stdparse.Pos(n.Pos),
+			Text:     append([]byte(nil), n.Text...),
+		}
+	case *parse.WithNode:
+		return &stdparse.WithNode{BranchNode: toStdBranch(stdparse.NodeWith, &n.BranchNode, t)}
+	}
+	// Fills are removed by compilation, and custom nodes can't be
+	// represented.
+	return nil
+}
+
+func toStdBranch(typ stdparse.NodeType, b *parse.BranchNode, t *stdparse.Tree) stdparse.BranchNode {
+	return stdparse.BranchNode{
+		NodeType: typ,
+		Pos:      stdparse.Pos(b.Pos),
+		Line:     b.Line,
+		Pipe:     toStdPipe(b.Pipe, t),
+		List:     toStdList(b.List, t),
+		ElseList: toStdList(b.ElseList, t),
+	}
+}
+
+func toStdPipe(p *parse.PipeNode, t *stdparse.Tree) *stdparse.PipeNode {
+	pipe := &stdparse.PipeNode{
+		NodeType: stdparse.NodePipe,
+		Pos:      stdparse.Pos(p.Pos),
+		Line:     p.Line,
+	}
+	for _, v := range p.Decl {
+		pipe.Decl = append(pipe.Decl, toStdVariable(v))
+	}
+	for _, cmd := range p.Cmds {
+		command := &stdparse.CommandNode{NodeType: stdparse.NodeCommand, Pos: stdparse.Pos(cmd.Pos)}
+		for _, arg := range cmd.Args {
+			command.Args = append(command.Args, toStdArg(arg, t))
+		}
+		pipe.Cmds = append(pipe.Cmds, command)
+	}
+	return pipe
+}
+
+func toStdVariable(v *parse.VariableNode) *stdparse.VariableNode {
+	return &stdparse.VariableNode{
+		NodeType: stdparse.NodeVariable,
+		Pos:      stdparse.Pos(v.Pos),
+		Ident:    append([]string(nil), v.Ident...),
+	}
+}
+
+func toStdArg(n parse.Node, t *stdparse.Tree) stdparse.Node {
+	switch n := n.(type) {
+	case *parse.BoolNode:
+		return &stdparse.BoolNode{NodeType: stdparse.NodeBool, Pos: stdparse.Pos(n.Pos), True: n.True}
+	case *parse.ChainNode:
+		return &stdparse.ChainNode{
+			NodeType: stdparse.NodeChain,
+			Pos:      stdparse.Pos(n.Pos),
+			Node:     toStdArg(n.Node, t),
+			Field:    append([]string(nil), n.Field...),
+		}
+	case *parse.DotNode:
+		return &stdparse.DotNode{NodeType: stdparse.NodeDot, Pos: stdparse.Pos(n.Pos)}
+	case *parse.FieldNode:
+		return &stdparse.FieldNode{
+			NodeType: stdparse.NodeField,
+			Pos:      stdparse.Pos(n.Pos),
+			Ident:    append([]string(nil), n.Ident...),
+		}
+	case *parse.IdentifierNode:
+		return stdparse.NewIdentifier(n.Ident).SetTree(t).SetPos(stdparse.Pos(n.Pos))
+	case *parse.NilNode:
+		return &stdparse.NilNode{NodeType: stdparse.NodeNil, Pos: stdparse.Pos(n.Pos)}
+	case *parse.NumberNode:
+		return &stdparse.NumberNode{
+			NodeType:   stdparse.NodeNumber,
+			Pos:        stdparse.Pos(n.Pos),
+			IsInt:      n.IsInt,
+			IsUint:     n.IsUint,
+			IsFloat:    n.IsFloat,
+			IsComplex:  n.IsComplex,
+			Int64:      n.Int64,
+			Uint64:     n.Uint64,
+			Float64:    n.Float64,
+			Complex128: n.Complex128,
+			Text:       n.Text,
+		}
+	case *parse.PipeNode:
+		return toStdPipe(n, t)
+	case *parse.StringNode:
+		return &stdparse.StringNode{
+			NodeType: stdparse.NodeString,
+			Pos:      stdparse.Pos(n.Pos),
+			Quoted:   n.Quoted,
+			Text:     n.Text,
+		}
+	case *parse.VariableNode:
+		return toStdVariable(n)
+	}
+	panic(fmt.Sprintf("template: unexpected argument %s", n))
+}
@@ -0,0 +1,79 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+)
+
+func TestFromStdTemplate(t *testing.T) {
+	std := htmltemplate.Must(htmltemplate.New("list").Funcs(htmltemplate.FuncMap{
+		"upper": strings.ToUpper,
+	}).Parse(`{{range $i, $e := .}}{{template "item" upper $e}}{{else}}none{{end}}` +
+		`{{define "item"}}{{/* an item */}}<li>{{.}}</li>{{end}}`))
+	set, err := FromStdTemplate(std)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = set.Funcs(FuncMap{"upper": strings.ToUpper}).Parse(
+		`{{define "page"}}<ul>{{template "list" .}}</ul>{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err = set.Execute(b, "page", []string{"a<b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "<ul><li>A&lt;B</li><li>C</li></ul>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Templates already escaped by html/template can't be converted.
+	if err = std.ExecuteTemplate(new(bytes.Buffer), "list", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = FromStdTemplate(std); err == nil || !strings.Contains(err.Error(), "already escaped") {
+		t.Errorf("expected escaped error; got %v", err)
+	}
+
+	std = htmltemplate.Must(htmltemplate.New("x").Parse(`{{range .}}{{break}}{{end}}`))
+	if _, err = FromStdTemplate(std); err == nil || !strings.Contains(err.Error(), "template: x: unsupported node") {
+		t.Errorf("expected unsupported node error; got %v", err)
+	}
+}
+
+func TestToStdTrees(t *testing.T) {
+	set := Must(new(Set).Parse(`
+{{define "base"}}[{{slot "body"}}default{{end}}]{{end}}
+{{define "page" "base"}}{{fill "body"}}{{let $x := .}}{{$x}}{{range .}}{{$x}}{{end}}{{end}}{{end}}{{end}}
+`))
+	trees, err := set.ToStdTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	std := texttemplate.New("")
+	for name, tree := range trees {
+		if _, err = std.AddParseTree(name, tree); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, data := range [][]int{{}, {1, 2}} {
+		want := new(bytes.Buffer)
+		if err = set.Execute(want, "page", data); err != nil {
+			t.Fatal(err)
+		}
+		got := new(bytes.Buffer)
+		if err = std.ExecuteTemplate(got, "page", data); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%v: got %q, want %q", data, got, want)
+		}
+	}
+}
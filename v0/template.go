@@ -6,10 +6,13 @@ package template
 
 import (
 	"fmt"
-	"io/ioutil"
+	"path"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/template/v0/escape"
 	"github.com/gorilla/template/v0/parse"
@@ -32,12 +35,46 @@ import (
 //         // do something with the execution error...
 //     }
 type Set struct {
-	mutex      sync.Mutex
-	tree       parse.Tree
-	leftDelim  string
-	rightDelim string
-	escape     bool // compilation flag to perform contextual escaping
-	compiled   bool // compilation flag to lock the set after first execution
+	mutex       sync.Mutex
+	rebuilding  sync.Mutex // serializes Reload and Extend
+	tree        parse.Tree
+	leftDelim   string
+	rightDelim  string
+	autoDefine  bool                     // parsing flag to name files without defines
+	fileParent  bool                     // parsing flag to set parents of files without defines
+	files       FilePolicy               // parsing option to select the files matched by ParseGlob
+	syntax      Syntax                   // parsing option for the syntax of templates
+	limits      Limits                   // parsing option to bound the size of templates
+	parsed      parseUsage               // parsing totals checked against the limits
+	imported    map[string]bool          // paths of the files parsed by {{import}}
+	sources     []parseCall              // parse calls, replayed by Reload
+	read        map[string]bool          // paths of the template and included files read
+	globs       []string                 // patterns passed to ParseGlob
+	modTimes    map[string]time.Time     // modification times of the files, checked by Reload
+	escape      bool                     // compilation flag to perform contextual escaping
+	compiled    bool                     // compilation flag to lock the set after first execution
+	compileErr  error                    // error of the failed compilation, returned by later ones
+	passes      []CompilePass            // compilation steps added with AddPass
+	source      SourcePolicy             // compilation option to retain the input text
+	nilPolicy   NilPolicy                // execution option for printing nil values
+	profile     *Profile                 // execution option for collecting measurements
+	lenient     bool                     // execution flag to continue after errors in actions
+	placeholder string                   // execution option for the output of failed actions
+	consts      map[string]reflect.Value // values of the constants defined by {{set}}
+	executors   map[string]NodeExecutor  // execution handlers of custom nodes
+	audit       func(Bypass)             // execution option to report escaping bypasses
+	provenance  map[string]Provenance    // origins of the templates not parsed from code
+	restrict    *Restrictions            // compilation option to restrict untrusted templates
+	truths      truthFuncs               // execution option for the truth values of types
+	recorder    *AccessRecorder          // execution option to record the data accessed
+	log         logConfig                // logging options
+	debug       bool                     // execution flag to enable {{debug}}
+	deprecated  map[string]string        // deprecated templates and funcs, with their messages
+	bindings    map[string]reflect.Type  // types of data bound to templates, checked by Render
+	output      outputCache              // execution option to cache rendered output
+	aliases     map[string]string        // compilation option mapping aliases to template names
+	lineEnding  LineEnding               // execution option for output line endings
+	indent      bool                     // execution flag to re-indent called templates
 	// We use two maps, one for parsing and one for execution.
 	parseFuncs FuncMap
 	execFuncs  map[string]reflect.Value
@@ -58,7 +95,8 @@ func (s *Set) init() {
 
 // Delims sets the action delimiters to the specified strings, to be used in
 // subsequent calls to Parse. An empty delimiter stands for the corresponding
-// default: "{{" or "}}".
+// default: "{{" or "}}". The delimiters can have any length and don't need
+// to match, as in Delims("<%", "%>") for templates written for ERB.
 // The return value is the set, so calls can be chained.
 func (s *Set) Delims(left, right string) *Set {
 	s.leftDelim = left
@@ -68,15 +106,196 @@ func (s *Set) Delims(left, right string) *Set {
 
 // Funcs adds the elements of the argument map to the template's function map.
 // It panics if a value in the map is not a function with appropriate return
-// type. However, it is legal to overwrite elements of the map. The return
-// value is the set, so calls can be chained.
+// type, or if its name is reserved: the escaping builtins html, js and
+// urlquery and the functions named html_template_* used by contextual
+// escaping can't be overridden, since that could disable escaping. However,
+// it is legal to overwrite other elements of the map. The return value is
+// the set, so calls can be chained.
 func (s *Set) Funcs(funcMap FuncMap) *Set {
+	for name := range funcMap {
+		if err := checkReservedFunc(name); err != nil {
+			panic(err)
+		}
+	}
+	return s.addFuncs(funcMap)
+}
+
+// addFuncs adds the functions to the set without checking their names.
+func (s *Set) addFuncs(funcMap FuncMap) *Set {
 	s.init()
 	addValueFuncs(s.execFuncs, funcMap)
 	addFuncs(s.parseFuncs, funcMap)
 	return s
 }
 
+// ReplaceFunc replaces the implementation of the function with the given
+// name, added with Funcs, by fn, which must have the same type. Unlike
+// Funcs, it is safe to call while the set is executed, for example to swap
+// behavior with a feature flag: executions in progress finish with the
+// previous function, and later ones use fn. Snapshots and prepared
+// templates keep the function they were created with.
+//
+// As with Funcs, reserved escaping functions can't be replaced.
+func (s *Set) ReplaceFunc(name string, fn interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := checkReservedFunc(name); err != nil {
+		return err
+	}
+	old, ok := s.execFuncs[name]
+	if !ok {
+		return fmt.Errorf("template: no function %q to replace", name)
+	}
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() || v.Type() != old.Type() {
+		return fmt.Errorf("template: function %q must be replaced by a %s; got %T", name, old.Type(), fn)
+	}
+	// Executions in progress use the current maps, so they are copied.
+	execFuncs := copyFuncs(s.execFuncs)
+	execFuncs[name] = v
+	parseFuncs := make(FuncMap, len(s.parseFuncs))
+	addFuncs(parseFuncs, s.parseFuncs)
+	parseFuncs[name] = fn
+	s.execFuncs, s.parseFuncs = execFuncs, parseFuncs
+	return nil
+}
+
+// FlatBuiltins makes the namespaced builtin functions also available by
+// their short names, as in {{upper .Name}} instead of {{strings.upper .Name}}.
+// Functions with the same name added to the set using Funcs take precedence
+// over the flat aliases. The return value is the set, so calls can be
+// chained.
+func (s *Set) FlatBuiltins() *Set {
+	s.init()
+	for name, fn := range flatBuiltins {
+		if _, ok := s.parseFuncs[name]; !ok {
+			if truthBuiltins[name] == nil {
+				s.execFuncs[name] = reflect.ValueOf(fn)
+			}
+			s.parseFuncs[name] = fn
+		}
+	}
+	return s
+}
+
+// PrintNil sets how nil pointers and interfaces and missing values are
+// printed. By default they print as "<nil>" or "<no value>", which is
+// rarely desirable in HTML output. The return value is the set, so calls
+// can be chained.
+func (s *Set) PrintNil(policy NilPolicy) *Set {
+	s.nilPolicy = policy
+	return s
+}
+
+// SourcePolicy defines what is retained of the input text of templates
+// after compilation. See Set.RetainSource.
+type SourcePolicy int
+
+const (
+	// SourceKeep keeps the input text, so that execution errors report
+	// the line and column of the failing action.
+	SourceKeep SourcePolicy = iota
+	// SourceDrop discards the input text. Execution errors only report
+	// the template name and the failing action.
+	SourceDrop
+	// SourceLines discards the input text but keeps the offsets of its
+	// lines, so that execution errors still report line and column.
+	SourceLines
+)
+
+// RetainSource sets what is kept of the input text of templates after the
+// set is compiled. By default the text is kept for error messages, which
+// for sets of thousands of templates can use a lot of memory; SourceLines
+// keeps the same messages using a fraction of it. The return
+// value is the set, so calls can be chained.
+func (s *Set) RetainSource(policy SourcePolicy) *Set {
+	s.source = policy
+	return s
+}
+
+// Size returns the approximate number of bytes used by the templates in the
+// set: their parse trees and the input text retained for error messages.
+func (s *Set) Size() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.tree.Size()
+}
+
+// AutoDefine makes ParseFiles and ParseGlob parse a file that contains no
+// {{define}} actions as a single template, named after the file path as
+// passed to ParseFiles or matched by ParseGlob, using forward slashes. It
+// spares the {{define}} boilerplate when each file holds one template. The
+// return value is the set, so calls can be chained.
+func (s *Set) AutoDefine() *Set {
+	s.autoDefine = true
+	return s
+}
+
+// FileInheritance makes a file parsed as a single template because of
+// AutoDefine extend the parent template set by its file name, as in
+// "article.extends.layout.html", which defines the template "article.html"
+// extending "layout.html" in the same directory. An {{extends}} action at
+// the start of the file takes precedence over the file name. The return
+// value is the set, so calls can be chained.
+func (s *Set) FileInheritance() *Set {
+	s.fileParent = true
+	return s
+}
+
+// FilePolicy selects the files parsed by ParseGlob among those matched by
+// the pattern, which never include directories. The zero value, the default, skips symbolic links and hidden
+// files, so that the files parsed don't depend on what a deployment
+// happens to leave in the template directories.
+type FilePolicy struct {
+	// FollowSymlinks makes ParseGlob parse the files that are symbolic
+	// links, reading their targets.
+	FollowSymlinks bool
+	// Hidden makes ParseGlob parse hidden files: files, or files in
+	// directories, whose name starts with a dot and is matched by a
+	// wildcard of the pattern. Names written in the pattern, as in
+	// ".config/*.html", are always allowed.
+	Hidden bool
+	// Extensions, if not empty, are the extensions of the files parsed,
+	// like ".html".
+	Extensions []string
+}
+
+// Files sets the policy selecting the files parsed by ParseGlob. Files
+// passed to ParseFiles are always parsed. The return value is the set, so
+// calls can be chained.
+func (s *Set) Files(p FilePolicy) *Set {
+	p.Extensions = append([]string(nil), p.Extensions...)
+	s.files = p
+	return s
+}
+
+// Syntax identifies the syntax of the templates parsed by a set.
+type Syntax int
+
+const (
+	// SyntaxNative is the syntax of this package, the default.
+	SyntaxNative Syntax = iota
+	// SyntaxJinja is a subset of the syntax of Jinja2 and Django
+	// templates, described in parse.ParseJinja.
+	SyntaxJinja
+	// SyntaxMustache is the syntax of Mustache and Handlebars templates,
+	// described in parse.ParseMustache.
+	SyntaxMustache
+)
+
+// Syntax sets the syntax of the templates parsed by subsequent calls to
+// ParseTemplate, ParseFiles and ParseGlob. With a syntax other than
+// SyntaxNative each text defines a single template, named as with
+// AutoDefine, and Parse can't be used. The return value is the set, so
+// calls can be chained.
+func (s *Set) Syntax(syntax Syntax) *Set {
+	s.syntax = syntax
+	if syntax == SyntaxMustache {
+		s.Funcs(mustacheFuncs)
+	}
+	return s
+}
+
 // Escape turns on contextual escaping in all templates in the set, rewriting
 // them to guarantee that the output is safe. The return value is the set,
 // so calls can be chained.
@@ -85,6 +304,32 @@ func (s *Set) Escape() *Set {
 	return s
 }
 
+// CompilePass is a custom step of the compilation of a set. Run receives
+// the templates of the set after inlining and removal of dead branches,
+// and can rewrite them in place, for example to extract messages for
+// translation or to transform markup. Contextual escaping is performed
+// after all passes.
+type CompilePass interface {
+	Run(tree parse.Tree) error
+}
+
+// CompilePassFunc is an adapter to allow the use of ordinary functions as
+// compilation passes.
+type CompilePassFunc func(tree parse.Tree) error
+
+// Run calls f(tree).
+func (f CompilePassFunc) Run(tree parse.Tree) error {
+	return f(tree)
+}
+
+// AddPass adds a step to the compilation of the set. Passes run in the
+// order they were added, and an error returned by a pass stops the
+// compilation. The return value is the set, so calls can be chained.
+func (s *Set) AddPass(p CompilePass) *Set {
+	s.passes = append(s.passes, p)
+	return s
+}
+
 // Clone returns a duplicate of the template, including all associated
 // templates. The actual representation is not copied, but the name space of
 // associated templates is, so further calls to Parse in the copy will add
@@ -106,101 +351,333 @@ func (s *Set) Clone() (*Set, error) {
 	if err != nil {
 		return nil, err
 	}
+	ns.autoDefine = s.autoDefine
+	ns.fileParent = s.fileParent
+	ns.files = s.files
+	ns.limits = s.limits
+	ns.parsed = s.parsed
+	ns.sources = s.sources[:len(s.sources):len(s.sources)]
+	for path := range s.read {
+		ns.addRead(path)
+	}
+	ns.globs = s.globs[:len(s.globs):len(s.globs)]
+	ns.syntax = s.syntax
+	for path := range s.imported {
+		if ns.imported == nil {
+			ns.imported = make(map[string]bool)
+		}
+		ns.imported[path] = true
+	}
 	ns.escape = s.escape
+	ns.passes = append([]CompilePass(nil), s.passes...)
 	ns.compiled = s.compiled
+	ns.compileErr = s.compileErr
+	ns.nilPolicy = s.nilPolicy
+	ns.source = s.source
+	ns.profile = s.profile
+	ns.consts = s.consts
+	ns.lenient = s.lenient
+	ns.placeholder = s.placeholder
+	ns.audit = s.audit
+	ns.restrict = s.restrict
+	for name, prov := range s.provenance {
+		if ns.provenance == nil {
+			ns.provenance = make(map[string]Provenance)
+		}
+		ns.provenance[name] = prov
+	}
+	ns.truths = s.truths
+	ns.recorder = s.recorder
+	ns.log = s.log
+	ns.debug = s.debug
+	ns.deprecated = s.deprecated
+	ns.bindings = s.bindings
+	ns.output = s.output
+	ns.aliases = s.aliases
+	ns.lineEnding = s.lineEnding
+	ns.indent = s.indent
+	for kind, fn := range s.executors {
+		if ns.executors == nil {
+			ns.executors = make(map[string]NodeExecutor)
+		}
+		ns.executors[kind] = fn
+	}
 	return ns, nil
 }
 
-// Compile performs inlining and contextual escaping in all templates in the
-// set. This doesn't need to be called manually because the set is compiled
-// automatically when executed, but it can be used to force compilation and
-// catch errors earlier.
+// Compile evaluates the constants defined by {{set}}, and performs inlining,
+// removal of {{if}} branches that can't execute because their pipeline is a
+// constant, the passes added with AddPass, detection of infinite recursion
+// and contextual escaping in all templates in the set. This doesn't need to be
+// called manually because the set is compiled automatically when executed,
+// but it can be used to force compilation and catch errors earlier.
+//
+// The set is compiled once, even by concurrent first executions. If
+// compilation fails, later calls and executions return the same error,
+// since the templates may be left partially compiled.
 func (s *Set) Compile() (*Set, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if s.compileErr != nil {
+		return nil, s.compileErr
+	}
 	if !s.compiled {
-		// Inlining.
-		if err := inlineTree(s.tree); err != nil {
+		if err := s.compile(); err != nil {
+			s.compileErr = err
 			return nil, err
 		}
-		// Contextual escaping.
-		if s.escape {
-			if err := escape.EscapeTree(s.tree); err != nil {
-				return nil, err
-			}
-			s.Funcs(escape.FuncMap)
-		}
 		s.compiled = true
 	}
 	return s, nil
 }
 
+// compile performs the compilation steps described in Compile. The caller
+// must hold the mutex.
+func (s *Set) compile() error {
+	// Constants.
+	if err := s.evalConsts(); err != nil {
+		return err
+	}
+	// Inlining.
+	if err := resolveAliases(s.tree, s.aliases); err != nil {
+		return err
+	}
+	if err := checkUntrusted(s.tree, s.provenance, s.restrict); err != nil {
+		return err
+	}
+	if err := checkSlots(s.tree); err != nil {
+		return err
+	}
+	lint(s.tree, s.log)
+	warnDeprecated(s.tree, s.deprecated, s.log)
+	if err := inlineTree(s.tree); err != nil {
+		return err
+	}
+	// Dead branch elimination.
+	foldTree(s.tree, s.truths.consts(s.consts))
+	// Custom passes.
+	for _, p := range s.passes {
+		if err := p.Run(s.tree); err != nil {
+			return err
+		}
+	}
+	if err := checkRecursion(s.tree); err != nil {
+		return err
+	}
+	if s.restrict != nil {
+		if err := s.restrict.checkRanges(s.tree, s.provenance); err != nil {
+			return err
+		}
+	}
+	// Contextual escaping.
+	if s.escape {
+		lintEscaping(s.tree, s.log)
+		if err := escape.EscapeTree(s.tree); err != nil {
+			return err
+		}
+		s.addFuncs(escape.FuncMap)
+	}
+	switch s.source {
+	case SourceDrop:
+		for _, define := range s.tree {
+			define.DropText()
+		}
+	case SourceLines:
+		s.tree.KeepLines()
+	}
+	return nil
+}
+
+// Stub replaces the named template by one that outputs the given text
+// verbatim, so that tests can avoid rendering heavy partials. The set is
+// compiled first; variants of the template derived by contextual escaping
+// are stubbed as well. Calling the returned function restores the original
+// template.
+//
+// Stub must not be called concurrently with executions of the set.
+func (s *Set) Stub(name, output string) (restore func(), err error) {
+	if _, err = s.Compile(); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.tree[name] == nil {
+		return nil, notFound(s.tree, name)
+	}
+	saved := make(parse.Tree)
+	for k, v := range s.tree {
+		if k == name || strings.HasPrefix(k, name+"$htmltemplate_") {
+			saved[k] = v
+			stub := v.CopyDefine()
+			stub.List = &parse.ListNode{
+				NodeType: parse.NodeList,
+				Nodes: []parse.Node{&parse.TextNode{
+					NodeType: parse.NodeText,
+					Text:     []byte(output),
+				}},
+			}
+			s.tree[k] = stub
+		}
+	}
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for k, v := range saved {
+			s.tree[k] = v
+		}
+	}, nil
+}
+
 // Parse ----------------------------------------------------------------------
 
 // parse parses the given text and adds the resulting templates to the set.
-// The name is only used for debugging purposes: when parsing files or glob,
-// it can show which file caused an error.
+// The name is used for debugging purposes: when parsing files or glob, it
+// can show which file caused an error. If body is true, a text without
+// defines is parsed as the contents of a template with that name, which
+// extends parent if it is not empty.
 //
 // Parsing templates after the set executed results in an error.
-func (s *Set) parse(text, name string) (*Set, error) {
+func (s *Set) parse(text, name, parent string, body bool, prov Provenance) (*Set, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	if s.compiled {
+	if s.compiled || s.compileErr != nil {
 		return nil, fmt.Errorf(
 			"template: new templates can't be added after execution")
 	}
 	s.init()
-	if tree, err := parse.Parse(name, text, s.leftDelim, s.rightDelim,
-		builtins, s.parseFuncs); err != nil {
+	var tree parse.Tree
+	var err error
+	if s.syntax == SyntaxNative && prov != ProvenanceUntrusted {
+		if text, err = s.expandIncludes(name, text); err != nil {
+			return nil, err
+		}
+	}
+	if err = s.checkLimits(name, text, prov == ProvenanceDisk); err != nil {
+		return nil, err
+	}
+	switch {
+	case s.syntax == SyntaxJinja && body:
+		tree, err = parse.ParseJinja(name, text, builtins, s.parseFuncs)
+	case s.syntax == SyntaxMustache && body:
+		tree, err = parse.ParseMustache(name, text, builtins, s.parseFuncs)
+	case s.syntax != SyntaxNative:
+		err = fmt.Errorf("template: %s: a template name is needed to parse this syntax", name)
+	case body:
+		tree, err = parse.ParseFile(name, text, s.leftDelim, s.rightDelim,
+			builtins, s.parseFuncs)
+	default:
+		tree, err = parse.Parse(name, text, s.leftDelim, s.rightDelim,
+			builtins, s.parseFuncs)
+	}
+	if err != nil {
 		return nil, err
-	} else if err = s.tree.AddTree(tree); err != nil {
+	}
+	if len(tree) == 0 && !body && prov == ProvenanceDisk {
+		// An empty or comment-only file, which is most likely a mistake.
+		return nil, fmt.Errorf("template: %s: file defines no templates", name)
+	}
+	if define := tree[name]; define != nil && parent != "" && define.Parent == "" {
+		define.Parent = parent
+	}
+	if prov == ProvenanceUntrusted {
+		if err = checkUntrustedText(name, tree); err != nil {
+			return nil, err
+		}
+	} else if err = s.loadImports(tree); err != nil {
+		return nil, err
+	}
+	if err = s.tree.AddTree(tree); err != nil {
 		return nil, err
 	}
+	s.setProvenance(tree, prov)
 	return s, nil
 }
 
+// parseFile parses the text of the named file and adds the resulting
+// templates to the set, applying AutoDefine and FileInheritance.
+func (s *Set) parseFile(text, filename string) (*Set, error) {
+	if !s.autoDefine && s.syntax == SyntaxNative {
+		return s.parse(text, filename, "", false, ProvenanceDisk)
+	}
+	name, parent := filepath.ToSlash(filename), ""
+	if s.fileParent {
+		name, parent = extendsFileName(name)
+	}
+	return s.parse(text, name, parent, true, ProvenanceDisk)
+}
+
+// extendsFileName returns the template name and the parent defined by a
+// file name like "dir/article.extends.layout.html", which are
+// "dir/article.html" and "dir/layout.html". Other names are returned
+// unchanged, without parent.
+func extendsFileName(filename string) (name, parent string) {
+	const sep = ".extends."
+	dir, base := path.Split(filename)
+	i := strings.Index(base, sep)
+	if i <= 0 || i+len(sep) == len(base) {
+		return filename, ""
+	}
+	rest := base[i+len(sep):]
+	return dir + base[:i] + path.Ext(rest), dir + rest
+}
+
 // Parse parses the given text and adds the resulting templates to the set.
 // If an error occurs, parsing stops and the returned set is nil; otherwise
 // it is s.
 func (s *Set) Parse(text string) (*Set, error) {
-	return s.parse(text, "template string")
+	return s.record(func(s *Set) (*Set, error) {
+		return s.parse(text, "template string", "", false, ProvenanceCode)
+	})
 }
 
-// ParseFiles parses the named files and adds the resulting templates to the
-// set. There must be at least one file. If an error occurs, parsing stops and
-// the returned set is nil; otherwise it is s.
-func (s *Set) ParseFiles(filenames ...string) (*Set, error) {
-	if len(filenames) == 0 {
-		// Not really a problem, but be consistent.
-		return nil, fmt.Errorf(
-			"template: ParseFiles must be called with at least one filename")
-	}
-	for _, filename := range filenames {
-		if b, err := ioutil.ReadFile(filename); err != nil {
-			return nil, err
-		} else if _, err = s.parse(string(b), filename); err != nil {
-			return nil, err
-		}
+// ParseTemplate is like Parse, but if the text contains no {{define}}
+// actions it is parsed as the contents of a template with the given name,
+// as html/template does. Such a template can extend another one with an
+// {{extends "parent"}} action at its start.
+func (s *Set) ParseTemplate(name, text string) (*Set, error) {
+	return s.record(func(s *Set) (*Set, error) {
+		return s.parse(text, name, "", true, ProvenanceCode)
+	})
+}
+
+// parseCall is a call to a parse method, such as Parse or ParseGlob.
+type parseCall func(*Set) (*Set, error)
+
+// record calls source and records it to be replayed by Reload if it
+// succeeds.
+func (s *Set) record(source parseCall) (*Set, error) {
+	if _, err := source(s); err != nil {
+		return nil, err
 	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sources = append(s.sources, source)
 	return s, nil
 }
 
-// ParseGlob parses the template definitions in the files identified by the
-// pattern and adds the resulting templates to the set. The pattern is
-// processed by filepath.Glob and must match at least one file. ParseGlob is
-// equivalent to calling s.ParseFiles with the list of files matched by the
-// pattern. If an error occurs, parsing stops and the returned set is nil;
-// otherwise it is s.
-func (s *Set) ParseGlob(pattern string) (*Set, error) {
-	filenames, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, err
+// addRead records that a template file was read, to be watched by Reload.
+// The caller must hold the mutex.
+func (s *Set) addRead(path string) {
+	if s.read == nil {
+		s.read = make(map[string]bool)
 	}
-	if len(filenames) == 0 {
-		return nil, fmt.Errorf(
-			"template: pattern doesn't match any files: %#q", pattern)
+	s.read[path] = true
+}
+
+// Names returns the sorted names of the templates in the set. Variants of
+// templates derived by contextual escaping and constants defined by {{set}}
+// are not included.
+func (s *Set) Names() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var names []string
+	for name, define := range s.tree {
+		if !strings.Contains(name, "$htmltemplate_") && !define.IsConstant() {
+			names = append(names, name)
+		}
 	}
-	return s.ParseFiles(filenames...)
+	sort.Strings(names)
+	return names
 }
 
 // Convenience parsing wrappers -----------------------------------------------
@@ -0,0 +1,114 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Truther is implemented by values that define their own truth value, for
+// example a money amount that is false when zero or an optional value that
+// is false when unset. It is consulted by {{if}}, {{with}} and the and, or
+// and not functions in place of the usual rules.
+type Truther interface {
+	IsTrue() bool
+}
+
+var trutherType = reflect.TypeOf((*Truther)(nil)).Elem()
+
+// truthFuncs holds the truth functions of a set, by type.
+type truthFuncs map[reflect.Type]func(interface{}) bool
+
+// Truth registers the function that computes the truth value of the values
+// of type typ in {{if}}, {{with}} and the and, or, not and html.classes
+// builtins, for types that can't implement Truther. It takes precedence
+// over Truther. Functions added with Funcs under the names of the builtins
+// are left alone. The return value is the set, so calls can be chained.
+//
+// Like Funcs, it must be called before the set is used.
+func (s *Set) Truth(typ reflect.Type, fn func(v interface{}) bool) *Set {
+	if typ == nil || fn == nil {
+		panic(fmt.Errorf("template: Truth needs a type and a function"))
+	}
+	truths := make(truthFuncs, len(s.truths)+1)
+	for k, v := range s.truths {
+		truths[k] = v
+	}
+	truths[typ] = fn
+	s.truths = truths
+	return s
+}
+
+// truthBuiltins maps the names of the builtins that depend on truth values
+// to their implementations using the given truth functions.
+var truthBuiltins = map[string]func(truthFuncs) interface{}{
+	"and":          func(t truthFuncs) interface{} { return t.and },
+	"or":           func(t truthFuncs) interface{} { return t.or },
+	"not":          func(t truthFuncs) interface{} { return t.not },
+	"html.classes": func(t truthFuncs) interface{} { return t.classes },
+	"classes":      func(t truthFuncs) interface{} { return t.classes },
+}
+
+// defaultTruthBuiltins holds the builtins that depend on truth values, for
+// sets without truth functions.
+var defaultTruthBuiltins = truthFuncs(nil).builtins()
+
+// builtins returns the builtins that depend on truth values, using t.
+func (t truthFuncs) builtins() map[string]reflect.Value {
+	m := make(map[string]reflect.Value, len(truthBuiltins))
+	for name, bind := range truthBuiltins {
+		m[name] = reflect.ValueOf(bind(t))
+	}
+	return m
+}
+
+// builtin returns the named builtin if it depends on truth values, using t.
+func (t truthFuncs) builtin(name string) (reflect.Value, bool) {
+	bind := truthBuiltins[name]
+	if bind == nil {
+		return reflect.Value{}, false
+	}
+	if len(t) == 0 {
+		return defaultTruthBuiltins[name], true
+	}
+	return reflect.ValueOf(bind(t)), true
+}
+
+// isTrue is like the isTrue function, but first consults the registered
+// truth functions.
+func (t truthFuncs) isTrue(val reflect.Value) (truth, ok bool) {
+	if val.IsValid() && len(t) > 0 {
+		v := val
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			v = v.Elem()
+		}
+		if fn := t[v.Type()]; fn != nil {
+			return fn(v.Interface()), true
+		}
+	}
+	return isTrue(val)
+}
+
+// truth returns the truth value of a.
+func (t truthFuncs) truth(a interface{}) bool {
+	truth, _ := t.isTrue(reflect.ValueOf(a))
+	return truth
+}
+
+// consts returns the constants whose truth value doesn't depend on the
+// registered truth functions, which are the ones that can be folded.
+func (t truthFuncs) consts(consts map[string]reflect.Value) map[string]reflect.Value {
+	if len(t) == 0 {
+		return consts
+	}
+	m := make(map[string]reflect.Value, len(consts))
+	for k, v := range consts {
+		if !v.IsValid() || t[v.Type()] == nil {
+			m[k] = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,87 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// money implements Truther: it is true when not zero.
+type money struct {
+	Cents int
+}
+
+func (m money) IsTrue() bool {
+	return m.Cents != 0
+}
+
+// optional is true when set, according to the truth function registered by
+// the tests.
+type optional struct {
+	Set   bool
+	Value string
+}
+
+func TestTruth(t *testing.T) {
+	data := map[string]interface{}{
+		"Zero":  money{},
+		"Ten":   money{1000},
+		"Unset": optional{},
+		"Empty": optional{Set: true},
+	}
+	tests := []struct {
+		input, output string
+	}{
+		{`{{if .Zero}}y{{else}}n{{end}}`, `n`},
+		{`{{if .Ten}}y{{else}}n{{end}}`, `y`},
+		{`{{with .Ten}}{{.Cents}}{{end}}`, `1000`},
+		{`{{if not .Zero}}y{{else}}n{{end}}`, `y`},
+		{`{{if and .Ten .Zero}}y{{else}}n{{end}}`, `n`},
+		{`{{or .Zero .Ten}}`, `{1000}`},
+		{`{{if .Unset}}y{{else}}n{{end}}`, `n`},
+		{`{{if .Empty}}y{{else}}n{{end}}`, `y`},
+		{`{{if or .Unset .Zero}}y{{else}}n{{end}}`, `n`},
+		{`{{if not .Unset}}y{{else}}n{{end}}`, `y`},
+	}
+	for _, test := range tests {
+		set, err := new(Set).Parse(`{{define "t"}}` + test.input + `{{end}}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set.Truth(reflect.TypeOf(optional{}), func(v interface{}) bool {
+			return v.(optional).Set
+		})
+		var b bytes.Buffer
+		if err := set.Execute(&b, "t", data); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.input, err)
+		} else if b.String() != test.output {
+			t.Errorf("%s: expected %q, got %q", test.input, test.output, b.String())
+		}
+	}
+	// Builtins use the truth functions, but user functions with the same
+	// names are kept.
+	set := Must(new(Set).Funcs(FuncMap{
+		"not": func(v interface{}) string { return "user" },
+	}).FlatBuiltins().Parse(`{{define "t"}}{{not .Unset}} {{html.classes (maps.dict "a" .Unset "b" .Empty)}} {{classes (maps.dict "c" .Unset)}}{{end}}`))
+	set.Truth(reflect.TypeOf(optional{}), func(v interface{}) bool {
+		return v.(optional).Set
+	})
+	var b bytes.Buffer
+	if err := set.Execute(&b, "t", data); err != nil {
+		t.Fatal(err)
+	} else if want := "user b "; b.String() != want {
+		t.Errorf("expected %q, got %q", want, b.String())
+	}
+	// Without the truth function, struct values are true.
+	set = Must(new(Set).Parse(`{{define "t"}}{{if .Unset}}y{{else}}n{{end}}{{end}}`))
+	b.Reset()
+	if err := set.Execute(&b, "t", data); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "y" {
+		t.Errorf("expected %q, got %q", "y", b.String())
+	}
+}
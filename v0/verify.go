@@ -0,0 +1,129 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/template/v0/parse"
+)
+
+// Verify checks that the templates called by {{template}} actions and the
+// parents of all templates are defined in the set, directly or through
+// aliases (see Alias). Otherwise such problems
+// are only found when the template is executed. All problems found are
+// reported in a single error, one per line, so it is convenient to call
+// Verify after all templates were parsed, for example at startup.
+func (s *Set) Verify() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	problems := undefinedTemplates(s.tree, s.aliases)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("template: undefined templates:\n\t%s", strings.Join(problems, "\n\t"))
+}
+
+// undefinedTemplates returns the sorted problems found by Verify in tree,
+// where the aliases are not resolved yet.
+func undefinedTemplates(tree parse.Tree, aliases map[string]string) []string {
+	defined := func(name string) bool {
+		return tree[resolveAlias(aliases, name)] != nil
+	}
+	var problems []string
+	for name, define := range tree {
+		if define.Parent != "" && !defined(define.Parent) {
+			problems = append(problems, fmt.Sprintf("%s: extends undefined template %q", name, define.Parent))
+		}
+		templateCalls(define.List, func(n *parse.TemplateNode) {
+			if !defined(n.Name) {
+				location, _ := define.ErrorContext(n)
+				problems = append(problems, fmt.Sprintf("%s: no such template %q", location, n.Name))
+			}
+		})
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// checkRecursion returns an error if a template calls itself, directly or
+// through other templates, without an {{if}}, {{range}} or {{with}} action
+// that could stop the recursion. Such templates never finish executing.
+func checkRecursion(tree parse.Tree) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			for i, v := range path {
+				if v == name {
+					cycle := append(path[i:], name)
+					return fmt.Errorf("template: infinite recursion: %s", strings.Join(cycle, " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+		define := tree[name]
+		if define == nil {
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		var err error
+		unconditionalCalls(define.List, func(n *parse.TemplateNode) {
+			if err == nil {
+				err = visit(n.Name)
+			}
+		})
+		path = path[:len(path)-1]
+		state[name] = visited
+		return err
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unconditionalCalls calls fn for each {{template}} action in n that is
+// always executed when n is.
+//
+// May contain child actions:
+// CaptureNode: n.List
+// LetNode:    n.List
+// ListNode:   n.Nodes
+func unconditionalCalls(n parse.Node, fn func(*parse.TemplateNode)) {
+	switch n := n.(type) {
+	case *parse.CaptureNode:
+		unconditionalCalls(n.List, fn)
+	case *parse.LetNode:
+		unconditionalCalls(n.List, fn)
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Nodes {
+			unconditionalCalls(v, fn)
+		}
+	case *parse.TemplateNode:
+		fn(n)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// Versions of a template are defined as templates named after it with a
+// version suffix, for example "email/welcome@v2" for the version "v2" of
+// "email/welcome". They are ordinary templates, which can also be called
+// by their full name, but ExecuteVersion selects them by version, falling
+// back to the unversioned template when the version is not defined. This
+// allows rolling out a template change gradually, or testing variants of a
+// template against each other, by choosing the version per execution.
+
+// versionSeparator separates the name of a template from its version.
+const versionSeparator = "@"
+
+// Versioned returns the name of the given version of the named template.
+func Versioned(name, version string) string {
+	return name + versionSeparator + version
+}
+
+// Versions returns the sorted versions defined for the named template.
+func (s *Set) Versions(name string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	prefix := name + versionSeparator
+	var versions []string
+	for k := range s.tree {
+		if strings.HasPrefix(k, prefix) {
+			versions = append(versions, k[len(prefix):])
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// ExecuteVersion applies the given version of the named template to the
+// specified data object and writes the output to wr. If the version is
+// empty or not defined, the unversioned template is executed.
+func (s *Set) ExecuteVersion(wr io.Writer, name, version string, data interface{}) error {
+	if _, err := s.Compile(); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	snap := s.current()
+	s.mutex.Unlock()
+	return snap.ExecuteVersion(wr, name, version, data)
+}
+
+// ExecuteVersion applies the given version of the named template from the
+// snapshot, as Set.ExecuteVersion does.
+func (s *Snapshot) ExecuteVersion(wr io.Writer, name, version string, data interface{}) error {
+	return s.execute(wr, s.version(name, version), data, false)
+}
+
+// version returns the name of the template to execute for the given
+// version of the named template.
+func (s *Snapshot) version(name, version string) string {
+	if version == "" {
+		return name
+	}
+	if v := Versioned(name, version); s.tree[v] != nil {
+		return v
+	}
+	return name
+}
@@ -0,0 +1,42 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExecuteVersion(t *testing.T) {
+	s := Must(new(Set).Parse(`
+{{define "welcome"}}Hello, {{.}}.{{end}}
+{{define "welcome@v2"}}Welcome, {{.}}!{{end}}
+{{define "welcome@v3"}}{{template "welcome@v2" .}} Enjoy.{{end}}`))
+	if got, want := s.Versions("welcome"), []string{"v2", "v3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got versions %q, want %q", got, want)
+	}
+	tests := []struct {
+		version, output string
+	}{
+		{"", "Hello, Ann."},
+		{"v2", "Welcome, Ann!"},
+		{"v3", "Welcome, Ann! Enjoy."},
+		{"v4", "Hello, Ann."},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := s.ExecuteVersion(&b, "welcome", test.version, "Ann"); err != nil {
+			t.Errorf("version %q: %s", test.version, err)
+			continue
+		}
+		if b.String() != test.output {
+			t.Errorf("version %q: got %q, want %q", test.version, b.String(), test.output)
+		}
+	}
+	if err := s.ExecuteVersion(new(bytes.Buffer), "missing", "v2", nil); err == nil {
+		t.Errorf("expected error executing a missing template")
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo
+// +build !tinygo
+
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Reload parses the templates of the set again if the files they were read
+// from changed since the previous call: files parsed by ParseFiles, files
+// matched by the patterns passed to ParseGlob, including the ones added
+// since, and files read by {{include}} and {{import}}. The parse calls are
+// replayed in order, texts parsed by Parse and the like included, into a
+// new set with the same options. If it compiles, its templates replace the
+// ones of the set, as Swap does; otherwise the set is unchanged. Reload
+// returns whether the files changed. The first call only records the
+// modification times of the files. They are recorded again only when the
+// set is rebuilt, so after a failed rebuild the next call tries again.
+//
+// Reload makes the set usable after compilation, when templates can't be
+// added anymore, for example to develop a web application without
+// restarting the server after each template edit. See also Watch.
+func (s *Set) Reload() (bool, error) {
+	s.rebuilding.Lock()
+	defer s.rebuilding.Unlock()
+	s.mutex.Lock()
+	var paths []string
+	for path := range s.read {
+		paths = append(paths, path)
+	}
+	for path := range s.imported {
+		paths = append(paths, path)
+	}
+	globs, policy, last := s.globs, s.files, s.modTimes
+	s.mutex.Unlock()
+	for _, pattern := range globs {
+		filenames, _ := filepath.Glob(pattern)
+		paths = append(paths, policy.filter(pattern, filenames)...)
+	}
+	modTimes := scanModTimes(paths)
+	if last != nil {
+		if equalModTimes(last, modTimes) {
+			return false, nil
+		}
+		if err := s.rebuild(nil); err != nil {
+			return true, err
+		}
+	}
+	s.mutex.Lock()
+	s.modTimes = modTimes
+	s.mutex.Unlock()
+	return last != nil, nil
+}
+
+// Watch calls Reload at the given interval until stop is closed. Reloads
+// and their errors are logged through the logger of the set, at the info
+// and error levels; see Logger.
+func (s *Set) Watch(interval time.Duration, stop <-chan struct{}) {
+	s.Reload()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if changed, err := s.Reload(); err != nil {
+				s.log.logf(LogError, "template: reloading templates: %s", err)
+			} else if changed {
+				s.log.logf(LogInfo, "template: reloaded templates")
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// scanModTimes returns the modification times of the files at paths, for
+// Reload and Reloader. Files that can't be found are left out: the error
+// is reported when they are parsed.
+func scanModTimes(paths []string) map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil {
+			modTimes[path] = fi.ModTime()
+		}
+	}
+	return modTimes
+}
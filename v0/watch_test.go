@@ -0,0 +1,83 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !tinygo
+// +build !tinygo
+
+package template
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mod := time.Now().Add(-time.Hour)
+	write := func(name, text string) {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mod = mod.Add(time.Second)
+		if err := os.Chtimes(filename, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var set *Set
+	check := func(name, want string) {
+		var b bytes.Buffer
+		if err := set.Execute(&b, name, nil); err != nil {
+			t.Errorf("%s: %s", name, err)
+		} else if b.String() != want {
+			t.Errorf("%s: got %q, want %q", name, b.String(), want)
+		}
+	}
+	reload := func(want bool) {
+		if changed, err := set.Reload(); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		} else if changed != want {
+			t.Errorf("got changed %v, want %v", changed, want)
+		}
+	}
+	write("a.tmpl", `{{define "a"}}A1{{end}}`)
+	set = Must(new(Set).ParseGlob(filepath.Join(dir, "*.tmpl")))
+	Must(set.Parse(`{{define "page"}}[{{template "a"}}]{{end}}`))
+	check("page", "[A1]")
+	reload(false)
+	reload(false)
+
+	write("a.tmpl", `{{define "a"}}A2{{end}}`)
+	reload(true)
+	check("page", "[A2]")
+	reload(false)
+
+	// New files matched by the pattern are parsed.
+	write("b.tmpl", `{{define "b"}}B{{end}}`)
+	reload(true)
+	check("b", "B")
+
+	// The templates are kept if the files don't parse.
+	write("a.tmpl", `{{define "a"}}{{end`)
+	if changed, err := set.Reload(); !changed || err == nil {
+		t.Errorf("got %v, %v; want a parse error", changed, err)
+	}
+	check("page", "[A2]")
+	// The modification times are kept until a rebuild succeeds.
+	if changed, err := set.Reload(); !changed || err == nil {
+		t.Errorf("got %v, %v; want the parse error again", changed, err)
+	}
+	write("a.tmpl", `{{define "a"}}A3{{end}}`)
+	reload(true)
+	check("page", "[A3]")
+	reload(false)
+}